@@ -3,13 +3,16 @@ package app
 import (
 	"context"
 	"flag"
+	"fmt"
 
 	certificateinstall "kubeception.cloud/kubeception/pkg/apis/certificate/install"
 
 	"github.com/go-logr/logr"
 	"github.com/spf13/cobra"
 	kubeceptioninstall "kubeception.cloud/kubeception/pkg/apis/kubeception/install"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
 	"kubeception.cloud/kubeception/pkg/controller"
+	"kubeception.cloud/kubeception/pkg/controller/common"
 	"kubeception.cloud/kubeception/pkg/util"
 	clusterapis "sigs.k8s.io/cluster-api/pkg/apis"
 	clustercontroller "sigs.k8s.io/cluster-api/pkg/controller"
@@ -57,5 +60,18 @@ func NewManagerCommand(ctx context.Context, logger logr.Logger) *cobra.Command {
 	cmd.Flags().AddGoFlagSet(flag.CommandLine)
 	cmd.Flags().BoolVar(&leaderElection, "leader-election", false, "Whether to do leader")
 
+	for name, image := range map[string]*v1alpha1.Image{
+		"hyperkube": common.DefaultImages.Hyperkube,
+		"etcd":      common.DefaultImages.ETCD,
+		"kubelet":   common.DefaultImages.Kubelet,
+		"coredns":   &common.DefaultCoreDNSImage,
+		"flannel":   &common.DefaultFlannelImage,
+	} {
+		cmd.Flags().StringVar(&image.Repository, fmt.Sprintf("%s-image-repository", name), image.Repository, fmt.Sprintf("Default repository of the %s image", name))
+		cmd.Flags().StringVar(&image.Tag, fmt.Sprintf("%s-image-tag", name), image.Tag, fmt.Sprintf("Default tag of the %s image", name))
+		cmd.Flags().StringVar(&image.Digest, fmt.Sprintf("%s-image-digest", name), image.Digest, fmt.Sprintf("Default digest of the %s image, takes precedence over its tag", name))
+	}
+	cmd.Flags().StringSliceVar(&common.DefaultImagePullSecrets, "image-pull-secrets", common.DefaultImagePullSecrets, "Secrets used to pull the images of all clusters, they have to exist in the namespace of each cluster")
+
 	return cmd
 }
@@ -1,6 +1,9 @@
 #!/bin/bash
 
-cat <<'EOF' | kubectl create -f -
+# Usage: hyper.sh [cluster-name]
+CLUSTER_NAME="${1:-cluster-example}"
+
+cat <<EOF | kubectl create -f -
 apiVersion: v1
 kind: Pod
 metadata:
@@ -25,7 +28,7 @@ spec:
   volumes:
   - name: kubeconfig
     secret:
-      secretName: kubeconfig
+      secretName: ${CLUSTER_NAME}-kubeconfig
 EOF
 
 while [[ "$(kubectl get pod hyper -o 'jsonpath={.status.conditions[?(@.type=="Ready")].status}')" != 'True' ]]; do
@@ -22,6 +22,7 @@ const (
 	KeyPairChecksumKey = "keypair.certificate.kubeception.cloud/checksum"
 
 	CertificateDataKey     = "certificate"
+	CertificatePEMDataKey  = "certificate-pem"
 	CertificateChecksumKey = "certificate.certificate.kubeception.cloud/checksum"
 
 	EventGeneratingKey            = "GeneratingKey"
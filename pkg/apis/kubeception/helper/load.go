@@ -17,8 +17,13 @@ var (
 
 	Codec runtime.Codec
 
+	// JSONCodec encodes objects as JSON, as required for raw extensions embedded in API objects.
+	JSONCodec runtime.Codec
+
 	defaultClusterConfigGVK = v1alpha1.SchemeGroupVersion.WithKind(v1alpha1.ClusterConfigKind)
 	defaultMachineConfigGVK = v1alpha1.SchemeGroupVersion.WithKind(v1alpha1.MachineConfigKind)
+	defaultClusterStatusGVK = v1alpha1.SchemeGroupVersion.WithKind(v1alpha1.ClusterStatusKind)
+	defaultMachineStatusGVK = v1alpha1.SchemeGroupVersion.WithKind(v1alpha1.MachineStatusKind)
 )
 
 func init() {
@@ -33,6 +38,15 @@ func init() {
 		v1alpha1.SchemeGroupVersion,
 		v1alpha1.SchemeGroupVersion,
 	)
+
+	jsonSerializer := json.NewSerializer(json.DefaultMetaFactory, Scheme, Scheme, false)
+	JSONCodec = versioning.NewDefaultingCodecForScheme(
+		Scheme,
+		jsonSerializer,
+		jsonSerializer,
+		v1alpha1.SchemeGroupVersion,
+		v1alpha1.SchemeGroupVersion,
+	)
 }
 
 func LoadClusterConfig(data []byte) (*v1alpha1.ClusterConfig, error) {
@@ -50,3 +64,37 @@ func LoadMachineConfig(data []byte) (*v1alpha1.MachineConfig, error) {
 	}
 	return config, nil
 }
+
+func LoadClusterStatus(data []byte) (*v1alpha1.ClusterStatus, error) {
+	status := &v1alpha1.ClusterStatus{}
+	if _, _, err := Codec.Decode(data, &defaultClusterStatusGVK, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// EncodeClusterStatus encodes the given cluster status as raw extension for the provider status of a cluster.
+func EncodeClusterStatus(status *v1alpha1.ClusterStatus) (*runtime.RawExtension, error) {
+	data, err := runtime.Encode(JSONCodec, status)
+	if err != nil {
+		return nil, err
+	}
+	return &runtime.RawExtension{Raw: data}, nil
+}
+
+func LoadMachineStatus(data []byte) (*v1alpha1.MachineStatus, error) {
+	status := &v1alpha1.MachineStatus{}
+	if _, _, err := Codec.Decode(data, &defaultMachineStatusGVK, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// EncodeMachineStatus encodes the given machine status as raw extension for the provider status of a machine.
+func EncodeMachineStatus(status *v1alpha1.MachineStatus) (*runtime.RawExtension, error) {
+	data, err := runtime.Encode(JSONCodec, status)
+	if err != nil {
+		return nil, err
+	}
+	return &runtime.RawExtension{Raw: data}, nil
+}
@@ -0,0 +1,81 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// ClusterAddon applies Kubernetes manifests to the hosted clusters it selects.
+type ClusterAddon struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterAddonSpec   `json:"spec"`
+	Status ClusterAddonStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterAddonList is a list of ClusterAddons.
+type ClusterAddonList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ClusterAddon `json:"items,omitempty"`
+}
+
+// ClusterAddonSpec is the specification of a ClusterAddon.
+type ClusterAddonSpec struct {
+	// ClusterSelector selects the clusters in the namespace of the addon the manifests are applied to.
+	// An empty selector selects all clusters of the namespace.
+	ClusterSelector metav1.LabelSelector `json:"clusterSelector"`
+	// Sources are the ConfigMaps and Secrets holding the manifests. Each key of a source holds one or more
+	// YAML or JSON documents separated by `---`.
+	Sources []ClusterAddonSource `json:"sources"`
+}
+
+// ClusterAddonSource is a ConfigMap or Secret in the namespace of the addon. Exactly one of its fields has to be set.
+type ClusterAddonSource struct {
+	// ConfigMap is a ConfigMap holding manifests.
+	ConfigMap *corev1.LocalObjectReference `json:"configMap,omitempty"`
+	// Secret is a Secret holding manifests.
+	Secret *corev1.LocalObjectReference `json:"secret,omitempty"`
+}
+
+// ClusterAddonPhase is the phase of a ClusterAddon in a cluster.
+type ClusterAddonPhase string
+
+const (
+	ClusterAddonApplied ClusterAddonPhase = "Applied"
+	ClusterAddonFailed  ClusterAddonPhase = "Failed"
+)
+
+// ClusterAddonStatus is the status of a ClusterAddon.
+type ClusterAddonStatus struct {
+	// Clusters is the status of the addon in each selected cluster.
+	Clusters []ClusterAddonClusterStatus `json:"clusters,omitempty"`
+}
+
+// ClusterAddonClusterStatus is the status of a ClusterAddon in a single cluster.
+type ClusterAddonClusterStatus struct {
+	// Name is the name of the cluster.
+	Name  string            `json:"name"`
+	Phase ClusterAddonPhase `json:"phase,omitempty"`
+	// Message is a human readable message indicating why applying the manifests failed.
+	Message string `json:"message,omitempty"`
+	// LastAppliedTime is the last time the manifests were applied successfully.
+	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
+	// Objects are the objects applied to the cluster. Objects that are removed from the manifests are deleted.
+	Objects []ClusterAddonObject `json:"objects,omitempty"`
+}
+
+// ClusterAddonObject references an object applied to a cluster.
+type ClusterAddonObject struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
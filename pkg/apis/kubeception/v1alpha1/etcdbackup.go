@@ -0,0 +1,116 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// EtcdBackup is a single snapshot of the etcd of a hosted cluster.
+type EtcdBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   EtcdBackupSpec   `json:"spec"`
+	Status EtcdBackupStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// EtcdBackupList is a list of EtcdBackups.
+type EtcdBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []EtcdBackup `json:"items,omitempty"`
+}
+
+// EtcdBackupSpec is the specification of an EtcdBackup.
+type EtcdBackupSpec struct {
+	// Cluster is the cluster in the namespace of the backup whose etcd is snapshotted.
+	Cluster corev1.LocalObjectReference `json:"cluster"`
+	// Storage is the location the snapshot is stored at.
+	Storage EtcdBackupStorage `json:"storage"`
+}
+
+// EtcdBackupStorage is the location snapshots are stored at. Exactly one of its fields has to be set.
+type EtcdBackupStorage struct {
+	// PersistentVolumeClaim stores snapshots in the given claim in the namespace of the backup.
+	PersistentVolumeClaim *corev1.PersistentVolumeClaimVolumeSource `json:"persistentVolumeClaim,omitempty"`
+	// HostPath stores snapshots in a directory of the hosting cluster node the snapshot is taken on.
+	HostPath *corev1.HostPathVolumeSource `json:"hostPath,omitempty"`
+}
+
+// EtcdBackupPhase is the phase of an EtcdBackup.
+type EtcdBackupPhase string
+
+const (
+	EtcdBackupPending   EtcdBackupPhase = "Pending"
+	EtcdBackupRunning   EtcdBackupPhase = "Running"
+	EtcdBackupSucceeded EtcdBackupPhase = "Succeeded"
+	EtcdBackupFailed    EtcdBackupPhase = "Failed"
+)
+
+// EtcdBackupStatus is the status of an EtcdBackup.
+type EtcdBackupStatus struct {
+	Phase EtcdBackupPhase `json:"phase,omitempty"`
+	// Path is the path of the snapshot file relative to the root of the storage.
+	Path string `json:"path,omitempty"`
+	// NodeName is the node the snapshot was taken on. Host path snapshots are only present on that node.
+	NodeName string `json:"nodeName,omitempty"`
+	// Size is the size of the snapshot in bytes.
+	Size int64 `json:"size,omitempty"`
+	// Revision is the etcd revision of the snapshot.
+	Revision int64 `json:"revision,omitempty"`
+	// SnapshotTime is the time the snapshot was completed.
+	SnapshotTime *metav1.Time `json:"snapshotTime,omitempty"`
+	// Message is a human readable message indicating why a backup failed.
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// EtcdBackupSchedule periodically creates EtcdBackups of a hosted cluster and prunes old ones.
+type EtcdBackupSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   EtcdBackupScheduleSpec   `json:"spec"`
+	Status EtcdBackupScheduleStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// EtcdBackupScheduleList is a list of EtcdBackupSchedules.
+type EtcdBackupScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []EtcdBackupSchedule `json:"items,omitempty"`
+}
+
+// EtcdBackupScheduleSpec is the specification of an EtcdBackupSchedule.
+type EtcdBackupScheduleSpec struct {
+	// Cluster is the cluster in the namespace of the schedule whose etcd is snapshotted.
+	Cluster corev1.LocalObjectReference `json:"cluster"`
+	// Schedule is a cron expression (minute, hour, day of month, month, day of week) at which backups are taken.
+	Schedule string `json:"schedule"`
+	// Generations is the number of successful backups to keep. Defaults to 3.
+	// Older backups and failed backups superseded by a successful one are deleted along with their snapshots.
+	Generations *int32 `json:"generations,omitempty"`
+	// Suspend suspends the creation of new backups.
+	Suspend bool `json:"suspend,omitempty"`
+	// Storage is the location the snapshots are stored at.
+	Storage EtcdBackupStorage `json:"storage"`
+}
+
+// EtcdBackupScheduleStatus is the status of an EtcdBackupSchedule.
+type EtcdBackupScheduleStatus struct {
+	// LastScheduleTime is the last time a backup was scheduled.
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	// LastSuccessfulBackup is the name of the last backup that succeeded.
+	LastSuccessfulBackup string `json:"lastSuccessfulBackup,omitempty"`
+}
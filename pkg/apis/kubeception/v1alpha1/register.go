@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
@@ -24,7 +25,17 @@ var (
 
 func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
+		&ClusterAddon{},
+		&ClusterAddonList{},
 		&ClusterConfig{},
+		&ClusterStatus{},
+		&EtcdBackup{},
+		&EtcdBackupList{},
+		&EtcdBackupSchedule{},
+		&EtcdBackupScheduleList{},
+		&MachineConfig{},
+		&MachineStatus{},
 	)
+	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
 }
@@ -1,6 +1,8 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"kubeception.cloud/kubeception/pkg/util"
 )
@@ -9,6 +11,10 @@ var (
 	ClusterConfigKind = util.MustTypeToKind(&ClusterConfig{})
 
 	MachineConfigKind = util.MustTypeToKind(&MachineConfig{})
+
+	ClusterStatusKind = util.MustTypeToKind(&ClusterStatus{})
+
+	MachineStatusKind = util.MustTypeToKind(&MachineStatus{})
 )
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -19,6 +25,86 @@ type ClusterConfig struct {
 
 	ControlPlane      ControlPlane `json:"controlPlane"`
 	KubernetesVersion string       `json:"kubernetesVersion"`
+	// Images overrides the images of the cluster components. Unset fields default to the images configured for
+	// the manager.
+	Images *Images `json:"images,omitempty"`
+	// DNS deploys CoreDNS into the cluster and points the kubelets of the machines to it.
+	// If unset, the cluster has no cluster DNS.
+	DNS *DNS `json:"dns,omitempty"`
+	// Network deploys a pod network into the cluster and configures the kubelets of the machines to use it.
+	// If unset, pods can only reach pods on the same machine.
+	Network *Network `json:"network,omitempty"`
+}
+
+// DNS carries the configuration of the CoreDNS addon of a cluster.
+type DNS struct {
+	// Image overrides the CoreDNS image configured for the manager.
+	Image *Image `json:"image,omitempty"`
+	// Replicas is the number of CoreDNS replicas. Defaults to 2.
+	Replicas *int32 `json:"replicas,omitempty"`
+	// UpstreamNameservers are the IP addresses, optionally with port, names outside of the cluster domain are
+	// resolved with. Defaults to the nameservers of the machines.
+	UpstreamNameservers []string `json:"upstreamNameservers,omitempty"`
+}
+
+// Images carries image overrides of the cluster components.
+type Images struct {
+	// Hyperkube is the image of the API server, controller manager and scheduler.
+	Hyperkube *Image `json:"hyperkube,omitempty"`
+	// ETCD is the image of the etcd members, backups and restores.
+	ETCD *Image `json:"etcd,omitempty"`
+	// Kubelet is the image of the machines running docker and the kubelet.
+	Kubelet *Image `json:"kubelet,omitempty"`
+	// ImagePullSecrets are secrets in the namespace of the cluster that are used to pull the images, in addition
+	// to the ones configured for the manager.
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+}
+
+// Image overrides parts of an image reference.
+type Image struct {
+	// Repository is the repository of the image, for example `registry.example.com/hyperkube`.
+	Repository string `json:"repository,omitempty"`
+	// Tag is the tag of the image. The hyperkube and kubelet images are tagged with the Kubernetes version
+	// unless a tag is set.
+	Tag string `json:"tag,omitempty"`
+	// Digest is the digest of the image, for example `sha256:...`. If set, it is used instead of the tag.
+	Digest string `json:"digest,omitempty"`
+}
+
+// NetworkType is the plugin providing the pod network of a cluster.
+type NetworkType string
+
+const (
+	// NetworkFlannel provides the pod network with flannel.
+	NetworkFlannel NetworkType = "Flannel"
+)
+
+// Network carries the configuration of the pod network of a cluster. The pod CIDRs of the nodes are allocated
+// by the controller manager from the pod CIDR of the cluster network, so a controller manager is required.
+type Network struct {
+	// Type is the plugin providing the pod network. Supported is Flannel.
+	Type NetworkType `json:"type"`
+	// Flannel configures the flannel plugin.
+	Flannel *FlannelNetwork `json:"flannel,omitempty"`
+}
+
+// FlannelBackend is the backend flannel forwards packets between nodes with.
+type FlannelBackend string
+
+const (
+	// FlannelVXLAN encapsulates the packets between nodes in VXLAN.
+	FlannelVXLAN FlannelBackend = "vxlan"
+	// FlannelHostGW bridges the pods of each node and routes between the nodes via host routes. It requires
+	// all nodes to be in the same layer 2 network.
+	FlannelHostGW FlannelBackend = "host-gw"
+)
+
+// FlannelNetwork carries the configuration of the flannel plugin.
+type FlannelNetwork struct {
+	// Image overrides the flannel image configured for the manager.
+	Image *Image `json:"image,omitempty"`
+	// Backend is the backend flannel forwards packets between nodes with. Defaults to vxlan.
+	Backend FlannelBackend `json:"backend,omitempty"`
 }
 
 // ControlPlane is the specification of a cluster control plane.
@@ -29,25 +115,329 @@ type ControlPlane struct {
 	Scheduler         *Scheduler         `json:"scheduler,omitempty"`
 }
 
+// WorkloadSettings carries the resources and scheduling constraints of the pods of a workload in the hosting
+// cluster.
+type WorkloadSettings struct {
+	// Resources are the compute resources of the main container of each pod.
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// NodeSelector selects the nodes of the hosting cluster the pods are scheduled to.
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Tolerations are the tolerations of the pods.
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// Affinity is the affinity of the pods. The node and pod affinities replace the default ones, the terms of
+	// the pod anti-affinity are added to the default ones which spread the replicas of control plane
+	// components across nodes.
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// PriorityClassName is the name of the priority class of the pods.
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+}
+
 // ETCD carries etcd configuration.
 type ETCD struct {
+	WorkloadSettings `json:",inline"`
+
+	// Replicas is the number of etcd members. Defaults to 1.
+	// Members are added or removed one at a time, an odd number is recommended.
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Storage is the storage configuration of the etcd members.
+	Storage ETCDStorage `json:"storage,omitempty"`
+	// RestoreFrom is a snapshot the etcd data is restored from. Each distinct source is restored once:
+	// The API server is stopped, all members restore the snapshot and the control plane is brought back.
+	RestoreFrom *ETCDRestoreSource `json:"restoreFrom,omitempty"`
+	// FinalBackup is the storage a last snapshot is taken to when the cluster is deleted. The EtcdBackup of
+	// the snapshot outlives the cluster. If unset, no snapshot is taken on deletion.
+	FinalBackup *EtcdBackupStorage `json:"finalBackup,omitempty"`
+}
+
+// ETCDStorage carries the storage configuration of etcd. It cannot be changed once etcd is created.
+type ETCDStorage struct {
+	// Size is the size of the volume of each member. Defaults to 1Gi.
+	Size *resource.Quantity `json:"size,omitempty"`
+	// StorageClassName is the storage class of the volume of each member.
+	// If unset, the default storage class of the hosting cluster is used.
+	StorageClassName *string `json:"storageClassName,omitempty"`
+	// EmptyDir stores the data in emptyDir volumes instead of persistent volumes.
+	// Data does not survive the rescheduling of members, so this is only meant for throwaway clusters.
+	EmptyDir bool `json:"emptyDir,omitempty"`
+}
+
+// ETCDRestoreSource is the location of an etcd snapshot. Exactly one of its fields has to be set.
+type ETCDRestoreSource struct {
+	// PersistentVolumeClaim restores from a snapshot file in a persistent volume claim, for example the one
+	// an EtcdBackup was stored in. The claim has to be mountable by all etcd members.
+	PersistentVolumeClaim *ETCDRestorePersistentVolumeClaimSource `json:"persistentVolumeClaim,omitempty"`
+	// Secret restores from a snapshot stored in a secret.
+	Secret *ETCDRestoreSecretSource `json:"secret,omitempty"`
+}
+
+// ETCDRestorePersistentVolumeClaimSource is an etcd snapshot file in a persistent volume claim.
+type ETCDRestorePersistentVolumeClaimSource struct {
+	// ClaimName is the name of the claim in the namespace of the cluster.
+	ClaimName string `json:"claimName"`
+	// Path is the path of the snapshot file relative to the root of the claim, as recorded in the status of
+	// an EtcdBackup.
+	Path string `json:"path"`
+}
+
+// ETCDRestoreSecretSource is an etcd snapshot stored in a secret.
+type ETCDRestoreSecretSource struct {
+	// Name is the name of the secret in the namespace of the cluster.
+	Name string `json:"name"`
+	// Key is the key of the snapshot in the secret. Defaults to `snapshot.db`.
+	Key string `json:"key,omitempty"`
 }
 
 // APIServer carries Kubernetes API server configuration.
 type APIServer struct {
+	WorkloadSettings `json:",inline"`
+
+	// Replicas is the number of API server replicas. Defaults to 2.
+	// The replicas are spread across the nodes of the hosting cluster where possible.
+	Replicas *int32 `json:"replicas,omitempty"`
+	// AuthorizationModes are the authorization modes of the API server in the order they are consulted.
+	// Supported are AlwaysAllow, AlwaysDeny, Node and RBAC. Defaults to Node and RBAC.
+	AuthorizationModes []string `json:"authorizationModes,omitempty"`
+	// EnableAdmissionPlugins are admission plugins that are enabled in addition to the default ones.
+	// NodeRestriction is enabled unless it is disabled explicitly.
+	EnableAdmissionPlugins []string `json:"enableAdmissionPlugins,omitempty"`
+	// DisableAdmissionPlugins are default admission plugins that are disabled.
+	DisableAdmissionPlugins []string `json:"disableAdmissionPlugins,omitempty"`
+	// FeatureGates enables or disables alpha and beta features of the API server.
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+	// ServiceNodePortRange is the port range reserved for node port services, for example `30000-32767`.
+	ServiceNodePortRange string `json:"serviceNodePortRange,omitempty"`
+	// RuntimeConfig enables or disables API versions and resources, for example `api/all: "true"`.
+	RuntimeConfig map[string]string `json:"runtimeConfig,omitempty"`
+	// ExtraArgs are additional flags of the API server without leading dashes. They override the flags rendered
+	// from the other fields. Flags managed by kubeception, like the etcd and serving certificate flags, cannot
+	// be set.
+	ExtraArgs map[string]string `json:"extraArgs,omitempty"`
+	// Exposure configures how the API server is reachable from outside the hosting cluster.
+	// Defaults to a node port service.
+	Exposure *APIServerExposure `json:"exposure,omitempty"`
+}
+
+// APIServerExposureType is the way the API server is exposed.
+type APIServerExposureType string
+
+const (
+	// APIServerExposureClusterIP exposes the API server only inside the hosting cluster.
+	APIServerExposureClusterIP APIServerExposureType = "ClusterIP"
+	// APIServerExposureNodePort exposes the API server on a port of every node of the hosting cluster.
+	APIServerExposureNodePort APIServerExposureType = "NodePort"
+	// APIServerExposureLoadBalancer exposes the API server via a load balancer of the hosting cluster.
+	APIServerExposureLoadBalancer APIServerExposureType = "LoadBalancer"
+	// APIServerExposureIngress exposes the API server via an ingress that passes TLS connections through to
+	// the API server based on their server name (SNI).
+	APIServerExposureIngress APIServerExposureType = "Ingress"
+)
+
+// APIServerExposure configures how the API server is reachable from outside the hosting cluster.
+type APIServerExposure struct {
+	// Type is the way the API server is exposed. Defaults to NodePort.
+	Type APIServerExposureType `json:"type,omitempty"`
+	// NodePort is the fixed node port of the API server service for the NodePort and LoadBalancer types.
+	// If unset, a port is allocated by the hosting cluster.
+	NodePort *int32 `json:"nodePort,omitempty"`
+	// Host is the host name or IP address the API server is reached at from outside the hosting cluster.
+	// It is added to the serving certificate of the API server. Required for the Ingress type, where it is
+	// the host of the ingress rule. Defaults to an address of a node of the hosting cluster for the NodePort
+	// type and to the address of the load balancer for the LoadBalancer type. Without a host, API servers of
+	// the ClusterIP type have no external kubeconfig.
+	Host string `json:"host,omitempty"`
+	// IngressAnnotations are additional annotations of the ingress of the Ingress type, for example to select
+	// an ingress class. The SSL passthrough annotation of the NGINX ingress controller is always set.
+	IngressAnnotations map[string]string `json:"ingressAnnotations,omitempty"`
 }
 
 // ControllerManager carries Kubernetes controller manager configuration.
 type ControllerManager struct {
+	WorkloadSettings `json:",inline"`
+
+	// Replicas is the number of controller manager replicas. Defaults to 1.
+	// Multiple replicas require leader election.
+	Replicas *int32 `json:"replicas,omitempty"`
+	// LeaderElection configures the leader election among the replicas.
+	LeaderElection *LeaderElection `json:"leaderElection,omitempty"`
+	// Controllers are the controllers to enable: `*` enables all controllers that are on by default, `foo`
+	// enables the controller foo and `-foo` disables it. Defaults to `*`.
+	Controllers []string `json:"controllers,omitempty"`
+	// NodeCIDRMaskSize is the mask size of the pod CIDRs allocated to the nodes. Defaults to 24.
+	NodeCIDRMaskSize *int32 `json:"nodeCIDRMaskSize,omitempty"`
+	// FeatureGates enables or disables alpha and beta features of the controller manager.
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+	// ExtraArgs are additional flags of the controller manager without leading dashes. They override the flags
+	// rendered from the other fields. Flags managed by kubeception, like the kubeconfig and network flags,
+	// cannot be set.
+	ExtraArgs map[string]string `json:"extraArgs,omitempty"`
 }
 
-// ControllerManager carries Kubernetes scheduler configuration.
+// Scheduler carries Kubernetes scheduler configuration.
 type Scheduler struct {
+	WorkloadSettings `json:",inline"`
+
+	// Replicas is the number of scheduler replicas. Defaults to 1.
+	// Multiple replicas require leader election.
+	Replicas *int32 `json:"replicas,omitempty"`
+	// LeaderElection configures the leader election among the replicas.
+	LeaderElection *LeaderElection `json:"leaderElection,omitempty"`
+	// Policy selects a scheduler policy file from a ConfigMap in the namespace of the cluster.
+	Policy *corev1.ConfigMapKeySelector `json:"policy,omitempty"`
+	// Config selects a KubeSchedulerConfiguration file from a ConfigMap in the namespace of the cluster.
+	// As the scheduler ignores most flags when a configuration file is given, the client connection of the
+	// configuration has to use the kubeconfig at /etc/kubeconfig/kubeconfig. Cannot be combined with Policy.
+	Config *corev1.ConfigMapKeySelector `json:"config,omitempty"`
+	// FeatureGates enables or disables alpha and beta features of the scheduler.
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+	// ExtraArgs are additional flags of the scheduler without leading dashes. They override the flags rendered
+	// from the other fields. Flags managed by kubeception, like the kubeconfig flags, cannot be set.
+	ExtraArgs map[string]string `json:"extraArgs,omitempty"`
+}
+
+// LeaderElection configures the leader election of a control plane component.
+type LeaderElection struct {
+	// LeaderElect enables leader election. Defaults to true.
+	LeaderElect *bool `json:"leaderElect,omitempty"`
+	// LeaseDuration is the duration non-leaders wait before trying to acquire leadership.
+	LeaseDuration *metav1.Duration `json:"leaseDuration,omitempty"`
+	// RenewDeadline is the duration within which the leader has to renew its leadership.
+	RenewDeadline *metav1.Duration `json:"renewDeadline,omitempty"`
+	// RetryPeriod is the duration between attempts to acquire or renew leadership.
+	RetryPeriod *metav1.Duration `json:"retryPeriod,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
-// ClusterConfig is the kubeception machine configuration.
+// MachineConfig is the kubeception machine configuration.
 type MachineConfig struct {
 	metav1.TypeMeta `json:",inline"`
+
+	WorkloadSettings `json:",inline"`
+
+	// Docker configures the docker daemon running the containers of the machine.
+	Docker *Docker `json:"docker,omitempty"`
+	// Kubelet configures the kubelet of the machine.
+	Kubelet *Kubelet `json:"kubelet,omitempty"`
+	// Storage is the volume the data of docker and the kubelet is stored on. If unset, the data is stored in the
+	// writable layer of the machine container and lost whenever it restarts.
+	Storage *MachineStorage `json:"storage,omitempty"`
+	// DrainTimeout is the maximum duration the pods of the node of a deleted machine are evicted for. Afterwards
+	// the node is deleted regardless of the remaining pods. Defaults to 5 minutes.
+	DrainTimeout *metav1.Duration `json:"drainTimeout,omitempty"`
+}
+
+// MachineStorage carries the storage configuration of a machine.
+type MachineStorage struct {
+	// Size is the size of the persistent volume of the machine, or the size limit of its emptyDir volume.
+	// Defaults to 10Gi for persistent volumes and to no limit for emptyDir volumes.
+	Size *resource.Quantity `json:"size,omitempty"`
+	// StorageClassName is the storage class of the persistent volume of the machine.
+	// If unset, the default storage class of the hosting cluster is used.
+	StorageClassName *string `json:"storageClassName,omitempty"`
+	// EmptyDir stores the data in an emptyDir volume instead of a persistent volume.
+	// Data survives restarts of the machine container, but not the rescheduling of the machine.
+	EmptyDir bool `json:"emptyDir,omitempty"`
+}
+
+// Docker configures the docker daemon of a machine.
+type Docker struct {
+	// StorageDriver is the storage driver of docker. Defaults to vfs, which works on any file system of the host
+	// but copies the full image for each container. Machines with storage default to overlay2 if the file system
+	// of their volume supports it.
+	StorageDriver string `json:"storageDriver,omitempty"`
+	// ExtraArgs are additional arguments of dockerd, for example `--registry-mirror=https://mirror.example.com`.
+	// The storage driver cannot be set via extra arguments.
+	ExtraArgs []string `json:"extraArgs,omitempty"`
+}
+
+// Kubelet configures the kubelet of a machine.
+type Kubelet struct {
+	// NodeLabels are the labels the node registers with.
+	NodeLabels map[string]string `json:"nodeLabels,omitempty"`
+	// Taints are the taints the node registers with.
+	Taints []corev1.Taint `json:"taints,omitempty"`
+	// MaxPods is the maximum number of pods of the node.
+	MaxPods *int32 `json:"maxPods,omitempty"`
+	// FeatureGates enables or disables alpha and beta features of the kubelet.
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+	// ExtraArgs are additional flags of the kubelet without leading dashes. They override the flags rendered
+	// from the other fields. Flags managed by kubeception, like the kubeconfig and network flags, cannot be set.
+	ExtraArgs map[string]string `json:"extraArgs,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterStatus is the kubeception provider status of a cluster.
+type ClusterStatus struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Ready indicates that all control plane components are ready.
+	Ready bool `json:"ready"`
+	// KubernetesVersion is the Kubernetes version the API server has last been observed to run.
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+	// ControlPlane is the status of the control plane components.
+	ControlPlane ControlPlaneStatus `json:"controlPlane"`
+	// Upgrade is the Kubernetes version upgrade in progress, if any.
+	Upgrade *UpgradeStatus `json:"upgrade,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MachineStatus is the kubeception provider status of a machine.
+type MachineStatus struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// PodName is the name of the pod running the machine.
+	PodName string `json:"podName,omitempty"`
+	// PodIP is the IP of the pod running the machine. The node of the machine is named after it.
+	PodIP string `json:"podIP,omitempty"`
+	// Ready indicates that the node of the machine is ready.
+	Ready bool `json:"ready"`
+	// KubeletVersion is the version of the kubelet reported by the node of the machine.
+	KubeletVersion string `json:"kubeletVersion,omitempty"`
+}
+
+// UpgradePhase is the step of a Kubernetes version upgrade.
+type UpgradePhase string
+
+const (
+	// UpgradeETCD waits for all etcd members to be ready before the API server is upgraded.
+	UpgradeETCD UpgradePhase = "ETCD"
+	// UpgradeAPIServer upgrades the API server.
+	UpgradeAPIServer UpgradePhase = "APIServer"
+	// UpgradeControllerManager upgrades the controller manager.
+	UpgradeControllerManager UpgradePhase = "ControllerManager"
+	// UpgradeScheduler upgrades the scheduler.
+	UpgradeScheduler UpgradePhase = "Scheduler"
+	// UpgradeMachines upgrades the kubelets of the machines one at a time.
+	UpgradeMachines UpgradePhase = "Machines"
+)
+
+// UpgradeStatus is the status of a Kubernetes version upgrade in progress.
+type UpgradeStatus struct {
+	// KubernetesVersion is the version the cluster is upgraded to.
+	KubernetesVersion string `json:"kubernetesVersion"`
+	// Phase is the current step of the upgrade. Each step waits for the previous one to be ready.
+	Phase UpgradePhase `json:"phase"`
+	// Machine is the machine that is currently upgraded in the Machines phase.
+	Machine string `json:"machine,omitempty"`
+}
+
+// ControlPlaneStatus is the status of the control plane components. Components that are not part of the
+// control plane are omitted.
+type ControlPlaneStatus struct {
+	ETCD              ComponentStatus  `json:"etcd"`
+	APIServer         ComponentStatus  `json:"apiServer"`
+	ControllerManager *ComponentStatus `json:"controllerManager,omitempty"`
+	Scheduler         *ComponentStatus `json:"scheduler,omitempty"`
+}
+
+// ComponentStatus is the status of a control plane component.
+type ComponentStatus struct {
+	// Replicas is the desired number of replicas of the component.
+	Replicas int32 `json:"replicas"`
+	// ReadyReplicas is the number of ready replicas of the component.
+	ReadyReplicas int32 `json:"readyReplicas"`
+	// Ready indicates that all desired replicas are up to date and ready.
+	Ready bool `json:"ready"`
 }
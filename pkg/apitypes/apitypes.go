@@ -36,9 +36,14 @@ func (i *IP) DeepCopyInto(out *IP) {
 }
 
 func (i *IP) UnmarshalJSON(data []byte) error {
-	ip := net.ParseIP(string(data))
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(s)
 	if ip == nil {
-		return fmt.Errorf("invalid IP %q", string(data))
+		return fmt.Errorf("invalid IP %q", s)
 	}
 
 	i.IP = ip
@@ -29,6 +29,8 @@ import (
 
 const (
 	FinalizerName = "kubeception.cloud/certificate"
+
+	CertificateBlockType = "CERTIFICATE"
 )
 
 var logger = log.Log.WithName("certificate")
@@ -6,6 +6,7 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/hex"
+	"encoding/pem"
 	"fmt"
 	"net"
 
@@ -68,6 +69,15 @@ func UpdateSecret(secret *corev1.Secret, certData []byte) {
 	}
 
 	secret.Data[v1alpha1.CertificateDataKey] = certData
+	secret.Data[v1alpha1.CertificatePEMDataKey] = EncodeCertificate(certData)
+}
+
+// EncodeCertificate PEM-encodes the given DER certificate data.
+func EncodeCertificate(certData []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  CertificateBlockType,
+		Bytes: certData,
+	})
 }
 
 func GetCertificateFromSecret(ctx context.Context, c client.Client, key client.ObjectKey) (*x509.Certificate, error) {
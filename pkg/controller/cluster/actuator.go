@@ -16,36 +16,52 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/util/intstr"
-	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/tools/record"
 	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
 	"kubeception.cloud/kubeception/pkg/util/pointers"
 	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
 	"sigs.k8s.io/cluster-api/pkg/controller/cluster"
+	controllererror "sigs.k8s.io/cluster-api/pkg/controller/error"
 	controllerruntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
-	ETCDClientPort      = 2379
-	ETCDServiceName     = "etcd"
-	ETCDStatefulSetName = ETCDServiceName
-
 	APIServerPort           = 443
 	APIServerDeploymentName = "apiserver"
 	APIServerServiceName    = APIServerDeploymentName
 
-	KubeconfigSecretName = "kubeconfig"
+	KubeconfigSecretName                  = "kubeconfig"
+	ControllerManagerKubeconfigSecretName = "controller-manager-kubeconfig"
+	SchedulerKubeconfigSecretName         = "scheduler-kubeconfig"
+
+	// AdminUser is the user name of the administrative kubeconfig, which is a member of the masters group.
+	AdminUser = "kubeception:admin"
+	// MastersGroup is the group that is granted full access by the default RBAC policy.
+	MastersGroup = "system:masters"
+	// ControllerManagerUser is the user the default RBAC policy grants controller manager access to.
+	ControllerManagerUser = "system:kube-controller-manager"
+	// SchedulerUser is the user the default RBAC policy grants scheduler access to.
+	SchedulerUser = "system:kube-scheduler"
 
 	ControllerManagerDeploymentName = "controller-manager"
 
 	SchedulerDeploymentName = "scheduler"
+
+	APIServerPKIDir     = "/etc/kubernetes/pki/apiserver"
+	APIServerETCDPKIDir = "/etc/kubernetes/pki/etcd-client"
+	// ServiceAccountPKIDir is the directory the service account key volume is mounted at in the API server and
+	// the controller manager.
+	ServiceAccountPKIDir = "/etc/kubernetes/pki/service-account"
+	// ClusterSigningPKIDir is the directory the cluster signing volume is mounted at in the controller manager.
+	ClusterSigningPKIDir = "/etc/kubernetes/pki/cluster-signing"
 )
 
 // NewActuatorWithDeps instantiates a new actuator with the dependencies that are usually injected.
 // TODO: Remove this constructor as soon as the cluster api supports proper injection on the actuators.
-func NewActuatorWithDeps(ctx context.Context, c client.Client, scheme *runtime.Scheme) cluster.Actuator {
+func NewActuatorWithDeps(ctx context.Context, c client.Client, scheme *runtime.Scheme, recorder record.EventRecorder) cluster.Actuator {
 	return &actuator{
+		recorder:    recorder,
 		WithContext: controller.NewWithContext(ctx),
 		WithClient:  controller.NewWithClient(c),
 		WithScheme:  controller.NewWithScheme(scheme),
@@ -53,6 +69,7 @@ func NewActuatorWithDeps(ctx context.Context, c client.Client, scheme *runtime.S
 }
 
 type actuator struct {
+	recorder record.EventRecorder
 	controller.WithContext
 	controller.WithClient
 	controller.WithScheme
@@ -68,16 +85,49 @@ func (a *actuator) Reconcile(cluster *clusterv1alpha1.Cluster) error {
 }
 
 func (a *actuator) reconcile(ctx context.Context, cluster *clusterv1alpha1.Cluster, config *v1alpha1.ClusterConfig) error {
-	if err := a.reconcileETCD(ctx, cluster, &config.ControlPlane.ETCD); err != nil {
+	if err := a.reconcileNames(ctx, cluster); err != nil {
+		return err
+	}
+
+	plan, err := a.planControlPlaneUpgrade(ctx, cluster, config)
+	if err != nil {
 		return err
 	}
 
-	if err := a.reconcileAPIServer(ctx, cluster, config, &config.ControlPlane.APIServer); err != nil {
+	// The status is updated even if the control plane could not be reconciled completely.
+	if err := a.reconcileControlPlane(ctx, cluster, config, plan); err != nil {
+		if statusErr := a.updateStatus(ctx, cluster, config, plan.Upgrade); statusErr != nil && !IsRequeueAfterError(statusErr) {
+			return statusErr
+		}
+		return err
+	}
+	return a.updateStatus(ctx, cluster, config, plan.Upgrade)
+}
+
+// reconcileControlPlane reconciles the control plane components with the versions of the given upgrade plan. Once
+// the control plane runs the desired version and is ready, the machines are upgraded. While an upgrade is in
+// progress, the plan records its phase and a RequeueAfterError is returned.
+func (a *actuator) reconcileControlPlane(ctx context.Context, cluster *clusterv1alpha1.Cluster, config *v1alpha1.ClusterConfig, plan *upgradePlan) error {
+	if err := a.reconcileCA(ctx, cluster); err != nil {
+		return err
+	}
+
+	if err := a.reconcileServiceAccountKeyPair(ctx, cluster); err != nil {
+		return err
+	}
+
+	// A pending etcd scale operation does not block the reconciliation of the remaining components.
+	etcdErr := a.reconcileETCD(ctx, cluster, &config.ControlPlane.ETCD, config.Images)
+	if etcdErr != nil && !IsRequeueAfterError(etcdErr) {
+		return etcdErr
+	}
+
+	if err := a.reconcileAPIServer(ctx, cluster, config, &config.ControlPlane.APIServer, plan.APIServer); err != nil {
 		return err
 	}
 
 	if config.ControlPlane.ControllerManager != nil {
-		if err := a.reconcileControllerManager(ctx, cluster, config, config.ControlPlane.ControllerManager); err != nil {
+		if err := a.reconcileControllerManager(ctx, cluster, config, config.ControlPlane.ControllerManager, plan.ControllerManager); err != nil {
 			return err
 		}
 	} else {
@@ -87,7 +137,7 @@ func (a *actuator) reconcile(ctx context.Context, cluster *clusterv1alpha1.Clust
 	}
 
 	if config.ControlPlane.Scheduler != nil {
-		if err := a.reconcileScheduler(ctx, cluster, config, config.ControlPlane.Scheduler); err != nil {
+		if err := a.reconcileScheduler(ctx, cluster, config, config.ControlPlane.Scheduler, plan.Scheduler); err != nil {
 			return err
 		}
 	} else {
@@ -96,130 +146,104 @@ func (a *actuator) reconcile(ctx context.Context, cluster *clusterv1alpha1.Clust
 		}
 	}
 
-	return nil
-}
+	// Neither does an external address of the API server that has not been assigned yet.
+	exposureErr := a.reconcileExternalKubeconfigSecret(ctx, cluster, config.ControlPlane.APIServer.Exposure)
+	if exposureErr != nil && !IsRequeueAfterError(exposureErr) {
+		return exposureErr
+	}
 
-func (a *actuator) reconcileETCD(ctx context.Context, cluster *clusterv1alpha1.Cluster, etcd *v1alpha1.ETCD) error {
-	etcdService := &corev1.Service{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: cluster.Namespace,
-			Name:      ETCDServiceName,
-		},
+	if err := a.reconcileAddons(ctx, cluster, config, plan.Ready); err != nil {
+		return err
 	}
-	if _, err := controllerruntime.CreateOrUpdate(ctx, a.Client, etcdService, func() error {
-		etcdService.Spec.Selector = ETCDLabels
-		etcdService.Spec.Type = corev1.ServiceTypeClusterIP
-		etcdService.Spec.Ports = []corev1.ServicePort{
-			{
-				Port:       ETCDClientPort,
-				TargetPort: intstr.FromInt(ETCDClientPort),
-			},
+
+	if plan.Upgrade == nil && plan.Ready {
+		machine, err := a.upgradeMachines(ctx, cluster, plan.Version)
+		if err != nil {
+			return err
+		}
+		if machine != "" {
+			plan.Upgrade = &v1alpha1.UpgradeStatus{KubernetesVersion: plan.Version, Phase: v1alpha1.UpgradeMachines, Machine: machine}
 		}
-		util.SetMetaDataLabels(etcdService, ETCDLabels)
-		return controllerruntime.SetControllerReference(cluster, etcdService, a.Scheme)
-	}); err != nil {
-		return err
+	}
+	if etcdErr == nil && plan.Upgrade != nil {
+		return &controllererror.RequeueAfterError{RequeueAfter: UpgradeRequeueAfter}
 	}
 
-	etcdStatefulSet := &appsv1.StatefulSet{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: cluster.Namespace,
-			Name:      ETCDStatefulSetName,
-		},
+	if etcdErr != nil {
+		return etcdErr
 	}
-	_, err := controllerruntime.CreateOrUpdate(ctx, a.Client, etcdStatefulSet, func() error {
-		util.SetMetaDataLabels(etcdStatefulSet, ETCDLabels)
-		etcdStatefulSet.Spec = appsv1.StatefulSetSpec{
-			Replicas: pointers.Int32(1),
-			Selector: &metav1.LabelSelector{
-				MatchLabels: ETCDLabels,
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: ETCDLabels,
-				},
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:  "etcd",
-							Image: "quay.io/coreos/etcd:v3.3.12",
-							Command: []string{
-								"etcd",
-								fmt.Sprintf("--advertise-client-urls=http://%s:%d", ETCDServiceName, ETCDClientPort),
-								fmt.Sprintf("--listen-client-urls=http://0.0.0.0:%d", ETCDClientPort),
-							},
-							Ports: []corev1.ContainerPort{
-								{
-									ContainerPort: ETCDClientPort,
-									Name:          "etcd",
-								},
-							},
-						},
-					},
-				},
-			},
-		}
-		return controllerruntime.SetControllerReference(cluster, etcdStatefulSet, a.Scheme)
-	})
-	return err
+	return exposureErr
 }
 
-func (a *actuator) reconcileAPIServer(ctx context.Context, cluster *clusterv1alpha1.Cluster, config *v1alpha1.ClusterConfig, apiServer *v1alpha1.APIServer) error {
+func (a *actuator) reconcileAPIServer(ctx context.Context, cluster *clusterv1alpha1.Cluster, config *v1alpha1.ClusterConfig, apiServer *v1alpha1.APIServer, version string) error {
+	names := ClusterNames(cluster)
+	apiServerArgs, err := apiServerFlags(names, apiServer)
+	if err != nil {
+		return fmt.Errorf("invalid API server configuration: %v", err)
+	}
+
 	service := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: cluster.Namespace,
-			Name:      APIServerServiceName,
+			Name:      names.Scoped(APIServerServiceName),
 		},
 	}
 	if _, err := controllerruntime.CreateOrUpdate(ctx, a.Client, service, func() error {
-		util.SetMetaDataLabels(service, APIServerLabels)
-		service.Spec.Type = corev1.ServiceTypeNodePort
-		service.Spec.Selector = APIServerLabels
-		service.Spec.Ports = []corev1.ServicePort{
-			{
-				Port:       APIServerPort,
-				TargetPort: intstr.FromInt(APIServerPort),
-			},
-		}
+		util.SetMetaDataLabels(service, names.Labels(APIServerComponent))
+		service.Spec.Selector = names.Labels(APIServerComponent)
+		setAPIServerServiceExposure(service, apiServer.Exposure)
 		return nil
 	}); err != nil {
 		return err
 	}
 
+	if err := a.reconcileAPIServerCertificate(ctx, cluster, service, apiServer.Exposure); err != nil {
+		return err
+	}
+
+	if err := a.reconcileAPIServerIngress(ctx, cluster, apiServer.Exposure); err != nil {
+		return err
+	}
+
+	// The API server must not write to etcd while a snapshot is being restored.
+	replicas := pointers.DerefInt32OrDefault(apiServer.Replicas, DefaultAPIServerReplicas)
+	restoring, err := a.etcdRestoring(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	if restoring {
+		replicas = 0
+	}
+
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: cluster.Namespace,
-			Name:      APIServerDeploymentName,
+			Name:      names.Scoped(APIServerDeploymentName),
 		},
 	}
 	if _, err := controllerruntime.CreateOrUpdate(ctx, a.Client, deployment, func() error {
-		util.SetMetaDataLabels(deployment, APIServerLabels)
+		util.SetMetaDataLabels(deployment, names.Labels(APIServerComponent))
+		util.SetMetaDataAnnotation(deployment, KubernetesVersionAnnotation, version)
 		deployment.Spec = appsv1.DeploymentSpec{
-			Replicas: pointers.Int32(1),
+			Replicas: pointers.Int32(replicas),
 			Selector: &metav1.LabelSelector{
-				MatchLabels: APIServerLabels,
+				MatchLabels: names.Labels(APIServerComponent),
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: APIServerLabels,
-					Annotations: map[string]string{
-						"basic-auth": `kubeception,kubeception,kubeception,"system:masters"`,
-					},
+					Labels: names.Labels(APIServerComponent),
 				},
 				Spec: corev1.PodSpec{
+					Affinity:         podAntiAffinity(names.Labels(APIServerComponent)),
+					ImagePullSecrets: common.ImagePullSecrets(config.Images),
 					Containers: []corev1.Container{
 						{
 							Name:  "kube-apiserver",
-							Image: common.HyperkubeImageForConfig(config),
-							Command: []string{
+							Image: common.HyperkubeImage(config.Images, version),
+							Command: append([]string{
 								"/hyperkube",
 								"apiserver",
-								fmt.Sprintf("--etcd-servers=http://%s:%d", ETCDServiceName, ETCDClientPort),
-								fmt.Sprintf("--secure-port=%d", APIServerPort),
-								"--basic-auth-file=/etc/basic-auth/basic-auth",
-								"--authorization-mode=AlwaysAllow,RBAC,Node",
-								"--disable-admission-plugins=ServiceAccount",
-							},
+							}, apiServerArgs...),
 							Ports: []corev1.ContainerPort{
 								{
 									Name:          "apiserver",
@@ -228,108 +252,139 @@ func (a *actuator) reconcileAPIServer(ctx context.Context, cluster *clusterv1alp
 							},
 							VolumeMounts: []corev1.VolumeMount{
 								{
-									Name:      "basic-auth",
-									MountPath: "/etc/basic-auth",
+									Name:      "certificate",
+									MountPath: APIServerPKIDir,
+								},
+								{
+									Name:      "etcd-client-certificate",
+									MountPath: APIServerETCDPKIDir,
+								},
+								{
+									Name:      "service-account-key",
+									MountPath: ServiceAccountPKIDir,
 								},
 							},
 						},
 					},
 					Volumes: []corev1.Volume{
-						{
-							Name: "basic-auth",
-							VolumeSource: corev1.VolumeSource{
-								DownwardAPI: &corev1.DownwardAPIVolumeSource{
-									Items: []corev1.DownwardAPIVolumeFile{
-										{Path: "basic-auth", FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.annotations['basic-auth']"}},
-									},
-								},
-							},
-						},
+						CertificateVolume("certificate", names.Scoped(APIServerCertificateName), names.Scoped(CACertificateName)),
+						CertificateVolume("etcd-client-certificate", names.Scoped(ETCDClientCertificateName), names.Scoped(CACertificateName)),
+						ServiceAccountKeyVolume("service-account-key", names),
 					},
 				},
 			},
 		}
+		common.ApplyWorkloadSettings(&deployment.Spec.Template.Spec, &apiServer.WorkloadSettings)
+
 		return controllerruntime.SetControllerReference(cluster, deployment, a.Scheme)
 	}); err != nil {
 		return err
 	}
 
-	kubeConfigSecret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: cluster.Namespace,
-			Name:      KubeconfigSecretName,
-		},
+	if err := a.reconcilePodDisruptionBudget(ctx, cluster, names.Scoped(APIServerDeploymentName), APIServerComponent, 1); err != nil {
+		return err
 	}
-	if _, err := controllerruntime.CreateOrUpdate(ctx, a.Client, kubeConfigSecret, func() error {
-		if err := UpdateKubeconfigSecret(kubeConfigSecret, &clientcmdapi.Config{
-			APIVersion:  "v1",
-			Kind:        "Config",
-			Preferences: clientcmdapi.Preferences{},
-			Clusters: map[string]*clientcmdapi.Cluster{
-				"kubeception": {
-					Server:                fmt.Sprintf("https://%s:%d", APIServerServiceName, APIServerPort),
-					InsecureSkipTLSVerify: true,
-				},
-			},
-			Contexts: map[string]*clientcmdapi.Context{
-				"kubeception": {
-					Cluster:  "kubeception",
-					AuthInfo: "kubeception",
-				},
-			},
-			CurrentContext: "kubeception",
-			AuthInfos: map[string]*clientcmdapi.AuthInfo{
-				"kubeception": {
-					Username: "kubeception",
-					Password: "kubeception",
-				},
-			},
-		}); err != nil {
+
+	if err := a.reconcileClientCertificate(ctx, cluster, AdminCertificateName, AdminUser, MastersGroup); err != nil {
+		return err
+	}
+
+	return a.reconcileKubeconfigSecret(ctx, cluster, KubeconfigSecretName, AdminCertificateName)
+}
+
+// reconcileKubeconfigSecret reconciles a secret with a kubeconfig that authenticates against the API server
+// with the client certificate of the given name. The secret and certificate names are base names that are
+// scoped by the cluster.
+// If the certificate has not been issued yet, a RequeueAfterError is returned.
+func (a *actuator) reconcileKubeconfigSecret(ctx context.Context, cluster *clusterv1alpha1.Cluster, name, certificateName string) error {
+	server := fmt.Sprintf("https://%s:%d", ClusterNames(cluster).Scoped(APIServerServiceName), APIServerPort)
+	return a.reconcileKubeconfigSecretForServer(ctx, cluster, name, certificateName, server)
+}
+
+// reconcileKubeconfigSecretForServer reconciles a kubeconfig secret like reconcileKubeconfigSecret, with the
+// given server URL instead of the URL of the API server service.
+func (a *actuator) reconcileKubeconfigSecretForServer(ctx context.Context, cluster *clusterv1alpha1.Cluster, name, certificateName, server string) error {
+	names := ClusterNames(cluster)
+	caData, err := a.readCertificatePEM(ctx, cluster.Namespace, names.Scoped(CACertificateName))
+	if err != nil {
+		return err
+	}
+
+	certData, err := a.readCertificatePEM(ctx, cluster.Namespace, names.Scoped(certificateName))
+	if err != nil {
+		return err
+	}
+
+	keyData, err := a.readPrivateKeyPEM(ctx, cluster.Namespace, names.Scoped(certificateName))
+	if err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{ObjectMeta: util.ObjectMeta(cluster.Namespace, names.Scoped(name))}
+	_, err = controllerruntime.CreateOrUpdate(ctx, a.Client, secret, func() error {
+		if err := UpdateKubeconfigSecret(secret, NewKubeconfig(server, caData, certData, keyData)); err != nil {
 			return err
 		}
 
-		return controllerruntime.SetControllerReference(cluster, kubeConfigSecret, a.Scheme)
-	}); err != nil {
+		return controllerruntime.SetControllerReference(cluster, secret, a.Scheme)
+	})
+	return err
+}
+
+func (a *actuator) reconcileControllerManager(ctx context.Context, cluster *clusterv1alpha1.Cluster, config *v1alpha1.ClusterConfig, controllerManager *v1alpha1.ControllerManager, version string) error {
+	controllerManagerArgs, err := controllerManagerFlags(cluster, controllerManager)
+	if err != nil {
+		return fmt.Errorf("invalid controller manager configuration: %v", err)
+	}
+
+	if err := a.reconcileClientCertificate(ctx, cluster, ControllerManagerCertificateName, ControllerManagerUser); err != nil {
 		return err
 	}
 
-	return nil
-}
+	if err := a.reconcileKubeconfigSecret(ctx, cluster, ControllerManagerKubeconfigSecretName, ControllerManagerCertificateName); err != nil {
+		return err
+	}
 
-func (a *actuator) reconcileControllerManager(ctx context.Context, cluster *clusterv1alpha1.Cluster, config *v1alpha1.ClusterConfig, controllerManager *v1alpha1.ControllerManager) error {
+	names := ClusterNames(cluster)
 	deployment := &appsv1.Deployment{
-		ObjectMeta: util.ObjectMeta(cluster.Namespace, ControllerManagerDeploymentName),
+		ObjectMeta: util.ObjectMeta(cluster.Namespace, names.Scoped(ControllerManagerDeploymentName)),
 	}
 	if _, err := controllerruntime.CreateOrUpdate(ctx, a.Client, deployment, func() error {
-		util.SetMetaDataLabels(deployment, ControllerManagerLabels)
+		util.SetMetaDataLabels(deployment, names.Labels(ControllerManagerComponent))
+		util.SetMetaDataAnnotation(deployment, KubernetesVersionAnnotation, version)
 		deployment.Spec = appsv1.DeploymentSpec{
-			Replicas: pointers.Int32(1),
+			Replicas: pointers.Int32(pointers.DerefInt32OrDefault(controllerManager.Replicas, DefaultControllerManagerReplicas)),
 			Selector: &metav1.LabelSelector{
-				MatchLabels: ControllerManagerLabels,
+				MatchLabels: names.Labels(ControllerManagerComponent),
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: ControllerManagerLabels,
+					Labels: names.Labels(ControllerManagerComponent),
 				},
 				Spec: corev1.PodSpec{
 					AutomountServiceAccountToken: pointers.Bool(false),
+					Affinity:                     podAntiAffinity(names.Labels(ControllerManagerComponent)),
+					ImagePullSecrets:             common.ImagePullSecrets(config.Images),
 					Containers: []corev1.Container{
 						{
 							Name:  "controller-manager",
-							Image: common.HyperkubeImageForConfig(config),
-							Command: []string{
+							Image: common.HyperkubeImage(config.Images, version),
+							Command: append([]string{
 								"/hyperkube",
 								"controller-manager",
-								fmt.Sprintf("--service-cluster-ip-range=%s", cluster.Spec.ClusterNetwork.Services.CIDRBlocks[0]),
-								fmt.Sprintf("--cluster-cidr=%s", cluster.Spec.ClusterNetwork.Pods.CIDRBlocks[0]),
-								"--kubeconfig=/etc/kubeconfig/kubeconfig",
-								"--allocate-node-cidrs=true",
-								"--cluster-name=kubeception",
-							},
+							}, controllerManagerArgs...),
 							VolumeMounts: []corev1.VolumeMount{
 								{
 									Name:      "kubeconfig",
-									MountPath: "/etc/kubeconfig",
+									MountPath: KubeconfigDir,
+								},
+								{
+									Name:      "service-account-key",
+									MountPath: ServiceAccountPKIDir,
+								},
+								{
+									Name:      "cluster-signing",
+									MountPath: ClusterSigningPKIDir,
 								},
 							},
 						},
@@ -339,67 +394,89 @@ func (a *actuator) reconcileControllerManager(ctx context.Context, cluster *clus
 							Name: "kubeconfig",
 							VolumeSource: corev1.VolumeSource{
 								Secret: &corev1.SecretVolumeSource{
-									SecretName: KubeconfigSecretName,
+									SecretName: names.Scoped(ControllerManagerKubeconfigSecretName),
 								},
 							},
 						},
+						ServiceAccountKeyVolume("service-account-key", names),
+						ClusterSigningVolume("cluster-signing", names),
 					},
 				},
 			},
 		}
 
+		common.ApplyWorkloadSettings(&deployment.Spec.Template.Spec, &controllerManager.WorkloadSettings)
+
 		return controllerruntime.SetControllerReference(cluster, deployment, a.Scheme)
 	}); err != nil {
 		return err
 	}
 
-	return nil
+	return a.reconcilePodDisruptionBudget(ctx, cluster, names.Scoped(ControllerManagerDeploymentName), ControllerManagerComponent, 1)
 }
 
 func (a *actuator) deleteControllerManager(ctx context.Context, cluster *clusterv1alpha1.Cluster) error {
 	controllerManagerDeployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: cluster.Namespace,
-			Name:      ControllerManagerDeploymentName,
+			Name:      ClusterNames(cluster).Scoped(ControllerManagerDeploymentName),
 		},
 	}
-	return client.IgnoreNotFound(a.Client.Delete(ctx, controllerManagerDeployment))
+	if err := client.IgnoreNotFound(a.Client.Delete(ctx, controllerManagerDeployment)); err != nil {
+		return err
+	}
+	return a.deletePodDisruptionBudget(ctx, cluster, controllerManagerDeployment.Name)
 }
 
-func (a *actuator) reconcileScheduler(ctx context.Context, cluster *clusterv1alpha1.Cluster, config *v1alpha1.ClusterConfig, scheduler *v1alpha1.Scheduler) error {
+func (a *actuator) reconcileScheduler(ctx context.Context, cluster *clusterv1alpha1.Cluster, config *v1alpha1.ClusterConfig, scheduler *v1alpha1.Scheduler, version string) error {
+	schedulerArgs, err := schedulerFlags(scheduler)
+	if err != nil {
+		return fmt.Errorf("invalid scheduler configuration: %v", err)
+	}
+
+	if err := a.reconcileClientCertificate(ctx, cluster, SchedulerCertificateName, SchedulerUser); err != nil {
+		return err
+	}
+
+	if err := a.reconcileKubeconfigSecret(ctx, cluster, SchedulerKubeconfigSecretName, SchedulerCertificateName); err != nil {
+		return err
+	}
+
+	names := ClusterNames(cluster)
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: cluster.Namespace,
-			Name:      SchedulerDeploymentName,
+			Name:      names.Scoped(SchedulerDeploymentName),
 		},
 	}
 	if _, err := controllerruntime.CreateOrUpdate(ctx, a.Client, deployment, func() error {
-		util.SetMetaDataLabels(deployment, SchedulerLabels)
+		util.SetMetaDataLabels(deployment, names.Labels(SchedulerComponent))
+		util.SetMetaDataAnnotation(deployment, KubernetesVersionAnnotation, version)
 		deployment.Spec = appsv1.DeploymentSpec{
-			Replicas: pointers.Int32(1),
+			Replicas: pointers.Int32(pointers.DerefInt32OrDefault(scheduler.Replicas, DefaultSchedulerReplicas)),
 			Selector: &metav1.LabelSelector{
-				MatchLabels: SchedulerLabels,
+				MatchLabels: names.Labels(SchedulerComponent),
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: SchedulerLabels,
+					Labels: names.Labels(SchedulerComponent),
 				},
 				Spec: corev1.PodSpec{
 					AutomountServiceAccountToken: pointers.Bool(false),
+					Affinity:                     podAntiAffinity(names.Labels(SchedulerComponent)),
+					ImagePullSecrets:             common.ImagePullSecrets(config.Images),
 					Containers: []corev1.Container{
 						{
 							Name:  "scheduler",
-							Image: common.HyperkubeImageForConfig(config),
-							Command: []string{
+							Image: common.HyperkubeImage(config.Images, version),
+							Command: append([]string{
 								"/hyperkube",
 								"scheduler",
-								"--kubeconfig=/etc/kubeconfig/kubeconfig",
-								"--authorization-kubeconfig=/etc/kubeconfig/kubeconfig",
-							},
+							}, schedulerArgs...),
 							VolumeMounts: []corev1.VolumeMount{
 								{
 									Name:      "kubeconfig",
-									MountPath: "/etc/kubeconfig",
+									MountPath: KubeconfigDir,
 								},
 							},
 						},
@@ -409,7 +486,7 @@ func (a *actuator) reconcileScheduler(ctx context.Context, cluster *clusterv1alp
 							Name: "kubeconfig",
 							VolumeSource: corev1.VolumeSource{
 								Secret: &corev1.SecretVolumeSource{
-									SecretName: KubeconfigSecretName,
+									SecretName: names.Scoped(SchedulerKubeconfigSecretName),
 								},
 							},
 						},
@@ -418,22 +495,37 @@ func (a *actuator) reconcileScheduler(ctx context.Context, cluster *clusterv1alp
 			},
 		}
 
+		if volume := schedulerConfigVolume(scheduler); volume != nil {
+			podSpec := &deployment.Spec.Template.Spec
+			podSpec.Volumes = append(podSpec.Volumes, *volume)
+			podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+				Name:      volume.Name,
+				MountPath: SchedulerConfigDir,
+				ReadOnly:  true,
+			})
+		}
+
+		common.ApplyWorkloadSettings(&deployment.Spec.Template.Spec, &scheduler.WorkloadSettings)
+
 		return controllerruntime.SetControllerReference(cluster, deployment, a.Scheme)
 	}); err != nil {
 		return err
 	}
 
-	return nil
+	return a.reconcilePodDisruptionBudget(ctx, cluster, names.Scoped(SchedulerDeploymentName), SchedulerComponent, 1)
 }
 
 func (a *actuator) deleteScheduler(ctx context.Context, cluster *clusterv1alpha1.Cluster) error {
 	schedulerDeployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: cluster.Namespace,
-			Name:      SchedulerDeploymentName,
+			Name:      ClusterNames(cluster).Scoped(SchedulerDeploymentName),
 		},
 	}
-	return client.IgnoreNotFound(a.Client.Delete(ctx, schedulerDeployment))
+	if err := client.IgnoreNotFound(a.Client.Delete(ctx, schedulerDeployment)); err != nil {
+		return err
+	}
+	return a.deletePodDisruptionBudget(ctx, cluster, schedulerDeployment.Name)
 }
 
 func (a *actuator) Delete(cluster *clusterv1alpha1.Cluster) error {
@@ -444,7 +536,3 @@ func (a *actuator) Delete(cluster *clusterv1alpha1.Cluster) error {
 
 	return a.delete(a.Context, cluster, config)
 }
-
-func (a *actuator) delete(ctx context.Context, cluster *clusterv1alpha1.Cluster, config *v1alpha1.ClusterConfig) error {
-	return nil
-}
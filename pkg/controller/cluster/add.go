@@ -6,8 +6,12 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
+const (
+	Name = "cluster"
+)
+
 // AddToManager adds the cluster controller with the kubeception actuator to the cluster.
 func AddToManager(mgr manager.Manager) error {
 	ctx := context.TODO()
-	return cluster.AddWithActuator(mgr, NewActuatorWithDeps(ctx, mgr.GetClient(), mgr.GetScheme()))
+	return cluster.AddWithActuator(mgr, NewActuatorWithDeps(ctx, mgr.GetClient(), mgr.GetScheme(), mgr.GetEventRecorderFor(Name)))
 }
@@ -0,0 +1,56 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/util"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllererror "sigs.k8s.io/cluster-api/pkg/controller/error"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// AddonRequeueAfter is the duration after which the addons of a cluster whose control plane is not ready yet
+	// are applied again.
+	AddonRequeueAfter = 10 * time.Second
+)
+
+// NewClientForCluster returns a client for the API server of the given cluster that authenticates with its
+// administrative kubeconfig, which is read with the given client.
+func NewClientForCluster(ctx context.Context, c client.Client, cluster *clusterv1alpha1.Cluster) (client.Client, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, util.Key(cluster.Namespace, ClusterNames(cluster).Scoped(KubeconfigSecretName)), secret); err != nil {
+		return nil, err
+	}
+	return NewClientForKubeconfigSecret(secret)
+}
+
+// reconcileAddons applies the RBAC rules for the TLS bootstrapping of kubelets and the configured addons to the
+// hosted cluster. As this requires the API server of the cluster, a RequeueAfterError is returned while its
+// control plane is not ready.
+func (a *actuator) reconcileAddons(ctx context.Context, cluster *clusterv1alpha1.Cluster, config *v1alpha1.ClusterConfig, controlPlaneReady bool) error {
+	if !controlPlaneReady {
+		return &controllererror.RequeueAfterError{RequeueAfter: AddonRequeueAfter}
+	}
+
+	guest, err := NewClientForCluster(ctx, a.Client, cluster)
+	if err != nil {
+		return fmt.Errorf("could not create client for cluster %s: %v", cluster.Name, err)
+	}
+
+	if err := reconcileBootstrapRBAC(ctx, guest); err != nil {
+		return err
+	}
+
+	if config.Network != nil {
+		if err := reconcileNetwork(ctx, guest, cluster, config, config.Network); err != nil {
+			return err
+		}
+	}
+
+	return reconcileDNS(ctx, guest, cluster, config.DNS)
+}
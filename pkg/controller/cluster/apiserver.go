@@ -0,0 +1,221 @@
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/util/flags"
+)
+
+const (
+	// DefaultAPIServerReplicas is the default number of API server replicas. With a single replica, draining
+	// the node of the hosting cluster it runs on would take the API of the cluster down.
+	DefaultAPIServerReplicas = 2
+	// DefaultServiceAccountIssuer is the issuer of service account tokens of API servers that do not configure
+	// any via extra arguments.
+	DefaultServiceAccountIssuer = "https://kubernetes.default.svc"
+)
+
+var (
+	// DefaultAuthorizationModes are the authorization modes of API servers that do not configure any.
+	// The control plane components and the kubelets authenticate with their own identities, so that they are
+	// authorized by the Node and RBAC authorizers like in any other cluster.
+	DefaultAuthorizationModes = []string{"Node", "RBAC"}
+	// DefaultAdmissionPlugins are the admission plugins that are enabled in addition to the default plugins of the
+	// API server unless they are disabled explicitly. NodeRestriction confines the kubelets to their own node.
+	DefaultAdmissionPlugins = []string{"NodeRestriction"}
+
+	supportedAuthorizationModes = map[string]bool{
+		"AlwaysAllow": true,
+		"AlwaysDeny":  true,
+		"Node":        true,
+		"RBAC":        true,
+	}
+
+	// apiServerManagedFlags are the API server flags that are rendered by kubeception and cannot be overridden.
+	apiServerManagedFlags = []string{
+		"etcd-servers",
+		"etcd-cafile",
+		"etcd-certfile",
+		"etcd-keyfile",
+		"secure-port",
+		"tls-cert-file",
+		"tls-private-key-file",
+		"client-ca-file",
+		"endpoint-reconciler-type",
+		"service-account-key-file",
+		"service-account-signing-key-file",
+		"enable-bootstrap-token-auth",
+	}
+)
+
+func validateNames(field string, values []string) error {
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		if value == "" || strings.ContainsAny(value, ", ") {
+			return fmt.Errorf("invalid %s %q", field, value)
+		}
+		if seen[value] {
+			return fmt.Errorf("duplicate %s %q", field, value)
+		}
+		seen[value] = true
+	}
+	return nil
+}
+
+func validatePortRange(portRange string) error {
+	parts := strings.Split(portRange, "-")
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid port range %q, must be of the form <min>-<max>", portRange)
+	}
+
+	min, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return fmt.Errorf("invalid port range %q: %v", portRange, err)
+	}
+	max, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return fmt.Errorf("invalid port range %q: %v", portRange, err)
+	}
+
+	if min < 1 || max > 65535 || min > max {
+		return fmt.Errorf("invalid port range %q, must be within 1-65535", portRange)
+	}
+	return nil
+}
+
+func validateFeatureGates(featureGates map[string]bool) error {
+	gates := make(map[string]string, len(featureGates))
+	for gate := range featureGates {
+		gates[gate] = ""
+	}
+	if err := flags.ValidateMap(gates); err != nil {
+		return fmt.Errorf("invalid feature gates: %v", err)
+	}
+	return nil
+}
+
+// validateAPIServer validates the given API server configuration.
+func validateAPIServer(apiServer *v1alpha1.APIServer) error {
+	if apiServer.Replicas != nil && *apiServer.Replicas < 0 {
+		return fmt.Errorf("invalid negative number of replicas %d", *apiServer.Replicas)
+	}
+
+	if err := validateNames("authorization mode", apiServer.AuthorizationModes); err != nil {
+		return err
+	}
+	for _, mode := range apiServer.AuthorizationModes {
+		if !supportedAuthorizationModes[mode] {
+			return fmt.Errorf("unsupported authorization mode %q", mode)
+		}
+	}
+
+	if err := validateNames("admission plugin", apiServer.EnableAdmissionPlugins); err != nil {
+		return err
+	}
+	if err := validateNames("admission plugin", apiServer.DisableAdmissionPlugins); err != nil {
+		return err
+	}
+	for _, enabled := range apiServer.EnableAdmissionPlugins {
+		for _, disabled := range apiServer.DisableAdmissionPlugins {
+			if enabled == disabled {
+				return fmt.Errorf("admission plugin %q cannot be both enabled and disabled", enabled)
+			}
+		}
+	}
+
+	if err := validateFeatureGates(apiServer.FeatureGates); err != nil {
+		return err
+	}
+
+	if apiServer.ServiceNodePortRange != "" {
+		if err := validatePortRange(apiServer.ServiceNodePortRange); err != nil {
+			return err
+		}
+	}
+
+	if err := flags.ValidateMap(apiServer.RuntimeConfig); err != nil {
+		return fmt.Errorf("invalid runtime config: %v", err)
+	}
+	return validateExposure(apiServer.Exposure)
+}
+
+// enabledAdmissionPlugins returns the default admission plugins that are not disabled followed by the admission
+// plugins enabled by the given configuration.
+func enabledAdmissionPlugins(apiServer *v1alpha1.APIServer) []string {
+	disabled := make(map[string]bool, len(apiServer.DisableAdmissionPlugins))
+	for _, plugin := range apiServer.DisableAdmissionPlugins {
+		disabled[plugin] = true
+	}
+
+	var enabled []string
+	seen := make(map[string]bool)
+	for _, plugin := range append(append([]string{}, DefaultAdmissionPlugins...), apiServer.EnableAdmissionPlugins...) {
+		if disabled[plugin] || seen[plugin] {
+			continue
+		}
+		seen[plugin] = true
+		enabled = append(enabled, plugin)
+	}
+	return enabled
+}
+
+// apiServerFlags renders the flags of the API server of the cluster with the given names from the given
+// configuration.
+func apiServerFlags(names Names, apiServer *v1alpha1.APIServer) ([]string, error) {
+	if err := validateAPIServer(apiServer); err != nil {
+		return nil, err
+	}
+
+	f := flags.New()
+	f.Set("etcd-servers", fmt.Sprintf("https://%s:%d", names.Scoped(ETCDServiceName), ETCDClientPort))
+	f.Set("etcd-cafile", fmt.Sprintf("%s/%s", APIServerETCDPKIDir, CAFile))
+	f.Set("etcd-certfile", fmt.Sprintf("%s/%s", APIServerETCDPKIDir, CertFile))
+	f.Set("etcd-keyfile", fmt.Sprintf("%s/%s", APIServerETCDPKIDir, KeyFile))
+	f.Set("secure-port", strconv.Itoa(APIServerPort))
+	f.Set("tls-cert-file", fmt.Sprintf("%s/%s", APIServerPKIDir, CertFile))
+	f.Set("tls-private-key-file", fmt.Sprintf("%s/%s", APIServerPKIDir, KeyFile))
+	f.Set("client-ca-file", fmt.Sprintf("%s/%s", APIServerPKIDir, CAFile))
+	// The replicas maintain the endpoints of the kubernetes service via leases, so that the endpoints of
+	// replicas that are gone expire.
+	f.Set("endpoint-reconciler-type", "lease")
+	// Service account tokens are signed by the controller manager and the token request API with the service
+	// account key pair of the cluster.
+	f.Set("service-account-key-file", fmt.Sprintf("%s/%s", ServiceAccountPKIDir, ServiceAccountKeyFile))
+	f.Set("service-account-signing-key-file", fmt.Sprintf("%s/%s", ServiceAccountPKIDir, ServiceAccountKeyFile))
+	f.Set("service-account-issuer", DefaultServiceAccountIssuer)
+	f.Set("enable-bootstrap-token-auth", "true")
+	// The nodes of machines with storage are named after their machine, which does not resolve, so that the
+	// kubelets are reached via their IP.
+	f.Set("kubelet-preferred-address-types", "InternalIP,Hostname,InternalDNS,ExternalDNS,ExternalIP")
+
+	authorizationModes := apiServer.AuthorizationModes
+	if len(authorizationModes) == 0 {
+		authorizationModes = DefaultAuthorizationModes
+	}
+	f.Set("authorization-mode", strings.Join(authorizationModes, ","))
+
+	if enabled := enabledAdmissionPlugins(apiServer); len(enabled) > 0 {
+		f.Set("enable-admission-plugins", strings.Join(enabled, ","))
+	}
+	if len(apiServer.DisableAdmissionPlugins) > 0 {
+		f.Set("disable-admission-plugins", strings.Join(apiServer.DisableAdmissionPlugins, ","))
+	}
+
+	if len(apiServer.FeatureGates) > 0 {
+		f.Set("feature-gates", flags.JoinFeatureGates(apiServer.FeatureGates))
+	}
+	if apiServer.ServiceNodePortRange != "" {
+		f.Set("service-node-port-range", apiServer.ServiceNodePortRange)
+	}
+	if len(apiServer.RuntimeConfig) > 0 {
+		f.Set("runtime-config", flags.JoinMap(apiServer.RuntimeConfig))
+	}
+
+	if err := f.SetExtraArgs(apiServer.ExtraArgs, apiServerManagedFlags...); err != nil {
+		return nil, err
+	}
+	return f.Args(), nil
+}
@@ -0,0 +1,108 @@
+package cluster
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+)
+
+var _ = Describe("APIServer", func() {
+	names := Names{Cluster: "foo"}
+
+	Describe("#apiServerFlags", func() {
+		It("should render the defaults for an empty configuration", func() {
+			args, err := apiServerFlags(names, &v1alpha1.APIServer{})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(ContainElement("--etcd-servers=https://foo-etcd:2379"))
+			Expect(args).To(ContainElement("--authorization-mode=Node,RBAC"))
+			Expect(args).To(ContainElement("--enable-admission-plugins=NodeRestriction"))
+			Expect(args).NotTo(ContainElement(HavePrefix("--disable-admission-plugins")))
+			Expect(args).To(ContainElement("--endpoint-reconciler-type=lease"))
+			Expect(args).To(ContainElement("--service-account-key-file=/etc/kubernetes/pki/service-account/sa.key"))
+			Expect(args).To(ContainElement("--service-account-issuer=https://kubernetes.default.svc"))
+			Expect(args).To(ContainElement("--enable-bootstrap-token-auth=true"))
+			Expect(args).To(ContainElement("--kubelet-preferred-address-types=InternalIP,Hostname,InternalDNS,ExternalDNS,ExternalIP"))
+		})
+
+		It("should reject a negative number of replicas", func() {
+			replicas := int32(-1)
+			_, err := apiServerFlags(names, &v1alpha1.APIServer{Replicas: &replicas})
+
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should render the configured flags", func() {
+			args, err := apiServerFlags(names, &v1alpha1.APIServer{
+				AuthorizationModes:      []string{"Node", "RBAC"},
+				EnableAdmissionPlugins:  []string{"AlwaysPullImages", "NodeRestriction"},
+				DisableAdmissionPlugins: []string{"DefaultStorageClass"},
+				FeatureGates:            map[string]bool{"TTLAfterFinished": true},
+				ServiceNodePortRange:    "30000-31000",
+				RuntimeConfig:           map[string]string{"api/all": "true"},
+				ExtraArgs:               map[string]string{"v": "4"},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(ContainElement("--authorization-mode=Node,RBAC"))
+			Expect(args).To(ContainElement("--enable-admission-plugins=NodeRestriction,AlwaysPullImages"))
+			Expect(args).To(ContainElement("--disable-admission-plugins=DefaultStorageClass"))
+			Expect(args).To(ContainElement("--feature-gates=TTLAfterFinished=true"))
+			Expect(args).To(ContainElement("--service-node-port-range=30000-31000"))
+			Expect(args).To(ContainElement("--runtime-config=api/all=true"))
+			Expect(args).To(ContainElement("--v=4"))
+		})
+
+		It("should let extra arguments override rendered flags", func() {
+			args, err := apiServerFlags(names, &v1alpha1.APIServer{
+				ExtraArgs: map[string]string{"authorization-mode": "RBAC"},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(ContainElement("--authorization-mode=RBAC"))
+			Expect(args).NotTo(ContainElement("--authorization-mode=Node,RBAC"))
+		})
+
+		It("should not enable default admission plugins that are disabled", func() {
+			args, err := apiServerFlags(names, &v1alpha1.APIServer{
+				DisableAdmissionPlugins: []string{"NodeRestriction"},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).NotTo(ContainElement(HavePrefix("--enable-admission-plugins")))
+			Expect(args).To(ContainElement("--disable-admission-plugins=NodeRestriction"))
+		})
+
+		It("should reject extra arguments overriding managed flags", func() {
+			_, err := apiServerFlags(names, &v1alpha1.APIServer{
+				ExtraArgs: map[string]string{"etcd-servers": "https://other:2379"},
+			})
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("#validateAPIServer", func() {
+		It("should reject unsupported authorization modes", func() {
+			Expect(validateAPIServer(&v1alpha1.APIServer{AuthorizationModes: []string{"Webhook"}})).NotTo(Succeed())
+			Expect(validateAPIServer(&v1alpha1.APIServer{AuthorizationModes: []string{"RBAC", "RBAC"}})).NotTo(Succeed())
+		})
+
+		It("should reject admission plugins that are both enabled and disabled", func() {
+			Expect(validateAPIServer(&v1alpha1.APIServer{
+				EnableAdmissionPlugins:  []string{"ServiceAccount"},
+				DisableAdmissionPlugins: []string{"ServiceAccount"},
+			})).NotTo(Succeed())
+		})
+
+		It("should reject invalid node port ranges", func() {
+			for _, portRange := range []string{"30000", "a-b", "32767-30000", "0-100", "30000-70000"} {
+				Expect(validateAPIServer(&v1alpha1.APIServer{ServiceNodePortRange: portRange})).NotTo(Succeed(), portRange)
+			}
+		})
+
+		It("should reject ambiguous runtime config", func() {
+			Expect(validateAPIServer(&v1alpha1.APIServer{RuntimeConfig: map[string]string{"api/all": "true,false"}})).NotTo(Succeed())
+		})
+	})
+})
@@ -0,0 +1,93 @@
+package cluster
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"kubeception.cloud/kubeception/pkg/util"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// HostnameTopologyKey is the node label the replicas of a control plane component are spread across.
+	HostnameTopologyKey = "kubernetes.io/hostname"
+)
+
+// podAntiAffinity returns an affinity that prefers to schedule pods with the given labels on different nodes
+// of the hosting cluster. The anti-affinity is not required, so that hosting clusters with fewer nodes than
+// replicas can still run all replicas.
+func podAntiAffinity(labels map[string]string) *corev1.Affinity {
+	return &corev1.Affinity{
+		PodAntiAffinity: &corev1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+				{
+					Weight: 100,
+					PodAffinityTerm: corev1.PodAffinityTerm{
+						LabelSelector: &metav1.LabelSelector{MatchLabels: labels},
+						TopologyKey:   HostnameTopologyKey,
+					},
+				},
+			},
+		},
+	}
+}
+
+// etcdMaxUnavailable returns the number of etcd members that may be disrupted without losing quorum.
+// A single member may be disrupted, as otherwise the nodes of the hosting cluster could not be drained, unless
+// there are two members: Both are needed for quorum, so none of them may be disrupted.
+func etcdMaxUnavailable(replicas int32) int32 {
+	if replicas == 2 {
+		return 0
+	}
+	if maxUnavailable := (replicas - 1) / 2; maxUnavailable > 1 {
+		return maxUnavailable
+	}
+	return 1
+}
+
+// reconcilePodDisruptionBudget reconciles a PodDisruptionBudget with the given name that allows the given
+// number of pods of the given control plane component to be disrupted at once. As the spec of a
+// PodDisruptionBudget cannot be updated before Kubernetes 1.15, a budget with a different spec is recreated.
+func (a *actuator) reconcilePodDisruptionBudget(ctx context.Context, cluster *clusterv1alpha1.Cluster, name, component string, maxUnavailable int32) error {
+	labels := ClusterNames(cluster).Labels(component)
+	maxUnavailableValue := intstr.FromInt(int(maxUnavailable))
+	spec := policyv1beta1.PodDisruptionBudgetSpec{
+		MaxUnavailable: &maxUnavailableValue,
+		Selector:       &metav1.LabelSelector{MatchLabels: labels},
+	}
+
+	existing := &policyv1beta1.PodDisruptionBudget{}
+	exists, err := a.getObject(ctx, cluster.Namespace, name, existing)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if apiequality.Semantic.DeepEqual(existing.Spec, spec) {
+			return nil
+		}
+		if err := client.IgnoreNotFound(a.Client.Delete(ctx, existing)); err != nil {
+			return err
+		}
+	}
+
+	budget := &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: util.ObjectMeta(cluster.Namespace, name),
+		Spec:       spec,
+	}
+	util.SetMetaDataLabels(budget, labels)
+	if err := controllerruntime.SetControllerReference(cluster, budget, a.Scheme); err != nil {
+		return err
+	}
+	return a.Client.Create(ctx, budget)
+}
+
+func (a *actuator) deletePodDisruptionBudget(ctx context.Context, cluster *clusterv1alpha1.Cluster, name string) error {
+	budget := &policyv1beta1.PodDisruptionBudget{ObjectMeta: util.ObjectMeta(cluster.Namespace, name)}
+	return client.IgnoreNotFound(a.Client.Delete(ctx, budget))
+}
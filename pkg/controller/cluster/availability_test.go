@@ -0,0 +1,39 @@
+package cluster
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Availability", func() {
+	Describe("#etcdMaxUnavailable", func() {
+		It("should allow disrupting as many members as possible without losing quorum", func() {
+			Expect(etcdMaxUnavailable(3)).To(Equal(int32(1)))
+			Expect(etcdMaxUnavailable(5)).To(Equal(int32(2)))
+			Expect(etcdMaxUnavailable(7)).To(Equal(int32(3)))
+		})
+
+		It("should allow disrupting a single member of smaller clusters", func() {
+			Expect(etcdMaxUnavailable(0)).To(Equal(int32(1)))
+			Expect(etcdMaxUnavailable(1)).To(Equal(int32(1)))
+		})
+
+		It("should not allow disrupting any of two members", func() {
+			Expect(etcdMaxUnavailable(2)).To(Equal(int32(0)))
+		})
+	})
+
+	Describe("#podAntiAffinity", func() {
+		It("should prefer spreading the pods with the given labels across nodes", func() {
+			labels := Names{Cluster: "foo"}.Labels(APIServerComponent)
+			affinity := podAntiAffinity(labels)
+
+			Expect(affinity.PodAntiAffinity).NotTo(BeNil())
+			Expect(affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution).To(BeEmpty())
+			terms := affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+			Expect(terms).To(HaveLen(1))
+			Expect(terms[0].PodAffinityTerm.TopologyKey).To(Equal(HostnameTopologyKey))
+			Expect(terms[0].PodAffinityTerm.LabelSelector.MatchLabels).To(Equal(labels))
+		})
+	})
+})
@@ -0,0 +1,53 @@
+package cluster
+
+import (
+	"context"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// NodeBootstrapTokenGroup is the group the kubelets of the machines authenticate in with their bootstrap tokens.
+	NodeBootstrapTokenGroup = "system:bootstrappers:kubeception:default-node-token"
+)
+
+// bootstrapClusterRoleBindings bind the cluster roles that allow kubelets to request their client certificates with
+// bootstrap tokens and have them approved automatically, as well as to renew them.
+var bootstrapClusterRoleBindings = []struct {
+	name        string
+	clusterRole string
+	group       string
+}{
+	{"kubeception:kubelet-bootstrap", "system:node-bootstrapper", NodeBootstrapTokenGroup},
+	{"kubeception:node-autoapprove-bootstrap", "system:certificates.k8s.io:certificatesigningrequests:nodeclient", NodeBootstrapTokenGroup},
+	{"kubeception:node-autoapprove-certificate-rotation", "system:certificates.k8s.io:certificatesigningrequests:selfnodeclient", "system:nodes"},
+}
+
+// reconcileBootstrapRBAC applies the RBAC rules for the TLS bootstrapping of kubelets to the hosted cluster with
+// the given client.
+func reconcileBootstrapRBAC(ctx context.Context, guest client.Client) error {
+	for _, binding := range bootstrapClusterRoleBindings {
+		clusterRoleBinding := &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: binding.name}}
+		if _, err := controllerruntime.CreateOrUpdate(ctx, guest, clusterRoleBinding, func() error {
+			clusterRoleBinding.RoleRef = rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "ClusterRole",
+				Name:     binding.clusterRole,
+			}
+			clusterRoleBinding.Subjects = []rbacv1.Subject{
+				{
+					APIGroup: rbacv1.GroupName,
+					Kind:     rbacv1.GroupKind,
+					Name:     binding.group,
+				},
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package cluster
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Bootstrap", func() {
+	Describe("#apiServerFlags", func() {
+		It("should confine bootstrapped kubelets to their node by default", func() {
+			args, err := apiServerFlags(Names{Cluster: "foo"}, &v1alpha1.APIServer{})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(ContainElement("--enable-bootstrap-token-auth=true"))
+			Expect(args).To(ContainElement("--authorization-mode=Node,RBAC"))
+			Expect(args).To(ContainElement("--enable-admission-plugins=NodeRestriction"))
+			Expect(args).NotTo(ContainElement(ContainSubstring("AlwaysAllow")))
+		})
+	})
+
+	Describe("#reconcileBootstrapRBAC", func() {
+		It("should only allow the bootstrap token group to request node client certificates", func() {
+			ctx := context.Background()
+			guest := fake.NewFakeClientWithScheme(scheme.Scheme)
+
+			Expect(reconcileBootstrapRBAC(ctx, guest)).To(Succeed())
+
+			clusterRoleBindings := &rbacv1.ClusterRoleBindingList{}
+			Expect(guest.List(ctx, clusterRoleBindings)).To(Succeed())
+
+			var bootstrapRoles []string
+			for _, clusterRoleBinding := range clusterRoleBindings.Items {
+				for _, subject := range clusterRoleBinding.Subjects {
+					if subject.Kind == rbacv1.GroupKind && subject.Name == NodeBootstrapTokenGroup {
+						bootstrapRoles = append(bootstrapRoles, clusterRoleBinding.RoleRef.Name)
+					}
+				}
+			}
+			Expect(bootstrapRoles).To(ConsistOf(
+				"system:node-bootstrapper",
+				"system:certificates.k8s.io:certificatesigningrequests:nodeclient",
+			))
+		})
+	})
+})
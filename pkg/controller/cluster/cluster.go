@@ -4,14 +4,73 @@ import (
 	"fmt"
 
 	corev1 "k8s.io/api/core/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	controllererror "sigs.k8s.io/cluster-api/pkg/controller/error"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
 	KubeconfigField = "kubeconfig"
+
+	kubeconfigName = "kubeception"
 )
 
+// NewKubeconfig creates a new clientcmdapi.Config that authenticates against the given server with
+// the given client certificate and key, verifying the server with the given certificate authority.
+func NewKubeconfig(server string, caData, certData, keyData []byte) *clientcmdapi.Config {
+	return newKubeconfig(server, caData, &clientcmdapi.AuthInfo{
+		ClientCertificateData: certData,
+		ClientKeyData:         keyData,
+	})
+}
+
+// NewTokenKubeconfig creates a new clientcmdapi.Config that authenticates against the given server with
+// the given bearer token, verifying the server with the given certificate authority.
+func NewTokenKubeconfig(server string, caData []byte, token string) *clientcmdapi.Config {
+	return newKubeconfig(server, caData, &clientcmdapi.AuthInfo{Token: token})
+}
+
+func newKubeconfig(server string, caData []byte, authInfo *clientcmdapi.AuthInfo) *clientcmdapi.Config {
+	return &clientcmdapi.Config{
+		APIVersion:  "v1",
+		Kind:        "Config",
+		Preferences: clientcmdapi.Preferences{},
+		Clusters: map[string]*clientcmdapi.Cluster{
+			kubeconfigName: {
+				Server:                   server,
+				CertificateAuthorityData: caData,
+			},
+		},
+		Contexts: map[string]*clientcmdapi.Context{
+			kubeconfigName: {
+				Cluster:  kubeconfigName,
+				AuthInfo: kubeconfigName,
+			},
+		},
+		CurrentContext: kubeconfigName,
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			kubeconfigName: authInfo,
+		},
+	}
+}
+
+// KubeconfigCluster returns the cluster of the current context of the given clientcmdapi.Config.
+func KubeconfigCluster(config *clientcmdapi.Config) (*clientcmdapi.Cluster, error) {
+	context, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return nil, fmt.Errorf("kubeconfig does not contain current context %q", config.CurrentContext)
+	}
+
+	cluster, ok := config.Clusters[context.Cluster]
+	if !ok {
+		return nil, fmt.Errorf("kubeconfig does not contain cluster %q", context.Cluster)
+	}
+	return cluster, nil
+}
+
 // ReadKubeconfigSecret reads the clientcmdapi.Config from the given secret.
 func ReadKubeconfigSecret(secret *corev1.Secret) (*clientcmdapi.Config, error) {
 	if secret.Data == nil {
@@ -29,6 +88,26 @@ func ReadKubeconfigSecret(secret *corev1.Secret) (*clientcmdapi.Config, error) {
 	return clientcmd.Load(data)
 }
 
+// NewRESTConfigForKubeconfigSecret creates a rest.Config for the API server of the kubeconfig in the given secret.
+func NewRESTConfigForKubeconfigSecret(secret *corev1.Secret) (*rest.Config, error) {
+	config, err := ReadKubeconfigSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}).ClientConfig()
+}
+
+// NewClientForKubeconfigSecret creates a client for the API server of the kubeconfig in the given secret. The
+// client knows the built-in Kubernetes types.
+func NewClientForKubeconfigSecret(secret *corev1.Secret) (client.Client, error) {
+	restConfig, err := NewRESTConfigForKubeconfigSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	return client.New(restConfig, client.Options{Scheme: clientgoscheme.Scheme})
+}
+
 // UpdateKubeconfigSecret updates the given secret to contain the given clientcmdapi.Config at the data KubeconfigField.
 func UpdateKubeconfigSecret(secret *corev1.Secret, config *clientcmdapi.Config) error {
 	data, err := clientcmd.Write(*config)
@@ -43,3 +122,10 @@ func UpdateKubeconfigSecret(secret *corev1.Secret, config *clientcmdapi.Config)
 	secret.Data[KubeconfigField] = data
 	return nil
 }
+
+// IsRequeueAfterError checks whether the given error only signals that the reconciliation should be retried
+// after some time.
+func IsRequeueAfterError(err error) bool {
+	_, ok := err.(*controllererror.RequeueAfterError)
+	return ok
+}
@@ -1,13 +1,24 @@
 package cluster
 
 import (
+	"context"
 	"testing"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/tools/record"
+	certificateinstall "kubeception.cloud/kubeception/pkg/apis/certificate/install"
+	kubeceptioninstall "kubeception.cloud/kubeception/pkg/apis/kubeception/install"
+	"kubeception.cloud/kubeception/pkg/util"
+	clusterapis "sigs.k8s.io/cluster-api/pkg/apis"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestCluster(t *testing.T) {
@@ -15,6 +26,25 @@ func TestCluster(t *testing.T) {
 	RunSpecs(t, "Cluster")
 }
 
+// newFakeActuator returns an actuator backed by a fake client containing the given objects.
+func newFakeActuator(objects ...runtime.Object) (*actuator, client.Client) {
+	s := runtime.NewScheme()
+	Expect(scheme.AddToScheme(s)).To(Succeed())
+	Expect(clusterapis.AddToScheme(s)).To(Succeed())
+	kubeceptioninstall.Install(s)
+	certificateinstall.Install(s)
+
+	c := fake.NewFakeClientWithScheme(s, objects...)
+	return NewActuatorWithDeps(context.Background(), c, s, record.NewFakeRecorder(64)).(*actuator), c
+}
+
+// newFakeCluster returns a cluster to be reconciled by a fake actuator.
+func newFakeCluster() *clusterv1alpha1.Cluster {
+	cluster := &clusterv1alpha1.Cluster{ObjectMeta: util.ObjectMeta("default", "foo")}
+	cluster.UID = "foo-uid"
+	return cluster
+}
+
 var _ = Describe("Cluster Suite", func() {
 	var (
 		kubeConfig    clientcmdapi.Config
@@ -55,6 +85,56 @@ var _ = Describe("Cluster Suite", func() {
 		})
 	})
 
+	Describe("#NewKubeconfig", func() {
+		It("should create a kubeconfig authenticating with the given client certificate", func() {
+			var (
+				caData   = []byte("ca")
+				certData = []byte("cert")
+				keyData  = []byte("key")
+			)
+
+			config := NewKubeconfig("https://apiserver:443", caData, certData, keyData)
+
+			Expect(config.CurrentContext).NotTo(BeEmpty())
+			context := config.Contexts[config.CurrentContext]
+			Expect(context).NotTo(BeNil())
+
+			cluster := config.Clusters[context.Cluster]
+			Expect(cluster).NotTo(BeNil())
+			Expect(cluster.Server).To(Equal("https://apiserver:443"))
+			Expect(cluster.CertificateAuthorityData).To(Equal(caData))
+			Expect(cluster.InsecureSkipTLSVerify).To(BeFalse())
+
+			authInfo := config.AuthInfos[context.AuthInfo]
+			Expect(authInfo).NotTo(BeNil())
+			Expect(authInfo.ClientCertificateData).To(Equal(certData))
+			Expect(authInfo.ClientKeyData).To(Equal(keyData))
+		})
+	})
+
+	Describe("#NewTokenKubeconfig", func() {
+		It("should create a kubeconfig authenticating with the given token", func() {
+			config := NewTokenKubeconfig("https://apiserver:443", []byte("ca"), "abcdef.0123456789abcdef")
+
+			cluster, err := KubeconfigCluster(config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cluster.Server).To(Equal("https://apiserver:443"))
+			Expect(cluster.CertificateAuthorityData).To(Equal([]byte("ca")))
+
+			authInfo := config.AuthInfos[config.Contexts[config.CurrentContext].AuthInfo]
+			Expect(authInfo).NotTo(BeNil())
+			Expect(authInfo.Token).To(Equal("abcdef.0123456789abcdef"))
+		})
+	})
+
+	Describe("#KubeconfigCluster", func() {
+		It("should fail without current context", func() {
+			_, err := KubeconfigCluster(&kubeConfig)
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 	Describe("#UpdateKubeconfigSecret", func() {
 		It("should correctly update the kubeconfig secret", func() {
 			secret := &corev1.Secret{}
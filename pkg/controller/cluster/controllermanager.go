@@ -0,0 +1,130 @@
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/util/flags"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+const (
+	// KubeconfigDir is the directory the kubeconfig of the controller manager and the scheduler is mounted at.
+	KubeconfigDir = "/etc/kubeconfig"
+	// KubeconfigFile is the kubeconfig of the controller manager and the scheduler.
+	KubeconfigFile = KubeconfigDir + "/" + KubeconfigField
+
+	// DefaultControllerManagerReplicas is the default number of controller manager replicas.
+	DefaultControllerManagerReplicas = 1
+)
+
+var (
+	// kubeconfigFlags are the flags that point control plane components to their kubeconfig.
+	kubeconfigFlags = []string{
+		"kubeconfig",
+		"authentication-kubeconfig",
+		"authorization-kubeconfig",
+	}
+
+	// controllerManagerManagedFlags are the controller manager flags that are rendered by kubeception and cannot
+	// be overridden.
+	controllerManagerManagedFlags = append([]string{
+		"service-cluster-ip-range",
+		"cluster-cidr",
+		"service-account-private-key-file",
+		"root-ca-file",
+		"cluster-signing-cert-file",
+		"cluster-signing-key-file",
+		"use-service-account-credentials",
+	}, kubeconfigFlags...)
+
+	// defaultControllers are the controllers of controller managers that do not configure any. Besides the
+	// default controllers, the token cleaner removes expired bootstrap tokens of the machines.
+	defaultControllers = []string{"*", "tokencleaner"}
+)
+
+// validateNodeCIDRMaskSize checks that node CIDRs with the given mask size can be allocated from the given
+// pod CIDR.
+func validateNodeCIDRMaskSize(podCIDR string, maskSize int32) error {
+	_, ipNet, err := net.ParseCIDR(podCIDR)
+	if err != nil {
+		return err
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	if int(maskSize) < ones || int(maskSize) > bits {
+		return fmt.Errorf("invalid node CIDR mask size %d, must be between %d and %d for pod CIDR %s", maskSize, ones, bits, podCIDR)
+	}
+	return nil
+}
+
+// validateControllerManager validates the given controller manager configuration of the given cluster.
+func validateControllerManager(cluster *clusterv1alpha1.Cluster, controllerManager *v1alpha1.ControllerManager) error {
+	replicas := int32(DefaultControllerManagerReplicas)
+	if controllerManager.Replicas != nil {
+		replicas = *controllerManager.Replicas
+	}
+	if err := validateLeaderElection(replicas, controllerManager.LeaderElection); err != nil {
+		return err
+	}
+
+	if err := validateNames("controller", controllerManager.Controllers); err != nil {
+		return err
+	}
+
+	if controllerManager.NodeCIDRMaskSize != nil {
+		if err := validateNodeCIDRMaskSize(cluster.Spec.ClusterNetwork.Pods.CIDRBlocks[0], *controllerManager.NodeCIDRMaskSize); err != nil {
+			return err
+		}
+	}
+
+	if err := validateFeatureGates(controllerManager.FeatureGates); err != nil {
+		return err
+	}
+	return nil
+}
+
+// controllerManagerFlags renders the flags of the controller manager of the given cluster from the given
+// configuration.
+func controllerManagerFlags(cluster *clusterv1alpha1.Cluster, controllerManager *v1alpha1.ControllerManager) ([]string, error) {
+	if err := validateControllerManager(cluster, controllerManager); err != nil {
+		return nil, err
+	}
+
+	f := flags.New()
+	f.Set("service-cluster-ip-range", cluster.Spec.ClusterNetwork.Services.CIDRBlocks[0])
+	f.Set("cluster-cidr", cluster.Spec.ClusterNetwork.Pods.CIDRBlocks[0])
+	for _, name := range kubeconfigFlags {
+		f.Set(name, KubeconfigFile)
+	}
+	f.Set("service-account-private-key-file", fmt.Sprintf("%s/%s", ServiceAccountPKIDir, ServiceAccountKeyFile))
+	f.Set("root-ca-file", fmt.Sprintf("%s/%s", ServiceAccountPKIDir, CAFile))
+	// Each controller runs with the credentials of its own service account, which RBAC grants the permissions
+	// of the controller.
+	f.Set("use-service-account-credentials", "true")
+	f.Set("cluster-signing-cert-file", fmt.Sprintf("%s/%s", ClusterSigningPKIDir, CAFile))
+	f.Set("cluster-signing-key-file", fmt.Sprintf("%s/%s", ClusterSigningPKIDir, CAKeyFile))
+	f.Set("allocate-node-cidrs", "true")
+	f.Set("cluster-name", "kubeception")
+
+	setLeaderElectionFlags(f, controllerManager.LeaderElection)
+	controllers := defaultControllers
+	if len(controllerManager.Controllers) > 0 {
+		controllers = controllerManager.Controllers
+	}
+	f.Set("controllers", strings.Join(controllers, ","))
+	if controllerManager.NodeCIDRMaskSize != nil {
+		f.Set("node-cidr-mask-size", strconv.Itoa(int(*controllerManager.NodeCIDRMaskSize)))
+	}
+	if len(controllerManager.FeatureGates) > 0 {
+		f.Set("feature-gates", flags.JoinFeatureGates(controllerManager.FeatureGates))
+	}
+
+	if err := f.SetExtraArgs(controllerManager.ExtraArgs, controllerManagerManagedFlags...); err != nil {
+		return nil, err
+	}
+	return f.Args(), nil
+}
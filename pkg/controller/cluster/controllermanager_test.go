@@ -0,0 +1,100 @@
+package cluster
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/util/pointers"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+var _ = Describe("ControllerManager", func() {
+	var cluster *clusterv1alpha1.Cluster
+	BeforeEach(func() {
+		cluster = &clusterv1alpha1.Cluster{
+			Spec: clusterv1alpha1.ClusterSpec{
+				ClusterNetwork: clusterv1alpha1.ClusterNetworkingConfig{
+					Services: clusterv1alpha1.NetworkRanges{CIDRBlocks: []string{"192.168.0.0/16"}},
+					Pods:     clusterv1alpha1.NetworkRanges{CIDRBlocks: []string{"192.169.0.0/16"}},
+				},
+			},
+		}
+	})
+
+	Describe("#controllerManagerFlags", func() {
+		It("should render the defaults for an empty configuration", func() {
+			args, err := controllerManagerFlags(cluster, &v1alpha1.ControllerManager{})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(ContainElement("--service-cluster-ip-range=192.168.0.0/16"))
+			Expect(args).To(ContainElement("--cluster-cidr=192.169.0.0/16"))
+			Expect(args).To(ContainElement("--kubeconfig=/etc/kubeconfig/kubeconfig"))
+			Expect(args).To(ContainElement("--service-account-private-key-file=/etc/kubernetes/pki/service-account/sa.key"))
+			Expect(args).To(ContainElement("--root-ca-file=/etc/kubernetes/pki/service-account/ca.crt"))
+			Expect(args).To(ContainElement("--use-service-account-credentials=true"))
+			Expect(args).To(ContainElement("--cluster-signing-cert-file=/etc/kubernetes/pki/cluster-signing/ca.crt"))
+			Expect(args).To(ContainElement("--cluster-signing-key-file=/etc/kubernetes/pki/cluster-signing/ca.key"))
+			Expect(args).To(ContainElement("--controllers=*,tokencleaner"))
+			Expect(args).To(ContainElement("--leader-elect=true"))
+		})
+
+		It("should render the configured flags", func() {
+			args, err := controllerManagerFlags(cluster, &v1alpha1.ControllerManager{
+				Replicas: pointers.Int32(3),
+				LeaderElection: &v1alpha1.LeaderElection{
+					LeaseDuration: &metav1.Duration{Duration: 30 * time.Second},
+					RenewDeadline: &metav1.Duration{Duration: 20 * time.Second},
+				},
+				Controllers:      []string{"*", "-bootstrapsigner"},
+				NodeCIDRMaskSize: pointers.Int32(26),
+				FeatureGates:     map[string]bool{"TTLAfterFinished": true},
+				ExtraArgs:        map[string]string{"v": "2"},
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(ContainElement("--leader-elect-lease-duration=30s"))
+			Expect(args).To(ContainElement("--leader-elect-renew-deadline=20s"))
+			Expect(args).To(ContainElement("--controllers=*,-bootstrapsigner"))
+			Expect(args).To(ContainElement("--node-cidr-mask-size=26"))
+			Expect(args).To(ContainElement("--feature-gates=TTLAfterFinished=true"))
+			Expect(args).To(ContainElement("--v=2"))
+		})
+
+		It("should require leader election for multiple replicas", func() {
+			_, err := controllerManagerFlags(cluster, &v1alpha1.ControllerManager{
+				Replicas:       pointers.Int32(2),
+				LeaderElection: &v1alpha1.LeaderElection{LeaderElect: pointers.Bool(false)},
+			})
+
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject a renew deadline exceeding the lease duration", func() {
+			_, err := controllerManagerFlags(cluster, &v1alpha1.ControllerManager{
+				LeaderElection: &v1alpha1.LeaderElection{
+					LeaseDuration: &metav1.Duration{Duration: 10 * time.Second},
+					RenewDeadline: &metav1.Duration{Duration: 10 * time.Second},
+				},
+			})
+
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject node CIDR mask sizes not fitting the pod CIDR", func() {
+			_, err := controllerManagerFlags(cluster, &v1alpha1.ControllerManager{NodeCIDRMaskSize: pointers.Int32(8)})
+
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject extra arguments overriding managed flags", func() {
+			_, err := controllerManagerFlags(cluster, &v1alpha1.ControllerManager{
+				ExtraArgs: map[string]string{"kubeconfig": "/tmp/kubeconfig"},
+			})
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,244 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/util"
+	clustercommon "sigs.k8s.io/cluster-api/pkg/apis/cluster/common"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllererror "sigs.k8s.io/cluster-api/pkg/controller/error"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	EventDeletingMachines     = "DeletingMachines"
+	EventDeletingControlPlane = "DeletingControlPlane"
+	EventTakingFinalBackup    = "TakingFinalBackup"
+	EventDeletingETCD         = "DeletingETCD"
+	EventDeletionFailed       = "DeletionFailed"
+
+	// DeletionRequeueAfter is the duration after which the progress of a cluster deletion is checked again.
+	DeletionRequeueAfter = 10 * time.Second
+)
+
+// FinalBackupName returns the name of the EtcdBackup taken when the given cluster is deleted.
+func FinalBackupName(cluster *clusterv1alpha1.Cluster) string {
+	return fmt.Sprintf("%s-final", cluster.Name)
+}
+
+// deleteObject requests the deletion of the given object unless it is already being deleted.
+// It returns whether the object still exists and whether its deletion has been requested by this call.
+func (a *actuator) deleteObject(ctx context.Context, obj interface {
+	runtime.Object
+	metav1.Object
+}) (bool, bool, error) {
+	if err := a.Client.Get(ctx, util.Key(obj.GetNamespace(), obj.GetName()), obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, false, nil
+		}
+		return false, false, err
+	}
+
+	if obj.GetDeletionTimestamp() != nil {
+		return true, false, nil
+	}
+	if err := a.Client.Delete(ctx, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, false, nil
+		}
+		return false, false, err
+	}
+	return true, true, nil
+}
+
+// deleteMachines deletes all machines of the given cluster. It returns true once all machines are gone.
+func (a *actuator) deleteMachines(ctx context.Context, cluster *clusterv1alpha1.Cluster, config *v1alpha1.ClusterConfig) (bool, error) {
+	machineList := &clusterv1alpha1.MachineList{}
+	if err := a.Client.List(ctx, machineList, client.InNamespace(cluster.Namespace), client.MatchingLabels{clusterv1alpha1.MachineClusterLabelName: cluster.Name}); err != nil {
+		return false, err
+	}
+
+	var requested int
+	for _, machine := range machineList.Items {
+		if machine.DeletionTimestamp != nil {
+			continue
+		}
+		if err := client.IgnoreNotFound(a.Client.Delete(ctx, &machine)); err != nil {
+			return false, err
+		}
+		requested++
+	}
+
+	if requested > 0 {
+		a.recorder.Eventf(cluster, corev1.EventTypeNormal, EventDeletingMachines, "Deleting %d machines", requested)
+	}
+	return len(machineList.Items) == 0, nil
+}
+
+// deleteControlPlaneComponents deletes the scheduler, the controller manager and the API server of the given
+// cluster. It returns true once none of their pods are left.
+func (a *actuator) deleteControlPlaneComponents(ctx context.Context, cluster *clusterv1alpha1.Cluster, config *v1alpha1.ClusterConfig) (bool, error) {
+	names := ClusterNames(cluster)
+	var requested, remaining bool
+	for _, component := range []struct {
+		deploymentName string
+		component      string
+	}{
+		{SchedulerDeploymentName, SchedulerComponent},
+		{ControllerManagerDeploymentName, ControllerManagerComponent},
+		{APIServerDeploymentName, APIServerComponent},
+	} {
+		exists, deleted, err := a.deleteObject(ctx, &appsv1.Deployment{ObjectMeta: util.ObjectMeta(cluster.Namespace, names.Scoped(component.deploymentName))})
+		if err != nil {
+			return false, err
+		}
+
+		running, err := a.podsExist(ctx, cluster.Namespace, names.Labels(component.component))
+		if err != nil {
+			return false, err
+		}
+
+		requested = requested || deleted
+		remaining = remaining || exists || running
+	}
+
+	if requested {
+		a.recorder.Event(cluster, corev1.EventTypeNormal, EventDeletingControlPlane, "Deleting scheduler, controller manager and API server")
+	}
+	return !remaining, nil
+}
+
+// takeFinalBackup takes a last snapshot of the etcd of the given cluster if a final backup is configured.
+// The backup is not owned by the cluster, so that it outlives it. It returns true once the backup succeeded.
+// A failed backup blocks the deletion until it is deleted or the final backup is unconfigured.
+func (a *actuator) takeFinalBackup(ctx context.Context, cluster *clusterv1alpha1.Cluster, config *v1alpha1.ClusterConfig) (bool, error) {
+	storage := config.ControlPlane.ETCD.FinalBackup
+	if storage == nil {
+		return true, nil
+	}
+
+	exists, err := a.getObject(ctx, cluster.Namespace, ClusterNames(cluster).Scoped(ETCDStatefulSetName), &appsv1.StatefulSet{})
+	if err != nil || !exists {
+		return !exists, err
+	}
+
+	backup := &v1alpha1.EtcdBackup{}
+	exists, err = a.getObject(ctx, cluster.Namespace, FinalBackupName(cluster), backup)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		backup = &v1alpha1.EtcdBackup{
+			ObjectMeta: util.ObjectMeta(cluster.Namespace, FinalBackupName(cluster)),
+			Spec: v1alpha1.EtcdBackupSpec{
+				Cluster: corev1.LocalObjectReference{Name: cluster.Name},
+				Storage: *storage.DeepCopy(),
+			},
+		}
+		if err := a.Client.Create(ctx, backup); err != nil {
+			return false, err
+		}
+
+		a.recorder.Eventf(cluster, corev1.EventTypeNormal, EventTakingFinalBackup, "Taking final etcd backup %s", backup.Name)
+		return false, nil
+	}
+
+	switch backup.Status.Phase {
+	case v1alpha1.EtcdBackupSucceeded:
+		return true, nil
+	case v1alpha1.EtcdBackupFailed:
+		return false, fmt.Errorf("final etcd backup %s failed: %s, delete it to retry or remove the final backup from the etcd configuration to skip it",
+			backup.Name, backup.Status.Message)
+	}
+	return false, nil
+}
+
+// deleteETCD deletes the etcd members of the given cluster and their volumes. It returns true once all of them
+// are gone.
+func (a *actuator) deleteETCD(ctx context.Context, cluster *clusterv1alpha1.Cluster, config *v1alpha1.ClusterConfig) (bool, error) {
+	names := ClusterNames(cluster)
+	exists, requested, err := a.deleteObject(ctx, &appsv1.StatefulSet{ObjectMeta: util.ObjectMeta(cluster.Namespace, names.Scoped(ETCDStatefulSetName))})
+	if err != nil {
+		return false, err
+	}
+	if requested {
+		a.recorder.Event(cluster, corev1.EventTypeNormal, EventDeletingETCD, "Deleting etcd and its volumes")
+	}
+
+	running, err := a.podsExist(ctx, cluster.Namespace, names.Labels(ETCDComponent))
+	if err != nil || exists || running {
+		return false, err
+	}
+
+	claimList := &corev1.PersistentVolumeClaimList{}
+	if err := a.Client.List(ctx, claimList, client.InNamespace(cluster.Namespace), client.MatchingLabels(names.Labels(ETCDComponent))); err != nil {
+		return false, err
+	}
+	for _, claim := range claimList.Items {
+		if claim.DeletionTimestamp != nil {
+			continue
+		}
+		if err := client.IgnoreNotFound(a.Client.Delete(ctx, &claim)); err != nil {
+			return false, err
+		}
+	}
+	return len(claimList.Items) == 0, nil
+}
+
+// reportDeletionError reports the given error on the cluster, both as event and in its status.
+func (a *actuator) reportDeletionError(ctx context.Context, cluster *clusterv1alpha1.Cluster, err error) error {
+	message := err.Error()
+	a.recorder.Event(cluster, corev1.EventTypeWarning, EventDeletionFailed, message)
+	if cluster.Status.ErrorReason == clustercommon.DeleteClusterError && cluster.Status.ErrorMessage != nil && *cluster.Status.ErrorMessage == message {
+		return err
+	}
+
+	cluster.Status.ErrorReason = clustercommon.DeleteClusterError
+	cluster.Status.ErrorMessage = &message
+	return utilerrors.NewAggregate([]error{err, a.Client.Status().Update(ctx, cluster)})
+}
+
+// clearDeletionError removes a previously reported deletion error from the status of the given cluster.
+func (a *actuator) clearDeletionError(ctx context.Context, cluster *clusterv1alpha1.Cluster) error {
+	if cluster.Status.ErrorReason != clustercommon.DeleteClusterError {
+		return nil
+	}
+
+	cluster.Status.ErrorReason = ""
+	cluster.Status.ErrorMessage = nil
+	return a.Client.Status().Update(ctx, cluster)
+}
+
+// delete tears the cluster down in order: The machines are deleted first, followed by the control plane
+// components that depend on etcd. Then an optional final snapshot is taken before etcd and its volumes are
+// removed. The remaining objects are garbage collected along with the cluster.
+// Until all steps are finished, a RequeueAfterError is returned.
+func (a *actuator) delete(ctx context.Context, cluster *clusterv1alpha1.Cluster, config *v1alpha1.ClusterConfig) error {
+	for _, step := range []func(context.Context, *clusterv1alpha1.Cluster, *v1alpha1.ClusterConfig) (bool, error){
+		a.deleteMachines,
+		a.deleteControlPlaneComponents,
+		a.takeFinalBackup,
+		a.deleteETCD,
+	} {
+		done, err := step(ctx, cluster, config)
+		if err != nil {
+			return a.reportDeletionError(ctx, cluster, err)
+		}
+		if !done {
+			if err := a.clearDeletionError(ctx, cluster); err != nil {
+				return err
+			}
+			return &controllererror.RequeueAfterError{RequeueAfter: DeletionRequeueAfter}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package cluster
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/util"
+	clustercommon "sigs.k8s.io/cluster-api/pkg/apis/cluster/common"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllererror "sigs.k8s.io/cluster-api/pkg/controller/error"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Delete", func() {
+	var (
+		ctx     context.Context
+		cluster *clusterv1alpha1.Cluster
+		names   Names
+		config  *v1alpha1.ClusterConfig
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		cluster = newFakeCluster()
+		names = ClusterNames(cluster)
+		config = &v1alpha1.ClusterConfig{}
+	})
+
+	exists := func(c client.Client, name string, obj runtime.Object) bool {
+		err := c.Get(ctx, util.Key(cluster.Namespace, name), obj)
+		if apierrors.IsNotFound(err) {
+			return false
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return true
+	}
+
+	machine := func(name, clusterName string) *clusterv1alpha1.Machine {
+		machine := &clusterv1alpha1.Machine{ObjectMeta: util.ObjectMeta(cluster.Namespace, name)}
+		machine.Labels = map[string]string{clusterv1alpha1.MachineClusterLabelName: clusterName}
+		return machine
+	}
+
+	pod := func(name, component string) *corev1.Pod {
+		pod := &corev1.Pod{ObjectMeta: util.ObjectMeta(cluster.Namespace, name)}
+		pod.Labels = names.Labels(component)
+		return pod
+	}
+
+	etcdStatefulSet := func() *appsv1.StatefulSet {
+		return &appsv1.StatefulSet{ObjectMeta: util.ObjectMeta(cluster.Namespace, names.Scoped(ETCDStatefulSetName))}
+	}
+
+	etcdClaim := func(name string) *corev1.PersistentVolumeClaim {
+		claim := &corev1.PersistentVolumeClaim{ObjectMeta: util.ObjectMeta(cluster.Namespace, name)}
+		claim.Labels = names.Labels(ETCDComponent)
+		return claim
+	}
+
+	finalBackup := func(phase v1alpha1.EtcdBackupPhase) *v1alpha1.EtcdBackup {
+		backup := &v1alpha1.EtcdBackup{ObjectMeta: util.ObjectMeta(cluster.Namespace, FinalBackupName(cluster))}
+		backup.Status.Phase = phase
+		backup.Status.Message = "snapshot job failed"
+		return backup
+	}
+
+	withFinalBackup := func() {
+		config.ControlPlane.ETCD.FinalBackup = &v1alpha1.EtcdBackupStorage{
+			HostPath: &corev1.HostPathVolumeSource{Path: "/var/backups"},
+		}
+	}
+
+	Describe("#deleteMachines", func() {
+		It("should delete all machines of the cluster and wait for them to be gone", func() {
+			a, c := newFakeActuator(cluster, machine("foo-1", "foo"), machine("foo-2", "foo"), machine("bar-1", "bar"))
+
+			done, err := a.deleteMachines(ctx, cluster, config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeFalse())
+			Expect(exists(c, "foo-1", &clusterv1alpha1.Machine{})).To(BeFalse())
+			Expect(exists(c, "foo-2", &clusterv1alpha1.Machine{})).To(BeFalse())
+			Expect(exists(c, "bar-1", &clusterv1alpha1.Machine{})).To(BeTrue())
+
+			done, err = a.deleteMachines(ctx, cluster, config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeTrue())
+		})
+	})
+
+	Describe("#deleteControlPlaneComponents", func() {
+		It("should delete the deployments and wait for their pods to be gone", func() {
+			var objects []runtime.Object
+			for _, name := range []string{SchedulerDeploymentName, ControllerManagerDeploymentName, APIServerDeploymentName} {
+				objects = append(objects, &appsv1.Deployment{ObjectMeta: util.ObjectMeta(cluster.Namespace, names.Scoped(name))})
+			}
+			apiServerPod := pod("foo-apiserver-abcde", APIServerComponent)
+			a, c := newFakeActuator(append(objects, cluster, apiServerPod)...)
+
+			done, err := a.deleteControlPlaneComponents(ctx, cluster, config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeFalse())
+			for _, name := range []string{SchedulerDeploymentName, ControllerManagerDeploymentName, APIServerDeploymentName} {
+				Expect(exists(c, names.Scoped(name), &appsv1.Deployment{})).To(BeFalse())
+			}
+
+			done, err = a.deleteControlPlaneComponents(ctx, cluster, config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeFalse())
+
+			Expect(c.Delete(ctx, apiServerPod)).To(Succeed())
+			done, err = a.deleteControlPlaneComponents(ctx, cluster, config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeTrue())
+		})
+	})
+
+	Describe("#takeFinalBackup", func() {
+		It("should be done right away if no final backup is configured", func() {
+			a, c := newFakeActuator(cluster, etcdStatefulSet())
+
+			done, err := a.takeFinalBackup(ctx, cluster, config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeTrue())
+			Expect(exists(c, FinalBackupName(cluster), &v1alpha1.EtcdBackup{})).To(BeFalse())
+		})
+
+		It("should be done right away if etcd is already gone", func() {
+			withFinalBackup()
+			a, c := newFakeActuator(cluster)
+
+			done, err := a.takeFinalBackup(ctx, cluster, config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeTrue())
+			Expect(exists(c, FinalBackupName(cluster), &v1alpha1.EtcdBackup{})).To(BeFalse())
+		})
+
+		It("should create a final backup that is not owned by the cluster", func() {
+			withFinalBackup()
+			a, c := newFakeActuator(cluster, etcdStatefulSet())
+
+			done, err := a.takeFinalBackup(ctx, cluster, config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeFalse())
+
+			backup := &v1alpha1.EtcdBackup{}
+			Expect(exists(c, FinalBackupName(cluster), backup)).To(BeTrue())
+			Expect(backup.OwnerReferences).To(BeEmpty())
+			Expect(backup.Spec.Cluster.Name).To(Equal(cluster.Name))
+			Expect(backup.Spec.Storage).To(Equal(*config.ControlPlane.ETCD.FinalBackup))
+		})
+
+		table.DescribeTable("should follow the phase of the final backup",
+			func(phase v1alpha1.EtcdBackupPhase, expectDone, expectErr bool) {
+				withFinalBackup()
+				a, _ := newFakeActuator(cluster, etcdStatefulSet(), finalBackup(phase))
+
+				done, err := a.takeFinalBackup(ctx, cluster, config)
+				if expectErr {
+					Expect(err).To(MatchError(ContainSubstring("final etcd backup foo-final failed: snapshot job failed")))
+				} else {
+					Expect(err).NotTo(HaveOccurred())
+				}
+				Expect(done).To(Equal(expectDone))
+			},
+			table.Entry("pending", v1alpha1.EtcdBackupPending, false, false),
+			table.Entry("running", v1alpha1.EtcdBackupRunning, false, false),
+			table.Entry("succeeded", v1alpha1.EtcdBackupSucceeded, true, false),
+			table.Entry("failed", v1alpha1.EtcdBackupFailed, false, true),
+		)
+	})
+
+	Describe("#deleteETCD", func() {
+		It("should delete etcd and remove its volumes once its pods are gone", func() {
+			etcdPod := pod("foo-etcd-0", ETCDComponent)
+			otherClaim := &corev1.PersistentVolumeClaim{ObjectMeta: util.ObjectMeta(cluster.Namespace, "other")}
+			a, c := newFakeActuator(cluster, etcdStatefulSet(), etcdPod, etcdClaim("data-foo-etcd-0"), etcdClaim("data-foo-etcd-1"), otherClaim)
+
+			done, err := a.deleteETCD(ctx, cluster, config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeFalse())
+			Expect(exists(c, names.Scoped(ETCDStatefulSetName), &appsv1.StatefulSet{})).To(BeFalse())
+			Expect(exists(c, "data-foo-etcd-0", &corev1.PersistentVolumeClaim{})).To(BeTrue())
+
+			Expect(c.Delete(ctx, etcdPod)).To(Succeed())
+			done, err = a.deleteETCD(ctx, cluster, config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeFalse())
+			Expect(exists(c, "data-foo-etcd-0", &corev1.PersistentVolumeClaim{})).To(BeFalse())
+			Expect(exists(c, "data-foo-etcd-1", &corev1.PersistentVolumeClaim{})).To(BeFalse())
+			Expect(exists(c, "other", &corev1.PersistentVolumeClaim{})).To(BeTrue())
+
+			done, err = a.deleteETCD(ctx, cluster, config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeTrue())
+		})
+	})
+
+	Describe("#delete", func() {
+		requeue := BeAssignableToTypeOf(&controllererror.RequeueAfterError{})
+
+		getCluster := func(c client.Client) *clusterv1alpha1.Cluster {
+			actual := &clusterv1alpha1.Cluster{}
+			Expect(c.Get(ctx, util.KeyFromObject(cluster), actual)).To(Succeed())
+			return actual
+		}
+
+		It("should tear the cluster down step by step", func() {
+			withFinalBackup()
+			a, c := newFakeActuator(cluster, machine("foo-1", "foo"), etcdStatefulSet(), etcdClaim("data-foo-etcd-0"))
+
+			By("deleting the machines")
+			Expect(a.delete(ctx, cluster, config)).To(requeue)
+			Expect(exists(c, "foo-1", &clusterv1alpha1.Machine{})).To(BeFalse())
+			Expect(exists(c, FinalBackupName(cluster), &v1alpha1.EtcdBackup{})).To(BeFalse())
+
+			By("taking the final backup")
+			Expect(a.delete(ctx, cluster, config)).To(requeue)
+			backup := &v1alpha1.EtcdBackup{}
+			Expect(exists(c, FinalBackupName(cluster), backup)).To(BeTrue())
+			Expect(exists(c, names.Scoped(ETCDStatefulSetName), &appsv1.StatefulSet{})).To(BeTrue())
+
+			Expect(a.delete(ctx, cluster, config)).To(requeue)
+			Expect(exists(c, names.Scoped(ETCDStatefulSetName), &appsv1.StatefulSet{})).To(BeTrue())
+
+			By("deleting etcd once the final backup succeeded")
+			backup.Status.Phase = v1alpha1.EtcdBackupSucceeded
+			Expect(c.Status().Update(ctx, backup)).To(Succeed())
+			Expect(a.delete(ctx, cluster, config)).To(requeue)
+			Expect(exists(c, names.Scoped(ETCDStatefulSetName), &appsv1.StatefulSet{})).To(BeFalse())
+			Expect(exists(c, "data-foo-etcd-0", &corev1.PersistentVolumeClaim{})).To(BeTrue())
+
+			By("removing the etcd volumes")
+			Expect(a.delete(ctx, cluster, config)).To(requeue)
+			Expect(exists(c, "data-foo-etcd-0", &corev1.PersistentVolumeClaim{})).To(BeFalse())
+
+			By("finishing once everything is gone")
+			Expect(a.delete(ctx, cluster, config)).To(Succeed())
+			Expect(exists(c, FinalBackupName(cluster), &v1alpha1.EtcdBackup{})).To(BeTrue())
+		})
+
+		It("should report a failed final backup and clear the error once it is unblocked", func() {
+			withFinalBackup()
+			a, c := newFakeActuator(cluster, etcdStatefulSet(), finalBackup(v1alpha1.EtcdBackupFailed))
+
+			err := a.delete(ctx, cluster, config)
+			Expect(err).To(HaveOccurred())
+			Expect(err).NotTo(requeue)
+			actual := getCluster(c)
+			Expect(actual.Status.ErrorReason).To(Equal(clustercommon.DeleteClusterError))
+			Expect(actual.Status.ErrorMessage).NotTo(BeNil())
+			Expect(*actual.Status.ErrorMessage).To(ContainSubstring("final etcd backup foo-final failed"))
+			Expect(exists(c, names.Scoped(ETCDStatefulSetName), &appsv1.StatefulSet{})).To(BeTrue())
+
+			By("retrying the backup once the failed one is deleted")
+			Expect(c.Delete(ctx, finalBackup(v1alpha1.EtcdBackupFailed))).To(Succeed())
+			cluster = actual
+			Expect(a.delete(ctx, cluster, config)).To(requeue)
+			actual = getCluster(c)
+			Expect(actual.Status.ErrorReason).To(BeEmpty())
+			Expect(actual.Status.ErrorMessage).To(BeNil())
+			Expect(exists(c, FinalBackupName(cluster), &v1alpha1.EtcdBackup{})).To(BeTrue())
+			Expect(exists(c, names.Scoped(ETCDStatefulSetName), &appsv1.StatefulSet{})).To(BeTrue())
+		})
+
+		It("should skip a failed final backup once it is unconfigured", func() {
+			withFinalBackup()
+			a, c := newFakeActuator(cluster, etcdStatefulSet(), finalBackup(v1alpha1.EtcdBackupFailed))
+
+			Expect(a.delete(ctx, cluster, config)).NotTo(Succeed())
+
+			config.ControlPlane.ETCD.FinalBackup = nil
+			cluster = getCluster(c)
+			Expect(a.delete(ctx, cluster, config)).To(requeue)
+			Expect(getCluster(c).Status.ErrorReason).To(BeEmpty())
+			Expect(exists(c, names.Scoped(ETCDStatefulSetName), &appsv1.StatefulSet{})).To(BeFalse())
+		})
+	})
+})
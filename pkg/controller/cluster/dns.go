@@ -0,0 +1,306 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/controller/common"
+	"kubeception.cloud/kubeception/pkg/util"
+	"kubeception.cloud/kubeception/pkg/util/cidr"
+	"kubeception.cloud/kubeception/pkg/util/pointers"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultDNSReplicas is the default number of CoreDNS replicas.
+	DefaultDNSReplicas = 2
+	// DefaultClusterDomain is the domain of clusters that do not configure a service domain.
+	DefaultClusterDomain = "cluster.local"
+	// DNSServiceIPOffset is the offset of the IP of the DNS service in the service CIDR of a cluster.
+	DNSServiceIPOffset = 10
+
+	// The DNS objects are created in the kube-system namespace of the hosted cluster under the names kubeadm
+	// uses, so that tools expecting them find them.
+	dnsNamespace       = metav1.NamespaceSystem
+	dnsName            = "coredns"
+	dnsServiceName     = "kube-dns"
+	dnsClusterRoleName = "system:coredns"
+	corefileKey        = "Corefile"
+	dnsAddonName       = "dns"
+)
+
+var (
+	dnsLabels = map[string]string{"k8s-app": "kube-dns"}
+	// dnsObjectLabels are the labels of the DNS objects, which mark them as managed by kubeception. They are not
+	// part of the selectors, so that the selector of an existing deployment stays the same.
+	dnsObjectLabels = map[string]string{"k8s-app": "kube-dns", AddonLabel: dnsAddonName}
+)
+
+// ClusterDomain returns the domain of the services of the given cluster.
+func ClusterDomain(cluster *clusterv1alpha1.Cluster) string {
+	if domain := cluster.Spec.ClusterNetwork.ServiceDomain; domain != "" {
+		return domain
+	}
+	return DefaultClusterDomain
+}
+
+// DNSServiceIP returns the cluster IP of the DNS service of the given cluster, the tenth IP of its service CIDR.
+func DNSServiceIP(cluster *clusterv1alpha1.Cluster) (net.IP, error) {
+	blocks := cluster.Spec.ClusterNetwork.Services.CIDRBlocks
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("cluster %s has no service CIDR", cluster.Name)
+	}
+	return cidr.NthIP(blocks[0], DNSServiceIPOffset)
+}
+
+// validateDNS validates the given DNS configuration.
+func validateDNS(dns *v1alpha1.DNS) error {
+	if dns.Replicas != nil && *dns.Replicas < 0 {
+		return fmt.Errorf("invalid negative number of replicas %d", *dns.Replicas)
+	}
+
+	for _, nameserver := range dns.UpstreamNameservers {
+		host := nameserver
+		if h, _, err := net.SplitHostPort(nameserver); err == nil {
+			host = h
+		}
+		if net.ParseIP(host) == nil {
+			return fmt.Errorf("invalid upstream nameserver %q, must be an IP address with optional port", nameserver)
+		}
+	}
+	return nil
+}
+
+// corefile renders the CoreDNS configuration that serves the given cluster domain and forwards other names to the
+// given upstream nameservers or, if there are none, to the nameservers of the node.
+func corefile(domain string, upstreamNameservers []string) string {
+	upstream := "/etc/resolv.conf"
+	if len(upstreamNameservers) > 0 {
+		upstream = strings.Join(upstreamNameservers, " ")
+	}
+
+	return fmt.Sprintf(`.:53 {
+    errors
+    health
+    kubernetes %s in-addr.arpa ip6.arpa {
+       pods insecure
+       upstream
+       fallthrough in-addr.arpa ip6.arpa
+    }
+    prometheus :9153
+    forward . %s
+    cache 30
+    loop
+    reload
+    loadbalance
+}
+`, domain, upstream)
+}
+
+// deleteDNS removes CoreDNS from the hosted cluster with the given client. Objects with the same names that were
+// not created by kubeception are left alone.
+func deleteDNS(ctx context.Context, guest client.Client) error {
+	for _, obj := range []interface {
+		runtime.Object
+		metav1.Object
+	}{
+		&corev1.Service{ObjectMeta: util.ObjectMeta(dnsNamespace, dnsServiceName)},
+		&appsv1.Deployment{ObjectMeta: util.ObjectMeta(dnsNamespace, dnsName)},
+		&corev1.ConfigMap{ObjectMeta: util.ObjectMeta(dnsNamespace, dnsName)},
+		&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: dnsClusterRoleName}},
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: dnsClusterRoleName}},
+		&corev1.ServiceAccount{ObjectMeta: util.ObjectMeta(dnsNamespace, dnsName)},
+	} {
+		if err := guest.Get(ctx, util.KeyFromObject(obj), obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if obj.GetLabels()[AddonLabel] != dnsAddonName {
+			continue
+		}
+		if err := client.IgnoreNotFound(guest.Delete(ctx, obj)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcileDNS applies CoreDNS with the given configuration to the hosted cluster with the given client.
+// Without configuration, CoreDNS is removed again.
+func reconcileDNS(ctx context.Context, guest client.Client, cluster *clusterv1alpha1.Cluster, dns *v1alpha1.DNS) error {
+	if dns == nil {
+		return deleteDNS(ctx, guest)
+	}
+
+	if err := validateDNS(dns); err != nil {
+		return fmt.Errorf("invalid DNS configuration: %v", err)
+	}
+
+	serviceIP, err := DNSServiceIP(cluster)
+	if err != nil {
+		return err
+	}
+
+	serviceAccount := &corev1.ServiceAccount{ObjectMeta: util.ObjectMeta(dnsNamespace, dnsName)}
+	if _, err := controllerruntime.CreateOrUpdate(ctx, guest, serviceAccount, func() error {
+		util.SetMetaDataLabels(serviceAccount, dnsObjectLabels)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	clusterRole := &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: dnsClusterRoleName}}
+	if _, err := controllerruntime.CreateOrUpdate(ctx, guest, clusterRole, func() error {
+		util.SetMetaDataLabels(clusterRole, dnsObjectLabels)
+		clusterRole.Rules = []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{""},
+				Resources: []string{"endpoints", "services", "pods", "namespaces"},
+				Verbs:     []string{"list", "watch"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"nodes"},
+				Verbs:     []string{"get"},
+			},
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	clusterRoleBinding := &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: dnsClusterRoleName}}
+	if _, err := controllerruntime.CreateOrUpdate(ctx, guest, clusterRoleBinding, func() error {
+		util.SetMetaDataLabels(clusterRoleBinding, dnsObjectLabels)
+		clusterRoleBinding.RoleRef = rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     dnsClusterRoleName,
+		}
+		clusterRoleBinding.Subjects = []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Namespace: dnsNamespace,
+				Name:      dnsName,
+			},
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	configMap := &corev1.ConfigMap{ObjectMeta: util.ObjectMeta(dnsNamespace, dnsName)}
+	if _, err := controllerruntime.CreateOrUpdate(ctx, guest, configMap, func() error {
+		util.SetMetaDataLabels(configMap, dnsObjectLabels)
+		configMap.Data = map[string]string{corefileKey: corefile(ClusterDomain(cluster), dns.UpstreamNameservers)}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	deployment := &appsv1.Deployment{ObjectMeta: util.ObjectMeta(dnsNamespace, dnsName)}
+	if _, err := controllerruntime.CreateOrUpdate(ctx, guest, deployment, func() error {
+		util.SetMetaDataLabels(deployment, dnsObjectLabels)
+		deployment.Spec = appsv1.DeploymentSpec{
+			Replicas: pointers.Int32(pointers.DerefInt32OrDefault(dns.Replicas, DefaultDNSReplicas)),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: dnsLabels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: dnsLabels,
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: dnsName,
+					PriorityClassName:  "system-cluster-critical",
+					DNSPolicy:          corev1.DNSDefault,
+					Affinity:           podAntiAffinity(dnsLabels),
+					Tolerations: []corev1.Toleration{
+						{
+							Key:      "CriticalAddonsOnly",
+							Operator: corev1.TolerationOpExists,
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:  "coredns",
+							Image: common.CoreDNSImage(dns.Image),
+							Args:  []string{"-conf", fmt.Sprintf("/etc/coredns/%s", corefileKey)},
+							Ports: []corev1.ContainerPort{
+								{Name: "dns", ContainerPort: 53, Protocol: corev1.ProtocolUDP},
+								{Name: "dns-tcp", ContainerPort: 53, Protocol: corev1.ProtocolTCP},
+								{Name: "metrics", ContainerPort: 9153, Protocol: corev1.ProtocolTCP},
+							},
+							LivenessProbe: &corev1.Probe{
+								Handler: corev1.Handler{
+									HTTPGet: &corev1.HTTPGetAction{
+										Path: "/health",
+										Port: intstr.FromInt(8080),
+									},
+								},
+								InitialDelaySeconds: 60,
+								TimeoutSeconds:      5,
+							},
+							SecurityContext: &corev1.SecurityContext{
+								AllowPrivilegeEscalation: pointers.Bool(false),
+								ReadOnlyRootFilesystem:   pointers.Bool(true),
+								Capabilities: &corev1.Capabilities{
+									Add:  []corev1.Capability{"NET_BIND_SERVICE"},
+									Drop: []corev1.Capability{"all"},
+								},
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      "config",
+									MountPath: "/etc/coredns",
+									ReadOnly:  true,
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "config",
+							VolumeSource: corev1.VolumeSource{
+								ConfigMap: &corev1.ConfigMapVolumeSource{
+									LocalObjectReference: corev1.LocalObjectReference{Name: dnsName},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	service := &corev1.Service{ObjectMeta: util.ObjectMeta(dnsNamespace, dnsServiceName)}
+	_, err = controllerruntime.CreateOrUpdate(ctx, guest, service, func() error {
+		util.SetMetaDataLabels(service, dnsObjectLabels)
+		service.Spec.Selector = dnsLabels
+		service.Spec.ClusterIP = serviceIP.String()
+		service.Spec.Ports = []corev1.ServicePort{
+			{Name: "dns", Port: 53, Protocol: corev1.ProtocolUDP, TargetPort: intstr.FromInt(53)},
+			{Name: "dns-tcp", Port: 53, Protocol: corev1.ProtocolTCP, TargetPort: intstr.FromInt(53)},
+			{Name: "metrics", Port: 9153, Protocol: corev1.ProtocolTCP, TargetPort: intstr.FromInt(9153)},
+		}
+		return nil
+	})
+	return err
+}
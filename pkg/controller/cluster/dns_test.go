@@ -0,0 +1,131 @@
+package cluster
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/util"
+	"kubeception.cloud/kubeception/pkg/util/pointers"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("DNS", func() {
+	var cluster *clusterv1alpha1.Cluster
+	BeforeEach(func() {
+		cluster = &clusterv1alpha1.Cluster{
+			Spec: clusterv1alpha1.ClusterSpec{
+				ClusterNetwork: clusterv1alpha1.ClusterNetworkingConfig{
+					Services: clusterv1alpha1.NetworkRanges{CIDRBlocks: []string{"10.96.0.0/12"}},
+				},
+			},
+		}
+	})
+
+	Describe("#DNSServiceIP", func() {
+		It("should use the tenth IP of the service CIDR", func() {
+			ip, err := DNSServiceIP(cluster)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ip.String()).To(Equal("10.96.0.10"))
+		})
+
+		It("should fail without service CIDR", func() {
+			cluster.Spec.ClusterNetwork.Services.CIDRBlocks = nil
+
+			_, err := DNSServiceIP(cluster)
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("#ClusterDomain", func() {
+		It("should default the cluster domain", func() {
+			Expect(ClusterDomain(cluster)).To(Equal("cluster.local"))
+
+			cluster.Spec.ClusterNetwork.ServiceDomain = "example.local"
+			Expect(ClusterDomain(cluster)).To(Equal("example.local"))
+		})
+	})
+
+	Describe("#validateDNS", func() {
+		It("should accept IP addresses with optional port as upstream nameservers", func() {
+			Expect(validateDNS(&v1alpha1.DNS{UpstreamNameservers: []string{"8.8.8.8", "1.1.1.1:53", "[::1]:53"}})).To(Succeed())
+		})
+
+		It("should reject invalid configurations", func() {
+			Expect(validateDNS(&v1alpha1.DNS{Replicas: pointers.Int32(-1)})).NotTo(Succeed())
+			Expect(validateDNS(&v1alpha1.DNS{UpstreamNameservers: []string{"dns.example.com"}})).NotTo(Succeed())
+		})
+	})
+
+	Describe("#corefile", func() {
+		It("should forward to the nameservers of the node by default", func() {
+			Expect(corefile("cluster.local", nil)).To(And(
+				ContainSubstring("kubernetes cluster.local in-addr.arpa ip6.arpa"),
+				ContainSubstring("forward . /etc/resolv.conf"),
+			))
+		})
+
+		It("should forward to the upstream nameservers", func() {
+			Expect(corefile("cluster.local", []string{"8.8.8.8", "1.1.1.1"})).To(ContainSubstring("forward . 8.8.8.8 1.1.1.1"))
+		})
+	})
+
+	Describe("#reconcileDNS", func() {
+		var ctx context.Context
+		BeforeEach(func() {
+			ctx = context.Background()
+		})
+
+		dnsObjects := func() []runtime.Object {
+			return []runtime.Object{
+				&corev1.Service{ObjectMeta: util.ObjectMeta(dnsNamespace, dnsServiceName)},
+				&appsv1.Deployment{ObjectMeta: util.ObjectMeta(dnsNamespace, dnsName)},
+				&corev1.ConfigMap{ObjectMeta: util.ObjectMeta(dnsNamespace, dnsName)},
+				&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: dnsClusterRoleName}},
+				&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: dnsClusterRoleName}},
+				&corev1.ServiceAccount{ObjectMeta: util.ObjectMeta(dnsNamespace, dnsName)},
+			}
+		}
+
+		It("should remove CoreDNS once the DNS configuration is removed", func() {
+			guest := fake.NewFakeClientWithScheme(scheme.Scheme)
+
+			Expect(reconcileDNS(ctx, guest, cluster, &v1alpha1.DNS{})).To(Succeed())
+			for _, obj := range dnsObjects() {
+				key, err := client.ObjectKeyFromObject(obj)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(guest.Get(ctx, key, obj)).To(Succeed())
+			}
+
+			Expect(reconcileDNS(ctx, guest, cluster, nil)).To(Succeed())
+			for _, obj := range dnsObjects() {
+				key, err := client.ObjectKeyFromObject(obj)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(apierrors.IsNotFound(guest.Get(ctx, key, obj))).To(BeTrue())
+			}
+		})
+
+		It("should keep DNS objects that were not created by kubeception", func() {
+			guest := fake.NewFakeClientWithScheme(scheme.Scheme, dnsObjects()...)
+
+			Expect(reconcileDNS(ctx, guest, cluster, nil)).To(Succeed())
+			for _, obj := range dnsObjects() {
+				key, err := client.ObjectKeyFromObject(obj)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(guest.Get(ctx, key, obj)).To(Succeed())
+			}
+		})
+	})
+})
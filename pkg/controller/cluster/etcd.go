@@ -0,0 +1,579 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/controller/common"
+	"kubeception.cloud/kubeception/pkg/util"
+	"kubeception.cloud/kubeception/pkg/util/pointers"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllererror "sigs.k8s.io/cluster-api/pkg/controller/error"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	ETCDClientPort      = 2379
+	ETCDPeerPort        = 2380
+	ETCDServiceName     = "etcd"
+	ETCDPeerServiceName = "etcd-peer"
+	ETCDStatefulSetName = ETCDServiceName
+	ETCDConfigMapName   = ETCDServiceName
+
+	// ETCDMembersKey is the key of the desired number of members in the etcd config map.
+	ETCDMembersKey = "members"
+
+	DefaultETCDReplicas = 1
+
+	ETCDVolumeName   = "data"
+	ETCDVolumeDir    = "/var/lib/etcd"
+	ETCDDataDir      = ETCDVolumeDir + "/data"
+	ETCDServerPKIDir = "/etc/etcd/pki/server"
+	ETCDPeerPKIDir   = "/etc/etcd/pki/peer"
+	ETCDClientPKIDir = "/etc/etcd/pki/client"
+
+	// DefaultETCDStorageSize is the default size of the volume of an etcd member.
+	DefaultETCDStorageSize = "1Gi"
+
+	// ETCDRequeueAfter is the duration after which the progress of an etcd membership change is checked again.
+	ETCDRequeueAfter = 10 * time.Second
+)
+
+var (
+	// ETCDBootstrapReplicasAnnotation is the annotation on the etcd StatefulSet that stores the number of members
+	// the etcd cluster was bootstrapped with.
+	ETCDBootstrapReplicasAnnotation = fmt.Sprintf("%s/etcd-bootstrap-replicas", common.LabelPrefix)
+)
+
+// etcdScript starts an etcd member. If the member has no data and an etcd cluster is already running, the member
+// is (re-)added to the existing cluster via the membership API. Otherwise, it bootstraps a new cluster with
+// the members of INITIAL_CLUSTER. Members whose ordinal is not below MEMBERS, the desired number of members, are
+// being scaled down and exit instead of adding themselves again after they were removed.
+const etcdScript = `
+export ETCDCTL_API=3
+ETCDCTL="etcdctl --dial-timeout=5s --endpoints=https://${CLIENT_SERVICE}:${CLIENT_PORT} --cacert=${PKI_DIR}/ca.crt --cert=${PKI_DIR}/tls.crt --key=${PKI_DIR}/tls.key"
+HOST="${POD_NAME}.${PEER_SERVICE}.${POD_NAMESPACE}.svc"
+PEER_URL="https://${HOST}:${PEER_PORT}"
+CLIENT_URL="https://${HOST}:${CLIENT_PORT}"
+INITIAL_CLUSTER_STATE=new
+
+if ${ETCDCTL} member list > /tmp/members 2>/dev/null; then
+  ID="$(grep ", ${PEER_URL}," /tmp/members | cut -d, -f1)"
+  if [ -d "${DATA_DIR}/member" ] && [ -z "${ID}" ]; then
+    echo "Member ${POD_NAME} has been removed from the cluster, discarding its data"
+    rm -rf "${DATA_DIR}/member"
+  fi
+
+  if [ ! -d "${DATA_DIR}/member" ]; then
+    if [ -n "${ID}" ]; then
+      echo "Removing member ${ID} that lost its data"
+      ${ETCDCTL} member remove "${ID}"
+    fi
+
+    if [ -n "${MEMBERS}" ] && [ "${POD_NAME##*-}" -ge "${MEMBERS}" ]; then
+      echo "Member ${POD_NAME} is not among the ${MEMBERS} desired members, not adding it to the cluster"
+      exit 1
+    fi
+
+    echo "Adding member ${POD_NAME} to the existing cluster"
+    INITIAL_CLUSTER="$(${ETCDCTL} member add "${POD_NAME}" --peer-urls="${PEER_URL}" | grep '^ETCD_INITIAL_CLUSTER=' | cut -d'"' -f2)"
+    INITIAL_CLUSTER_STATE=existing
+  fi
+fi
+
+exec etcd \
+  --name="${POD_NAME}" \
+  --data-dir="${DATA_DIR}" \
+  --advertise-client-urls="${CLIENT_URL}" \
+  --initial-advertise-peer-urls="${PEER_URL}" \
+  --initial-cluster="${INITIAL_CLUSTER}" \
+  --initial-cluster-state="${INITIAL_CLUSTER_STATE}" \
+  "$@"
+`
+
+// etcdMemberRemovalScript removes the member with the peer URL PEER_URL from the etcd cluster, if present.
+const etcdMemberRemovalScript = `
+export ETCDCTL_API=3
+ETCDCTL="etcdctl --dial-timeout=5s --endpoints=https://${CLIENT_SERVICE}:${CLIENT_PORT} --cacert=${PKI_DIR}/ca.crt --cert=${PKI_DIR}/tls.crt --key=${PKI_DIR}/tls.key"
+ID="$(${ETCDCTL} member list | grep ", ${PEER_URL}," | cut -d, -f1)"
+if [ -n "${ID}" ]; then
+  ${ETCDCTL} member remove "${ID}"
+fi
+`
+
+// ETCDMemberName returns the name of the etcd member with the given ordinal.
+func (n Names) ETCDMemberName(ordinal int32) string {
+	return fmt.Sprintf("%s-%d", n.Scoped(ETCDStatefulSetName), ordinal)
+}
+
+// ETCDPeerURL returns the peer URL of the etcd member with the given name.
+func (n Names) ETCDPeerURL(namespace, memberName string) string {
+	return fmt.Sprintf("https://%s.%s.%s.svc:%d", memberName, n.Scoped(ETCDPeerServiceName), namespace, ETCDPeerPort)
+}
+
+// ETCDInitialCluster returns the initial cluster of an etcd cluster bootstrapped with the given number of members.
+func (n Names) ETCDInitialCluster(namespace string, members int32) string {
+	var initialCluster []string
+	for ordinal := int32(0); ordinal < members; ordinal++ {
+		name := n.ETCDMemberName(ordinal)
+		initialCluster = append(initialCluster, fmt.Sprintf("%s=%s", name, n.ETCDPeerURL(namespace, name)))
+	}
+	return strings.Join(initialCluster, ",")
+}
+
+func etcdctlScriptEnv(names Names) []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{Name: "CLIENT_SERVICE", Value: names.Scoped(ETCDServiceName)},
+		{Name: "CLIENT_PORT", Value: strconv.Itoa(ETCDClientPort)},
+	}
+}
+
+func (a *actuator) reconcileETCD(ctx context.Context, cluster *clusterv1alpha1.Cluster, etcd *v1alpha1.ETCD, images *v1alpha1.Images) error {
+	if err := a.reconcileETCDCertificates(ctx, cluster); err != nil {
+		return err
+	}
+
+	names := ClusterNames(cluster)
+	etcdService := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: cluster.Namespace,
+			Name:      names.Scoped(ETCDServiceName),
+		},
+	}
+	if _, err := controllerruntime.CreateOrUpdate(ctx, a.Client, etcdService, func() error {
+		etcdService.Spec.Selector = names.Labels(ETCDComponent)
+		etcdService.Spec.Type = corev1.ServiceTypeClusterIP
+		etcdService.Spec.Ports = []corev1.ServicePort{
+			{
+				Port:       ETCDClientPort,
+				TargetPort: intstr.FromInt(ETCDClientPort),
+			},
+		}
+		util.SetMetaDataLabels(etcdService, names.Labels(ETCDComponent))
+		return controllerruntime.SetControllerReference(cluster, etcdService, a.Scheme)
+	}); err != nil {
+		return err
+	}
+
+	peerService := &corev1.Service{ObjectMeta: util.ObjectMeta(cluster.Namespace, names.Scoped(ETCDPeerServiceName))}
+	if _, err := controllerruntime.CreateOrUpdate(ctx, a.Client, peerService, func() error {
+		peerService.Spec.Selector = names.Labels(ETCDComponent)
+		peerService.Spec.Type = corev1.ServiceTypeClusterIP
+		peerService.Spec.ClusterIP = corev1.ClusterIPNone
+		peerService.Spec.PublishNotReadyAddresses = true
+		peerService.Spec.Ports = []corev1.ServicePort{
+			{
+				Name:       "client",
+				Port:       ETCDClientPort,
+				TargetPort: intstr.FromInt(ETCDClientPort),
+			},
+			{
+				Name:       "peer",
+				Port:       ETCDPeerPort,
+				TargetPort: intstr.FromInt(ETCDPeerPort),
+			},
+		}
+		util.SetMetaDataLabels(peerService, names.Labels(ETCDComponent))
+		return controllerruntime.SetControllerReference(cluster, peerService, a.Scheme)
+	}); err != nil {
+		return err
+	}
+
+	desiredReplicas := pointers.DerefInt32OrDefault(etcd.Replicas, DefaultETCDReplicas)
+	if err := a.reconcilePodDisruptionBudget(ctx, cluster, names.Scoped(ETCDStatefulSetName), ETCDComponent, etcdMaxUnavailable(desiredReplicas)); err != nil {
+		return err
+	}
+
+	// The desired number of members has to be known to the members before one of them is removed, so that it
+	// does not add itself again when it restarts after its removal.
+	configMap := &corev1.ConfigMap{ObjectMeta: util.ObjectMeta(cluster.Namespace, names.Scoped(ETCDConfigMapName))}
+	if _, err := controllerruntime.CreateOrUpdate(ctx, a.Client, configMap, func() error {
+		configMap.Data = map[string]string{ETCDMembersKey: strconv.Itoa(int(desiredReplicas))}
+		util.SetMetaDataLabels(configMap, names.Labels(ETCDComponent))
+		return controllerruntime.SetControllerReference(cluster, configMap, a.Scheme)
+	}); err != nil {
+		return err
+	}
+
+	existing := &appsv1.StatefulSet{}
+	if err := a.Client.Get(ctx, util.Key(cluster.Namespace, names.Scoped(ETCDStatefulSetName)), existing); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		existing = nil
+	}
+
+	if existing != nil {
+		// The volume claim templates of StatefulSets created before etcd had a data volume cannot be added. They
+		// are recreated with the configured storage like StatefulSets whose other immutable fields changed.
+		if !etcdHasDataVolume(existing) {
+			return a.orphanETCDStatefulSet(ctx, existing)
+		}
+		if err := validateETCDStorageUnchanged(existing, names, &etcd.Storage); err != nil {
+			return fmt.Errorf("invalid etcd configuration: %v", err)
+		}
+	}
+
+	if restoreID := ETCDRestoreID(etcd.RestoreFrom); restoreID != "" && (existing == nil || existing.Annotations[ETCDRestoredAnnotation] != restoreID) {
+		return a.restoreETCD(ctx, cluster, etcd, images, existing, restoreID)
+	}
+
+	replicas, bootstrapReplicas := desiredReplicas, desiredReplicas
+	if existing != nil {
+		currentReplicas := pointers.DerefInt32OrDefault(existing.Spec.Replicas, 1)
+		bootstrapReplicas = currentReplicas
+		if value, ok := existing.Annotations[ETCDBootstrapReplicasAnnotation]; ok {
+			if parsed, err := strconv.ParseInt(value, 10, 32); err == nil {
+				bootstrapReplicas = int32(parsed)
+			}
+		}
+
+		spec := etcdStatefulSetSpec(cluster, etcd, images, currentReplicas, bootstrapReplicas, "")
+		hash, err := common.StatefulSetImmutableSpecHash(&spec)
+		if err != nil {
+			return err
+		}
+
+		// Fields like the service name cannot be updated.
+		if common.StatefulSetNeedsRecreation(existing, hash) {
+			return a.orphanETCDStatefulSet(ctx, existing)
+		}
+
+		replicas, err = a.scaleETCD(ctx, cluster, images, existing, desiredReplicas)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := a.applyETCDStatefulSet(ctx, cluster, etcd, images, replicas, bootstrapReplicas, "", nil); err != nil {
+		return err
+	}
+
+	if replicas != desiredReplicas {
+		return &controllererror.RequeueAfterError{RequeueAfter: ETCDRequeueAfter}
+	}
+	return nil
+}
+
+// orphanETCDStatefulSet deletes the given etcd StatefulSet so that it is recreated on the next reconciliation.
+// Its pods are orphaned so that they are adopted by the recreated StatefulSet.
+func (a *actuator) orphanETCDStatefulSet(ctx context.Context, existing *appsv1.StatefulSet) error {
+	if err := a.Client.Delete(ctx, existing, client.PropagationPolicy(metav1.DeletePropagationOrphan)); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	return &controllererror.RequeueAfterError{RequeueAfter: ETCDRequeueAfter}
+}
+
+// applyETCDStatefulSet creates or updates the etcd StatefulSet. If a restore ID is given, the members restore the
+// corresponding snapshot on startup. The optional mutate function may modify the StatefulSet further.
+func (a *actuator) applyETCDStatefulSet(ctx context.Context, cluster *clusterv1alpha1.Cluster, etcd *v1alpha1.ETCD, images *v1alpha1.Images, replicas, bootstrapReplicas int32, restoreID string, mutate func(*appsv1.StatefulSet)) error {
+	spec := etcdStatefulSetSpec(cluster, etcd, images, replicas, bootstrapReplicas, restoreID)
+	hash, err := common.StatefulSetImmutableSpecHash(&spec)
+	if err != nil {
+		return err
+	}
+
+	names := ClusterNames(cluster)
+	etcdStatefulSet := &appsv1.StatefulSet{ObjectMeta: util.ObjectMeta(cluster.Namespace, names.Scoped(ETCDStatefulSetName))}
+	_, err = controllerruntime.CreateOrUpdate(ctx, a.Client, etcdStatefulSet, func() error {
+		util.SetMetaDataLabels(etcdStatefulSet, names.Labels(ETCDComponent))
+		util.SetMetaDataAnnotations(etcdStatefulSet, map[string]string{
+			common.ImmutableSpecHashAnnotation: hash,
+			ETCDBootstrapReplicasAnnotation:    strconv.Itoa(int(bootstrapReplicas)),
+		})
+		etcdStatefulSet.Spec = spec
+		if mutate != nil {
+			mutate(etcdStatefulSet)
+		}
+		return controllerruntime.SetControllerReference(cluster, etcdStatefulSet, a.Scheme)
+	})
+	return err
+}
+
+// scaleETCD computes the next number of replicas of the given etcd StatefulSet on the way to the desired replicas.
+// Members are added one at a time and only if all current members are ready. New members add themselves to the
+// cluster on startup. Before a member is removed, it is removed from the etcd cluster via a membership job.
+func (a *actuator) scaleETCD(ctx context.Context, cluster *clusterv1alpha1.Cluster, images *v1alpha1.Images, statefulSet *appsv1.StatefulSet, desiredReplicas int32) (int32, error) {
+	currentReplicas := pointers.DerefInt32OrDefault(statefulSet.Spec.Replicas, 1)
+	switch {
+	case desiredReplicas > currentReplicas:
+		if !common.StatefulSetReady(statefulSet) {
+			return currentReplicas, nil
+		}
+		return currentReplicas + 1, nil
+	case desiredReplicas < currentReplicas:
+		removed, err := a.removeETCDMember(ctx, cluster, images, currentReplicas-1)
+		if err != nil || !removed {
+			return currentReplicas, err
+		}
+		return currentReplicas - 1, nil
+	default:
+		return currentReplicas, nil
+	}
+}
+
+// removeETCDMember ensures a job removing the etcd member with the given ordinal exists.
+// It returns true if the job succeeded, in which case the job is deleted.
+func (a *actuator) removeETCDMember(ctx context.Context, cluster *clusterv1alpha1.Cluster, images *v1alpha1.Images, ordinal int32) (bool, error) {
+	memberName := ClusterNames(cluster).ETCDMemberName(ordinal)
+	job := &batchv1.Job{}
+	if err := a.Client.Get(ctx, util.Key(cluster.Namespace, fmt.Sprintf("%s-remove", memberName)), job); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+
+		job = etcdMemberRemovalJob(cluster, images, memberName)
+		if err := controllerruntime.SetControllerReference(cluster, job, a.Scheme); err != nil {
+			return false, err
+		}
+		return false, a.Client.Create(ctx, job)
+	}
+
+	if job.Status.Succeeded > 0 {
+		return true, client.IgnoreNotFound(a.Client.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)))
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return false, fmt.Errorf("could not remove etcd member %s: %s", memberName, condition.Message)
+		}
+	}
+	return false, nil
+}
+
+func etcdMemberRemovalJob(cluster *clusterv1alpha1.Cluster, images *v1alpha1.Images, memberName string) *batchv1.Job {
+	names := ClusterNames(cluster)
+	return &batchv1.Job{
+		ObjectMeta: util.ObjectMeta(cluster.Namespace, fmt.Sprintf("%s-remove", memberName)),
+		Spec: batchv1.JobSpec{
+			BackoffLimit: pointers.Int32(3),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy:                corev1.RestartPolicyNever,
+					AutomountServiceAccountToken: pointers.Bool(false),
+					ImagePullSecrets:             common.ImagePullSecrets(images),
+					Containers: []corev1.Container{
+						{
+							Name:    "etcdctl",
+							Image:   common.ETCDImage(images),
+							Command: []string{"/bin/sh", "-ec", etcdMemberRemovalScript},
+							Env: append(etcdctlScriptEnv(names),
+								corev1.EnvVar{Name: "PKI_DIR", Value: ETCDClientPKIDir},
+								corev1.EnvVar{Name: "PEER_URL", Value: names.ETCDPeerURL(cluster.Namespace, memberName)},
+							),
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      "client-certificate",
+									MountPath: ETCDClientPKIDir,
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						CertificateVolume("client-certificate", names.Scoped(ETCDClientCertificateName), names.Scoped(CACertificateName)),
+					},
+				},
+			},
+		},
+	}
+}
+
+// etcdVolumes returns the data volumes of the etcd pods (in case of emptyDir storage) or the volume claim templates
+// of the etcd StatefulSet (in case of persistent storage).
+func etcdVolumes(names Names, storage *v1alpha1.ETCDStorage) ([]corev1.Volume, []corev1.PersistentVolumeClaim) {
+	if storage.EmptyDir {
+		return []corev1.Volume{
+			{
+				Name:         ETCDVolumeName,
+				VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+			},
+		}, nil
+	}
+
+	size := resource.MustParse(DefaultETCDStorageSize)
+	if storage.Size != nil {
+		size = *storage.Size
+	}
+
+	return nil, []corev1.PersistentVolumeClaim{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   ETCDVolumeName,
+				Labels: names.Labels(ETCDComponent),
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				StorageClassName: storage.StorageClassName,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceStorage: size,
+					},
+				},
+			},
+		},
+	}
+}
+
+// etcdHasDataVolume checks whether the given etcd StatefulSet has a data volume, either as volume claim template or
+// as volume of its pods.
+func etcdHasDataVolume(statefulSet *appsv1.StatefulSet) bool {
+	if len(statefulSet.Spec.VolumeClaimTemplates) > 0 {
+		return true
+	}
+	for _, volume := range statefulSet.Spec.Template.Spec.Volumes {
+		if volume.Name == ETCDVolumeName {
+			return true
+		}
+	}
+	return false
+}
+
+// validateETCDStorageUnchanged checks that the given storage configuration matches the storage of the given existing
+// etcd StatefulSet. The volume claim templates of a StatefulSet cannot be updated, and replacing the volumes of the
+// members would lose the data of the cluster.
+func validateETCDStorageUnchanged(existing *appsv1.StatefulSet, names Names, storage *v1alpha1.ETCDStorage) error {
+	_, volumeClaimTemplates := etcdVolumes(names, storage)
+	if len(volumeClaimTemplates) != len(existing.Spec.VolumeClaimTemplates) {
+		return fmt.Errorf("the storage of an existing etcd cannot be switched between emptyDir and persistent volumes, restore a backup into a new cluster instead")
+	}
+
+	for i, desired := range volumeClaimTemplates {
+		current := existing.Spec.VolumeClaimTemplates[i]
+		desiredSize, currentSize := desired.Spec.Resources.Requests[corev1.ResourceStorage], current.Spec.Resources.Requests[corev1.ResourceStorage]
+		if desiredSize.Cmp(currentSize) != 0 {
+			return fmt.Errorf("the storage size of an existing etcd cannot be changed from %s to %s", currentSize.String(), desiredSize.String())
+		}
+		desiredClass, currentClass := pointers.DerefStringOrDefault(desired.Spec.StorageClassName, ""), pointers.DerefStringOrDefault(current.Spec.StorageClassName, "")
+		if desiredClass != currentClass {
+			return fmt.Errorf("the storage class of an existing etcd cannot be changed from %q to %q", currentClass, desiredClass)
+		}
+	}
+	return nil
+}
+
+func etcdStatefulSetSpec(cluster *clusterv1alpha1.Cluster, etcd *v1alpha1.ETCD, images *v1alpha1.Images, replicas, bootstrapReplicas int32, restoreID string) appsv1.StatefulSetSpec {
+	names := ClusterNames(cluster)
+	volumes, volumeClaimTemplates := etcdVolumes(names, &etcd.Storage)
+	spec := appsv1.StatefulSetSpec{
+		Replicas:            pointers.Int32(replicas),
+		ServiceName:         names.Scoped(ETCDPeerServiceName),
+		PodManagementPolicy: appsv1.ParallelPodManagement,
+		Selector: &metav1.LabelSelector{
+			MatchLabels: names.Labels(ETCDComponent),
+		},
+		Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: names.Labels(ETCDComponent),
+			},
+			Spec: corev1.PodSpec{
+				AutomountServiceAccountToken: pointers.Bool(false),
+				Affinity:                     podAntiAffinity(names.Labels(ETCDComponent)),
+				ImagePullSecrets:             common.ImagePullSecrets(images),
+				Containers: []corev1.Container{
+					{
+						Name:  "etcd",
+						Image: common.ETCDImage(images),
+						Command: []string{
+							"/bin/sh",
+							"-ec",
+							etcdScript,
+							"etcd",
+							fmt.Sprintf("--listen-client-urls=https://0.0.0.0:%d", ETCDClientPort),
+							"--client-cert-auth=true",
+							fmt.Sprintf("--trusted-ca-file=%s/%s", ETCDServerPKIDir, CAFile),
+							fmt.Sprintf("--cert-file=%s/%s", ETCDServerPKIDir, CertFile),
+							fmt.Sprintf("--key-file=%s/%s", ETCDServerPKIDir, KeyFile),
+							fmt.Sprintf("--listen-peer-urls=https://0.0.0.0:%d", ETCDPeerPort),
+							"--peer-client-cert-auth=true",
+							fmt.Sprintf("--peer-trusted-ca-file=%s/%s", ETCDPeerPKIDir, CAFile),
+							fmt.Sprintf("--peer-cert-file=%s/%s", ETCDPeerPKIDir, CertFile),
+							fmt.Sprintf("--peer-key-file=%s/%s", ETCDPeerPKIDir, KeyFile),
+						},
+						Env: append(etcdctlScriptEnv(names),
+							corev1.EnvVar{Name: "POD_NAME", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}}},
+							corev1.EnvVar{Name: "POD_NAMESPACE", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"}}},
+							corev1.EnvVar{Name: "PEER_SERVICE", Value: names.Scoped(ETCDPeerServiceName)},
+							corev1.EnvVar{Name: "PEER_PORT", Value: strconv.Itoa(ETCDPeerPort)},
+							corev1.EnvVar{Name: "PKI_DIR", Value: ETCDServerPKIDir},
+							corev1.EnvVar{Name: "DATA_DIR", Value: ETCDDataDir},
+							corev1.EnvVar{Name: "INITIAL_CLUSTER", Value: names.ETCDInitialCluster(cluster.Namespace, bootstrapReplicas)},
+							// The desired number of members is read from the config map instead of the pod template
+							// so that changing it does not restart all members.
+							corev1.EnvVar{Name: "MEMBERS", ValueFrom: &corev1.EnvVarSource{
+								ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+									LocalObjectReference: corev1.LocalObjectReference{Name: names.Scoped(ETCDConfigMapName)},
+									Key:                  ETCDMembersKey,
+									Optional:             pointers.Bool(true),
+								},
+							}},
+						),
+						Ports: []corev1.ContainerPort{
+							{
+								ContainerPort: ETCDClientPort,
+								Name:          "etcd",
+							},
+							{
+								ContainerPort: ETCDPeerPort,
+								Name:          "peer",
+							},
+						},
+						ReadinessProbe: &corev1.Probe{
+							Handler: corev1.Handler{
+								Exec: &corev1.ExecAction{
+									Command: []string{
+										"/bin/sh",
+										"-ec",
+										fmt.Sprintf("ETCDCTL_API=3 etcdctl --endpoints=https://127.0.0.1:%d --cacert=%s/%s --cert=%s/%s --key=%s/%s endpoint health",
+											ETCDClientPort, ETCDServerPKIDir, CAFile, ETCDServerPKIDir, CertFile, ETCDServerPKIDir, KeyFile),
+									},
+								},
+							},
+							PeriodSeconds:  5,
+							TimeoutSeconds: 5,
+						},
+						VolumeMounts: []corev1.VolumeMount{
+							{
+								Name:      "server-certificate",
+								MountPath: ETCDServerPKIDir,
+							},
+							{
+								Name:      "peer-certificate",
+								MountPath: ETCDPeerPKIDir,
+							},
+							{
+								Name:      ETCDVolumeName,
+								MountPath: ETCDVolumeDir,
+							},
+						},
+					},
+				},
+				Volumes: append([]corev1.Volume{
+					CertificateVolume("server-certificate", names.Scoped(ETCDServerCertificateName), names.Scoped(CACertificateName)),
+					CertificateVolume("peer-certificate", names.Scoped(ETCDPeerCertificateName), names.Scoped(CACertificateName)),
+				}, volumes...),
+			},
+		},
+		VolumeClaimTemplates: volumeClaimTemplates,
+	}
+	common.ApplyWorkloadSettings(&spec.Template.Spec, &etcd.WorkloadSettings)
+
+	if restoreID != "" {
+		podSpec := &spec.Template.Spec
+		podSpec.InitContainers = append(podSpec.InitContainers, etcdRestoreInitContainer(cluster, images, etcd.RestoreFrom, restoreID, bootstrapReplicas))
+		podSpec.Volumes = append(podSpec.Volumes, etcdRestoreVolume(etcd.RestoreFrom))
+		spec.Template.Annotations = map[string]string{ETCDRestoreAnnotation: restoreID}
+	}
+	return spec
+}
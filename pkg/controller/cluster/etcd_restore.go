@@ -0,0 +1,247 @@
+package cluster
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/controller/common"
+	"kubeception.cloud/kubeception/pkg/util"
+	"kubeception.cloud/kubeception/pkg/util/pointers"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllererror "sigs.k8s.io/cluster-api/pkg/controller/error"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ETCDRestoreDir is the directory the snapshot source is mounted at in the restore init container.
+	ETCDRestoreDir = "/var/lib/etcd-restore"
+	// ETCDRestoreMarkerFile is the file in the data volume that records the ID of the last restored snapshot.
+	ETCDRestoreMarkerFile = ETCDVolumeDir + "/restored-from"
+	// DefaultETCDRestoreSecretKey is the default key of the snapshot in a restore secret.
+	DefaultETCDRestoreSecretKey = "snapshot.db"
+
+	etcdRestoreVolumeName = "restore-source"
+)
+
+var (
+	// ETCDRestoringAnnotation is the annotation on the etcd StatefulSet that carries the ID of the snapshot that
+	// is currently being restored. While it is present, the API server is scaled down.
+	ETCDRestoringAnnotation = fmt.Sprintf("%s/etcd-restoring", common.LabelPrefix)
+	// ETCDRestoredAnnotation is the annotation on the etcd StatefulSet that carries the ID of the last snapshot
+	// that has been restored.
+	ETCDRestoredAnnotation = fmt.Sprintf("%s/etcd-restored", common.LabelPrefix)
+	// ETCDRestoreAnnotation is the annotation on the etcd pod template that carries the ID of the snapshot the
+	// members restore on startup.
+	ETCDRestoreAnnotation = fmt.Sprintf("%s/etcd-restore", common.LabelPrefix)
+)
+
+// etcdRestoreScript restores the snapshot SNAPSHOT_FILE into the data directory of the member, unless the snapshot
+// with RESTORE_ID has already been restored into it.
+const etcdRestoreScript = `
+export ETCDCTL_API=3
+if [ "$(cat "${RESTORE_MARKER}" 2>/dev/null)" = "${RESTORE_ID}" ]; then
+  echo "Snapshot ${RESTORE_ID} has already been restored"
+  exit 0
+fi
+
+HOST="${POD_NAME}.${PEER_SERVICE}.${POD_NAMESPACE}.svc"
+rm -rf "${DATA_DIR}"
+etcdctl snapshot restore "${SNAPSHOT_FILE}" \
+  --name="${POD_NAME}" \
+  --data-dir="${DATA_DIR}" \
+  --initial-cluster="${INITIAL_CLUSTER}" \
+  --initial-cluster-token="${RESTORE_ID}" \
+  --initial-advertise-peer-urls="https://${HOST}:${PEER_PORT}"
+echo "${RESTORE_ID}" > "${RESTORE_MARKER}"
+`
+
+// ETCDRestoreID returns an ID identifying the given restore source or the empty string if the source is nil.
+func ETCDRestoreID(source *v1alpha1.ETCDRestoreSource) string {
+	if source == nil {
+		return ""
+	}
+
+	data, err := json.Marshal(source)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+func validateETCDRestoreSource(source *v1alpha1.ETCDRestoreSource) error {
+	if (source.PersistentVolumeClaim == nil) == (source.Secret == nil) {
+		return fmt.Errorf("exactly one of persistentVolumeClaim and secret has to be specified as etcd restore source")
+	}
+	return nil
+}
+
+func etcdRestoreVolume(source *v1alpha1.ETCDRestoreSource) corev1.Volume {
+	volume := corev1.Volume{Name: etcdRestoreVolumeName}
+	if claim := source.PersistentVolumeClaim; claim != nil {
+		volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: claim.ClaimName,
+			ReadOnly:  true,
+		}
+		return volume
+	}
+
+	key := source.Secret.Key
+	if key == "" {
+		key = DefaultETCDRestoreSecretKey
+	}
+	volume.Secret = &corev1.SecretVolumeSource{
+		SecretName: source.Secret.Name,
+		Items:      []corev1.KeyToPath{{Key: key, Path: DefaultETCDRestoreSecretKey}},
+	}
+	return volume
+}
+
+func etcdRestoreSnapshotFile(source *v1alpha1.ETCDRestoreSource) string {
+	if claim := source.PersistentVolumeClaim; claim != nil {
+		return path.Join(ETCDRestoreDir, claim.Path)
+	}
+	return path.Join(ETCDRestoreDir, DefaultETCDRestoreSecretKey)
+}
+
+func etcdRestoreInitContainer(cluster *clusterv1alpha1.Cluster, images *v1alpha1.Images, source *v1alpha1.ETCDRestoreSource, restoreID string, members int32) corev1.Container {
+	names := ClusterNames(cluster)
+	return corev1.Container{
+		Name:    "restore",
+		Image:   common.ETCDImage(images),
+		Command: []string{"/bin/sh", "-ec", etcdRestoreScript},
+		Env: []corev1.EnvVar{
+			{Name: "POD_NAME", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}}},
+			{Name: "POD_NAMESPACE", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"}}},
+			{Name: "PEER_SERVICE", Value: names.Scoped(ETCDPeerServiceName)},
+			{Name: "PEER_PORT", Value: strconv.Itoa(ETCDPeerPort)},
+			{Name: "DATA_DIR", Value: ETCDDataDir},
+			{Name: "INITIAL_CLUSTER", Value: names.ETCDInitialCluster(cluster.Namespace, members)},
+			{Name: "RESTORE_ID", Value: restoreID},
+			{Name: "RESTORE_MARKER", Value: ETCDRestoreMarkerFile},
+			{Name: "SNAPSHOT_FILE", Value: etcdRestoreSnapshotFile(source)},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      ETCDVolumeName,
+				MountPath: ETCDVolumeDir,
+			},
+			{
+				Name:      etcdRestoreVolumeName,
+				MountPath: ETCDRestoreDir,
+				ReadOnly:  true,
+			},
+		},
+	}
+}
+
+// podsExist checks whether there are any pods with the given labels in the namespace.
+func (a *actuator) podsExist(ctx context.Context, namespace string, labels map[string]string) (bool, error) {
+	podList := &corev1.PodList{}
+	if err := a.Client.List(ctx, podList, client.InNamespace(namespace), client.MatchingLabels(labels)); err != nil {
+		return false, err
+	}
+	return len(podList.Items) > 0, nil
+}
+
+// etcdRestoring checks whether a snapshot is currently being restored into the etcd of the given cluster.
+func (a *actuator) etcdRestoring(ctx context.Context, cluster *clusterv1alpha1.Cluster) (bool, error) {
+	statefulSet := &appsv1.StatefulSet{}
+	if err := a.Client.Get(ctx, util.Key(cluster.Namespace, ClusterNames(cluster).Scoped(ETCDStatefulSetName)), statefulSet); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	_, ok := statefulSet.Annotations[ETCDRestoringAnnotation]
+	return ok, nil
+}
+
+// restoreETCD restores the snapshot with the given restore ID into the etcd of the cluster. It proceeds in steps,
+// returning a RequeueAfterError until the restore is finished:
+//
+//  1. The restore is marked as in progress on the StatefulSet, which scales the API server down.
+//  2. Once no API server pods are left, all members are stopped at once with a pod template that restores the
+//     snapshot in an init container. The restored members must not join the old cluster.
+//  3. Once no member pods are left, the members are started again and bootstrap a new cluster from the snapshot.
+//  4. Once all members are ready, the restore init container is removed again and the restore is marked as done.
+//     Members added later on join the restored cluster instead of restoring the snapshot themselves.
+//
+// A cluster without an etcd StatefulSet is bootstrapped from the snapshot right away.
+func (a *actuator) restoreETCD(ctx context.Context, cluster *clusterv1alpha1.Cluster, etcd *v1alpha1.ETCD, images *v1alpha1.Images, existing *appsv1.StatefulSet, restoreID string) error {
+	if err := validateETCDRestoreSource(etcd.RestoreFrom); err != nil {
+		return err
+	}
+
+	names := ClusterNames(cluster)
+	requeue := &controllererror.RequeueAfterError{RequeueAfter: ETCDRequeueAfter}
+	replicas := pointers.DerefInt32OrDefault(etcd.Replicas, DefaultETCDReplicas)
+	setRestoring := func(statefulSet *appsv1.StatefulSet) {
+		util.SetMetaDataAnnotation(statefulSet, ETCDRestoringAnnotation, restoreID)
+	}
+
+	if existing == nil {
+		if err := a.applyETCDStatefulSet(ctx, cluster, etcd, images, replicas, replicas, restoreID, setRestoring); err != nil {
+			return err
+		}
+		return requeue
+	}
+
+	if existing.Annotations[ETCDRestoringAnnotation] != restoreID {
+		withoutAnnotation := existing.DeepCopy()
+		setRestoring(existing)
+		if err := a.Client.Patch(ctx, existing, client.MergeFrom(withoutAnnotation)); err != nil {
+			return err
+		}
+		return requeue
+	}
+
+	if running, err := a.podsExist(ctx, cluster.Namespace, names.Labels(APIServerComponent)); err != nil || running {
+		if err != nil {
+			return err
+		}
+		return requeue
+	}
+
+	if existing.Spec.Template.Annotations[ETCDRestoreAnnotation] != restoreID {
+		if err := a.applyETCDStatefulSet(ctx, cluster, etcd, images, 0, replicas, restoreID, setRestoring); err != nil {
+			return err
+		}
+		return requeue
+	}
+
+	if pointers.DerefInt32OrDefault(existing.Spec.Replicas, 1) == 0 {
+		if running, err := a.podsExist(ctx, cluster.Namespace, names.Labels(ETCDComponent)); err != nil || running {
+			if err != nil {
+				return err
+			}
+			return requeue
+		}
+
+		if err := a.applyETCDStatefulSet(ctx, cluster, etcd, images, replicas, replicas, restoreID, setRestoring); err != nil {
+			return err
+		}
+		return requeue
+	}
+
+	if !common.StatefulSetReady(existing) {
+		return requeue
+	}
+
+	restoredReplicas := pointers.DerefInt32OrDefault(existing.Spec.Replicas, 1)
+	return a.applyETCDStatefulSet(ctx, cluster, etcd, images, restoredReplicas, restoredReplicas, "", func(statefulSet *appsv1.StatefulSet) {
+		delete(statefulSet.Annotations, ETCDRestoringAnnotation)
+		util.SetMetaDataAnnotation(statefulSet, ETCDRestoredAnnotation, restoreID)
+	})
+}
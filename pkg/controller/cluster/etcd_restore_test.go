@@ -0,0 +1,155 @@
+package cluster
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/util"
+	"kubeception.cloud/kubeception/pkg/util/pointers"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllererror "sigs.k8s.io/cluster-api/pkg/controller/error"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("ETCD Restore", func() {
+	Describe("#restoreETCD", func() {
+		var (
+			ctx       context.Context
+			a         *actuator
+			c         client.Client
+			cluster   *clusterv1alpha1.Cluster
+			names     Names
+			etcd      *v1alpha1.ETCD
+			restoreID string
+		)
+		BeforeEach(func() {
+			ctx = context.Background()
+			cluster = newFakeCluster()
+			names = ClusterNames(cluster)
+			a, c = newFakeActuator(cluster)
+			etcd = &v1alpha1.ETCD{
+				Replicas:    pointers.Int32(3),
+				RestoreFrom: &v1alpha1.ETCDRestoreSource{Secret: &v1alpha1.ETCDRestoreSecretSource{Name: "snapshot"}},
+			}
+			restoreID = ETCDRestoreID(etcd.RestoreFrom)
+		})
+
+		getStatefulSet := func() *appsv1.StatefulSet {
+			statefulSet := &appsv1.StatefulSet{}
+			if err := c.Get(ctx, util.Key(cluster.Namespace, names.Scoped(ETCDStatefulSetName)), statefulSet); err != nil {
+				Expect(apierrors.IsNotFound(err)).To(BeTrue())
+				return nil
+			}
+			return statefulSet
+		}
+
+		restore := func() error {
+			return a.restoreETCD(ctx, cluster, etcd, nil, getStatefulSet(), restoreID)
+		}
+
+		createPod := func(name, component string) *corev1.Pod {
+			pod := &corev1.Pod{ObjectMeta: util.ObjectMeta(cluster.Namespace, name)}
+			pod.Labels = names.Labels(component)
+			Expect(c.Create(ctx, pod)).To(Succeed())
+			return pod
+		}
+
+		hasRestoreInitContainer := func(statefulSet *appsv1.StatefulSet) bool {
+			for _, container := range statefulSet.Spec.Template.Spec.InitContainers {
+				if container.Name == "restore" {
+					return true
+				}
+			}
+			return false
+		}
+
+		requeue := BeAssignableToTypeOf(&controllererror.RequeueAfterError{})
+
+		It("should bootstrap a new etcd from the snapshot", func() {
+			Expect(restore()).To(requeue)
+
+			statefulSet := getStatefulSet()
+			Expect(statefulSet).NotTo(BeNil())
+			Expect(statefulSet.Spec.Replicas).To(Equal(pointers.Int32(3)))
+			Expect(statefulSet.Annotations).To(HaveKeyWithValue(ETCDRestoringAnnotation, restoreID))
+			Expect(statefulSet.Spec.Template.Annotations).To(HaveKeyWithValue(ETCDRestoreAnnotation, restoreID))
+			Expect(hasRestoreInitContainer(statefulSet)).To(BeTrue())
+		})
+
+		It("should step through the restore of an existing etcd", func() {
+			Expect(a.applyETCDStatefulSet(ctx, cluster, &v1alpha1.ETCD{Replicas: pointers.Int32(3)}, nil, 3, 3, "", nil)).To(Succeed())
+			apiServerPod := createPod("foo-apiserver-abcde", APIServerComponent)
+			etcdPod := createPod("foo-etcd-0", ETCDComponent)
+
+			By("marking the restore as in progress")
+			Expect(restore()).To(requeue)
+			statefulSet := getStatefulSet()
+			Expect(statefulSet.Annotations).To(HaveKeyWithValue(ETCDRestoringAnnotation, restoreID))
+			Expect(statefulSet.Spec.Replicas).To(Equal(pointers.Int32(3)))
+			Expect(hasRestoreInitContainer(statefulSet)).To(BeFalse())
+
+			By("waiting for the API server to be scaled down")
+			Expect(restore()).To(requeue)
+			Expect(getStatefulSet().Spec.Replicas).To(Equal(pointers.Int32(3)))
+			Expect(c.Delete(ctx, apiServerPod)).To(Succeed())
+
+			By("stopping all members with the restore pod template")
+			Expect(restore()).To(requeue)
+			statefulSet = getStatefulSet()
+			Expect(statefulSet.Spec.Replicas).To(Equal(pointers.Int32(0)))
+			Expect(statefulSet.Spec.Template.Annotations).To(HaveKeyWithValue(ETCDRestoreAnnotation, restoreID))
+			Expect(hasRestoreInitContainer(statefulSet)).To(BeTrue())
+
+			By("waiting for the members to be stopped")
+			Expect(restore()).To(requeue)
+			Expect(getStatefulSet().Spec.Replicas).To(Equal(pointers.Int32(0)))
+			Expect(c.Delete(ctx, etcdPod)).To(Succeed())
+
+			By("starting the members again")
+			Expect(restore()).To(requeue)
+			statefulSet = getStatefulSet()
+			Expect(statefulSet.Spec.Replicas).To(Equal(pointers.Int32(3)))
+			Expect(statefulSet.Annotations).To(HaveKeyWithValue(ETCDRestoringAnnotation, restoreID))
+			Expect(hasRestoreInitContainer(statefulSet)).To(BeTrue())
+
+			By("waiting for the members to be ready")
+			Expect(restore()).To(requeue)
+			statefulSet = getStatefulSet()
+			Expect(statefulSet.Annotations).NotTo(HaveKey(ETCDRestoredAnnotation))
+			statefulSet.Status.UpdatedReplicas = 3
+			statefulSet.Status.ReadyReplicas = 3
+			Expect(c.Status().Update(ctx, statefulSet)).To(Succeed())
+
+			By("marking the restore as done")
+			Expect(restore()).To(Succeed())
+			statefulSet = getStatefulSet()
+			Expect(statefulSet.Spec.Replicas).To(Equal(pointers.Int32(3)))
+			Expect(statefulSet.Annotations).NotTo(HaveKey(ETCDRestoringAnnotation))
+			Expect(statefulSet.Annotations).To(HaveKeyWithValue(ETCDRestoredAnnotation, restoreID))
+			Expect(statefulSet.Spec.Template.Annotations).NotTo(HaveKey(ETCDRestoreAnnotation))
+			Expect(hasRestoreInitContainer(statefulSet)).To(BeFalse())
+		})
+
+		table.DescribeTable("should reject an invalid restore source",
+			func(source *v1alpha1.ETCDRestoreSource) {
+				etcd.RestoreFrom = source
+
+				err := restore()
+				Expect(err).To(HaveOccurred())
+				Expect(err).NotTo(requeue)
+				Expect(getStatefulSet()).To(BeNil())
+			},
+			table.Entry("no source", &v1alpha1.ETCDRestoreSource{}),
+			table.Entry("both sources", &v1alpha1.ETCDRestoreSource{
+				PersistentVolumeClaim: &v1alpha1.ETCDRestorePersistentVolumeClaimSource{ClaimName: "backups", Path: "a.db"},
+				Secret:                &v1alpha1.ETCDRestoreSecretSource{Name: "snapshot"},
+			}),
+		)
+	})
+})
@@ -0,0 +1,268 @@
+package cluster
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/util"
+	"kubeception.cloud/kubeception/pkg/util/pointers"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllererror "sigs.k8s.io/cluster-api/pkg/controller/error"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ETCD", func() {
+	Describe("#ETCDInitialCluster", func() {
+		It("should list the peer URLs of all bootstrap members", func() {
+			names := Names{Cluster: "bar"}
+			Expect(names.ETCDInitialCluster("foo", 3)).To(Equal(
+				"bar-etcd-0=https://bar-etcd-0.bar-etcd-peer.foo.svc:2380," +
+					"bar-etcd-1=https://bar-etcd-1.bar-etcd-peer.foo.svc:2380," +
+					"bar-etcd-2=https://bar-etcd-2.bar-etcd-peer.foo.svc:2380",
+			))
+		})
+
+		It("should use the unscoped names of legacy clusters", func() {
+			names := Names{Cluster: "bar", Legacy: true}
+			Expect(names.ETCDInitialCluster("foo", 3)).To(Equal(
+				"etcd-0=https://etcd-0.etcd-peer.foo.svc:2380," +
+					"etcd-1=https://etcd-1.etcd-peer.foo.svc:2380," +
+					"etcd-2=https://etcd-2.etcd-peer.foo.svc:2380",
+			))
+		})
+	})
+
+	Describe("#ETCDRestoreID", func() {
+		It("should be empty without a restore source", func() {
+			Expect(ETCDRestoreID(nil)).To(BeEmpty())
+		})
+
+		It("should be stable for the same source and differ between sources", func() {
+			source := &v1alpha1.ETCDRestoreSource{
+				PersistentVolumeClaim: &v1alpha1.ETCDRestorePersistentVolumeClaimSource{ClaimName: "backups", Path: "a.db"},
+			}
+			other := &v1alpha1.ETCDRestoreSource{
+				PersistentVolumeClaim: &v1alpha1.ETCDRestorePersistentVolumeClaimSource{ClaimName: "backups", Path: "b.db"},
+			}
+
+			Expect(ETCDRestoreID(source)).To(Equal(ETCDRestoreID(source.DeepCopy())))
+			Expect(ETCDRestoreID(source)).NotTo(Equal(ETCDRestoreID(other)))
+		})
+	})
+
+	Describe("#validateETCDStorageUnchanged", func() {
+		var (
+			names    Names
+			existing *appsv1.StatefulSet
+		)
+		BeforeEach(func() {
+			names = Names{Cluster: "bar"}
+			_, volumeClaimTemplates := etcdVolumes(names, &v1alpha1.ETCDStorage{})
+			existing = &appsv1.StatefulSet{Spec: appsv1.StatefulSetSpec{VolumeClaimTemplates: volumeClaimTemplates}}
+		})
+
+		It("should accept the storage the StatefulSet was created with", func() {
+			size := resource.MustParse(DefaultETCDStorageSize)
+
+			Expect(validateETCDStorageUnchanged(existing, names, &v1alpha1.ETCDStorage{})).To(Succeed())
+			Expect(validateETCDStorageUnchanged(existing, names, &v1alpha1.ETCDStorage{Size: &size})).To(Succeed())
+		})
+
+		It("should reject changes of the storage", func() {
+			size := resource.MustParse("5Gi")
+			storageClassName := "fast"
+
+			for _, storage := range []*v1alpha1.ETCDStorage{
+				{EmptyDir: true},
+				{Size: &size},
+				{StorageClassName: &storageClassName},
+			} {
+				Expect(validateETCDStorageUnchanged(existing, names, storage)).NotTo(Succeed())
+			}
+		})
+
+		It("should reject persistent volumes for emptyDir members", func() {
+			existing.Spec.VolumeClaimTemplates = nil
+
+			Expect(validateETCDStorageUnchanged(existing, names, &v1alpha1.ETCDStorage{EmptyDir: true})).To(Succeed())
+			Expect(validateETCDStorageUnchanged(existing, names, &v1alpha1.ETCDStorage{})).NotTo(Succeed())
+		})
+	})
+
+	Describe("#reconcileETCD", func() {
+		var (
+			ctx     context.Context
+			a       *actuator
+			c       client.Client
+			cluster *clusterv1alpha1.Cluster
+			names   Names
+		)
+		BeforeEach(func() {
+			ctx = context.Background()
+			cluster = newFakeCluster()
+			names = ClusterNames(cluster)
+			a, c = newFakeActuator(cluster)
+		})
+
+		requeue := BeAssignableToTypeOf(&controllererror.RequeueAfterError{})
+
+		getStatefulSet := func() *appsv1.StatefulSet {
+			statefulSet := &appsv1.StatefulSet{}
+			Expect(c.Get(ctx, util.Key(cluster.Namespace, names.Scoped(ETCDStatefulSetName)), statefulSet)).To(Succeed())
+			return statefulSet
+		}
+
+		getMembers := func() string {
+			configMap := &corev1.ConfigMap{}
+			Expect(c.Get(ctx, util.Key(cluster.Namespace, names.Scoped(ETCDConfigMapName)), configMap)).To(Succeed())
+			return configMap.Data[ETCDMembersKey]
+		}
+
+		It("should read the desired number of members from the config map", func() {
+			Expect(a.reconcileETCD(ctx, cluster, &v1alpha1.ETCD{Replicas: pointers.Int32(3)}, nil)).To(Succeed())
+
+			Expect(getMembers()).To(Equal("3"))
+			Expect(getStatefulSet().Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name: "MEMBERS",
+				ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "foo-etcd"},
+					Key:                  ETCDMembersKey,
+					Optional:             pointers.Bool(true),
+				}},
+			}))
+		})
+
+		It("should lower the desired number of members before removing a member when scaling from two to one", func() {
+			Expect(a.reconcileETCD(ctx, cluster, &v1alpha1.ETCD{Replicas: pointers.Int32(2)}, nil)).To(Succeed())
+			Expect(getMembers()).To(Equal("2"))
+
+			By("removing the member from etcd while it is still running")
+			Expect(a.reconcileETCD(ctx, cluster, &v1alpha1.ETCD{Replicas: pointers.Int32(1)}, nil)).To(requeue)
+			Expect(getMembers()).To(Equal("1"))
+			Expect(getStatefulSet().Spec.Replicas).To(Equal(pointers.Int32(2)))
+
+			job := &batchv1.Job{}
+			Expect(c.Get(ctx, util.Key(cluster.Namespace, "foo-etcd-1-remove"), job)).To(Succeed())
+			Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name:  "PEER_URL",
+				Value: "https://foo-etcd-1.foo-etcd-peer.default.svc:2380",
+			}))
+
+			Expect(a.reconcileETCD(ctx, cluster, &v1alpha1.ETCD{Replicas: pointers.Int32(1)}, nil)).To(requeue)
+			Expect(getStatefulSet().Spec.Replicas).To(Equal(pointers.Int32(2)))
+
+			By("scaling the StatefulSet down once the member was removed")
+			job.Status.Succeeded = 1
+			Expect(c.Status().Update(ctx, job)).To(Succeed())
+			Expect(a.reconcileETCD(ctx, cluster, &v1alpha1.ETCD{Replicas: pointers.Int32(1)}, nil)).To(Succeed())
+			Expect(getMembers()).To(Equal("1"))
+			Expect(getStatefulSet().Spec.Replicas).To(Equal(pointers.Int32(1)))
+			err := c.Get(ctx, util.Key(cluster.Namespace, "foo-etcd-1-remove"), &batchv1.Job{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+		It("should recreate a StatefulSet created before etcd had a data volume with the configured storage", func() {
+			cluster.Annotations = map[string]string{LegacyNamesAnnotation: "true"}
+			names = ClusterNames(cluster)
+			labels := names.Labels(ETCDComponent)
+			legacy := &appsv1.StatefulSet{
+				ObjectMeta: util.ObjectMeta(cluster.Namespace, ETCDStatefulSetName),
+				Spec: appsv1.StatefulSetSpec{
+					Replicas: pointers.Int32(1),
+					Selector: &metav1.LabelSelector{MatchLabels: labels},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "etcd", Image: "quay.io/coreos/etcd:v3.3.12"}},
+						},
+					},
+				},
+			}
+			legacy.Labels = labels
+			Expect(c.Create(ctx, legacy)).To(Succeed())
+
+			By("deleting the StatefulSet while orphaning its pods")
+			Expect(a.reconcileETCD(ctx, cluster, &v1alpha1.ETCD{}, nil)).To(requeue)
+			err := c.Get(ctx, util.Key(cluster.Namespace, ETCDStatefulSetName), &appsv1.StatefulSet{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+			By("recreating it with volume claim templates")
+			Expect(a.reconcileETCD(ctx, cluster, &v1alpha1.ETCD{}, nil)).To(Succeed())
+			statefulSet := getStatefulSet()
+			Expect(statefulSet.Name).To(Equal(ETCDStatefulSetName))
+			Expect(statefulSet.Spec.VolumeClaimTemplates).To(HaveLen(1))
+			Expect(statefulSet.Spec.VolumeClaimTemplates[0].Name).To(Equal(ETCDVolumeName))
+
+			Expect(a.reconcileETCD(ctx, cluster, &v1alpha1.ETCD{}, nil)).To(Succeed())
+		})
+	})
+
+	Describe("#etcdScript", func() {
+		var dir string
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "etcd")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.MkdirAll(filepath.Join(dir, "bin"), 0755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(dir, "data", "member"), 0755)).To(Succeed())
+			// The fake etcdctl lists only the first member and records the members added.
+			Expect(ioutil.WriteFile(filepath.Join(dir, "bin", "etcdctl"), []byte(`#!/bin/sh
+case "$*" in
+*"member list"*) echo "1, started, foo-etcd-0, https://foo-etcd-0.foo-etcd-peer.default.svc:2380, https://foo-etcd-0.foo-etcd-peer.default.svc:2379" ;;
+*"member add"*) echo "$*" >> "`+dir+`/added"; echo 'ETCD_INITIAL_CLUSTER="foo-etcd-0=https://foo-etcd-0.foo-etcd-peer.default.svc:2380"' ;;
+esac
+`), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "bin", "etcd"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+		})
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		// startRemovedMember runs the script for the second member after it was removed from the cluster.
+		startRemovedMember := func(members string) error {
+			cmd := exec.Command("/bin/sh", "-ec", etcdScript, "etcd")
+			cmd.Env = []string{
+				"PATH=" + filepath.Join(dir, "bin") + ":/usr/bin:/bin",
+				"CLIENT_SERVICE=foo-etcd",
+				"CLIENT_PORT=2379",
+				"PKI_DIR=/etc/etcd/pki/server",
+				"POD_NAME=foo-etcd-1",
+				"POD_NAMESPACE=default",
+				"PEER_SERVICE=foo-etcd-peer",
+				"PEER_PORT=2380",
+				"DATA_DIR=" + filepath.Join(dir, "data"),
+				"MEMBERS=" + members,
+			}
+			return cmd.Run()
+		}
+
+		added := func() bool {
+			_, err := os.Stat(filepath.Join(dir, "added"))
+			return err == nil
+		}
+
+		It("should not add a member again that is scaled down", func() {
+			Expect(startRemovedMember("1")).NotTo(Succeed())
+			Expect(added()).To(BeFalse())
+			Expect(filepath.Join(dir, "data", "member")).NotTo(BeADirectory())
+		})
+
+		It("should add a member again that is still desired", func() {
+			Expect(startRemovedMember("2")).To(Succeed())
+			Expect(added()).To(BeTrue())
+		})
+	})
+})
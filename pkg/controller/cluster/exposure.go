@@ -0,0 +1,261 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/util"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllererror "sigs.k8s.io/cluster-api/pkg/controller/error"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ExternalKubeconfigSecretName is the base name of the secret with the administrative kubeconfig that
+	// connects to the API server from outside the hosting cluster.
+	ExternalKubeconfigSecretName = "external-kubeconfig"
+	// APIServerIngressName is the base name of the ingress of API servers exposed via ingress.
+	APIServerIngressName = APIServerServiceName
+
+	// IngressSSLPassthroughAnnotation is the annotation that makes the NGINX ingress controller pass TLS
+	// connections through to the backend instead of terminating them.
+	IngressSSLPassthroughAnnotation = "nginx.ingress.kubernetes.io/ssl-passthrough"
+
+	// ExposureRequeueAfter is the duration after which the external address of an API server whose address
+	// has not been assigned yet is checked again.
+	ExposureRequeueAfter = 10 * time.Second
+
+	// DefaultAPIServerExposureType is the exposure type of API servers that do not configure any.
+	DefaultAPIServerExposureType = v1alpha1.APIServerExposureNodePort
+)
+
+// apiServerExposureType returns the exposure type of the given exposure.
+func apiServerExposureType(exposure *v1alpha1.APIServerExposure) v1alpha1.APIServerExposureType {
+	if exposure == nil || exposure.Type == "" {
+		return DefaultAPIServerExposureType
+	}
+	return exposure.Type
+}
+
+// hasNodePort checks whether the API server service of the given exposure type allocates a node port.
+func hasNodePort(exposureType v1alpha1.APIServerExposureType) bool {
+	return exposureType == v1alpha1.APIServerExposureNodePort || exposureType == v1alpha1.APIServerExposureLoadBalancer
+}
+
+// validateExposure validates the given API server exposure.
+func validateExposure(exposure *v1alpha1.APIServerExposure) error {
+	if exposure == nil {
+		return nil
+	}
+
+	exposureType := apiServerExposureType(exposure)
+	switch exposureType {
+	case v1alpha1.APIServerExposureClusterIP, v1alpha1.APIServerExposureNodePort, v1alpha1.APIServerExposureLoadBalancer, v1alpha1.APIServerExposureIngress:
+	default:
+		return fmt.Errorf("unsupported exposure type %q", exposureType)
+	}
+
+	if exposure.NodePort != nil {
+		if !hasNodePort(exposureType) {
+			return fmt.Errorf("a node port cannot be set for exposure type %s", exposureType)
+		}
+		if nodePort := *exposure.NodePort; nodePort < 1 || nodePort > 65535 {
+			return fmt.Errorf("invalid node port %d", nodePort)
+		}
+	}
+
+	if exposureType == v1alpha1.APIServerExposureIngress {
+		if exposure.Host == "" || net.ParseIP(exposure.Host) != nil {
+			return fmt.Errorf("a host name is required for exposure type %s", exposureType)
+		}
+	} else if len(exposure.IngressAnnotations) > 0 {
+		return fmt.Errorf("ingress annotations cannot be set for exposure type %s", exposureType)
+	}
+	return nil
+}
+
+// setAPIServerServiceExposure sets the type and ports of the given API server service according to the given
+// exposure. A node port allocated by the hosting cluster is kept.
+func setAPIServerServiceExposure(service *corev1.Service, exposure *v1alpha1.APIServerExposure) {
+	exposureType := apiServerExposureType(exposure)
+
+	var nodePort int32
+	if hasNodePort(exposureType) {
+		if exposure != nil && exposure.NodePort != nil {
+			nodePort = *exposure.NodePort
+		} else {
+			for _, port := range service.Spec.Ports {
+				if port.Port == APIServerPort {
+					nodePort = port.NodePort
+				}
+			}
+		}
+	}
+
+	service.Spec.Type = corev1.ServiceType(exposureType)
+	if exposureType == v1alpha1.APIServerExposureIngress {
+		service.Spec.Type = corev1.ServiceTypeClusterIP
+	}
+	service.Spec.Ports = []corev1.ServicePort{
+		{
+			Port:       APIServerPort,
+			TargetPort: intstr.FromInt(APIServerPort),
+			NodePort:   nodePort,
+		},
+	}
+}
+
+// hasExternalAddress checks whether the API server of the given exposure is reached from outside the hosting
+// cluster. API servers exposed via ClusterIP are only reached from outside if a host is set.
+func hasExternalAddress(exposure *v1alpha1.APIServerExposure) bool {
+	return apiServerExposureType(exposure) != v1alpha1.APIServerExposureClusterIP || exposure.Host != ""
+}
+
+// loadBalancerAddresses returns the addresses assigned to the load balancer of the given service.
+func loadBalancerAddresses(service *corev1.Service) []string {
+	var addresses []string
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			addresses = append(addresses, ingress.IP)
+		}
+		if ingress.Hostname != "" {
+			addresses = append(addresses, ingress.Hostname)
+		}
+	}
+	return addresses
+}
+
+// exposureCertificateAddresses returns the DNS names and IP addresses the API server is reached at from outside
+// the hosting cluster, apart from the node addresses that are always part of its serving certificate.
+func exposureCertificateAddresses(service *corev1.Service, exposure *v1alpha1.APIServerExposure) ([]string, []net.IP) {
+	var addresses []string
+	if exposure != nil && exposure.Host != "" {
+		addresses = append(addresses, exposure.Host)
+	}
+	if apiServerExposureType(exposure) == v1alpha1.APIServerExposureLoadBalancer {
+		addresses = append(addresses, loadBalancerAddresses(service)...)
+	}
+
+	var (
+		dnsNames []string
+		ips      []net.IP
+	)
+	for _, address := range addresses {
+		if ip := net.ParseIP(address); ip != nil {
+			ips = append(ips, ip)
+		} else {
+			dnsNames = append(dnsNames, address)
+		}
+	}
+	return dnsNames, ips
+}
+
+// externalAPIServerURL returns the URL the API server with the given service is reached at from outside the
+// hosting cluster. If the address has not been assigned yet or there is none, false is returned.
+func externalAPIServerURL(service *corev1.Service, exposure *v1alpha1.APIServerExposure, nodeIPs []net.IP) (string, bool) {
+	var host string
+	if exposure != nil {
+		host = exposure.Host
+	}
+	port := int32(APIServerPort)
+
+	switch apiServerExposureType(exposure) {
+	case v1alpha1.APIServerExposureNodePort:
+		port = 0
+		for _, servicePort := range service.Spec.Ports {
+			if servicePort.Port == APIServerPort {
+				port = servicePort.NodePort
+			}
+		}
+		if host == "" && len(nodeIPs) > 0 {
+			host = nodeIPs[0].String()
+		}
+	case v1alpha1.APIServerExposureLoadBalancer:
+		if addresses := loadBalancerAddresses(service); host == "" && len(addresses) > 0 {
+			host = addresses[0]
+		}
+	}
+
+	if host == "" || port == 0 {
+		return "", false
+	}
+	return fmt.Sprintf("https://%s", net.JoinHostPort(host, strconv.Itoa(int(port)))), true
+}
+
+// reconcileAPIServerIngress reconciles the ingress of an API server exposed via ingress and deletes it otherwise.
+func (a *actuator) reconcileAPIServerIngress(ctx context.Context, cluster *clusterv1alpha1.Cluster, exposure *v1alpha1.APIServerExposure) error {
+	names := ClusterNames(cluster)
+	ingress := &extensionsv1beta1.Ingress{ObjectMeta: util.ObjectMeta(cluster.Namespace, names.Scoped(APIServerIngressName))}
+	if apiServerExposureType(exposure) != v1alpha1.APIServerExposureIngress {
+		return client.IgnoreNotFound(a.Client.Delete(ctx, ingress))
+	}
+
+	_, err := controllerruntime.CreateOrUpdate(ctx, a.Client, ingress, func() error {
+		util.SetMetaDataLabels(ingress, names.Labels(APIServerComponent))
+		for key, value := range exposure.IngressAnnotations {
+			util.SetMetaDataAnnotation(ingress, key, value)
+		}
+		util.SetMetaDataAnnotation(ingress, IngressSSLPassthroughAnnotation, "true")
+		ingress.Spec = extensionsv1beta1.IngressSpec{
+			Rules: []extensionsv1beta1.IngressRule{
+				{
+					Host: exposure.Host,
+					IngressRuleValue: extensionsv1beta1.IngressRuleValue{
+						HTTP: &extensionsv1beta1.HTTPIngressRuleValue{
+							Paths: []extensionsv1beta1.HTTPIngressPath{
+								{
+									Backend: extensionsv1beta1.IngressBackend{
+										ServiceName: names.Scoped(APIServerServiceName),
+										ServicePort: intstr.FromInt(APIServerPort),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+		return controllerruntime.SetControllerReference(cluster, ingress, a.Scheme)
+	})
+	return err
+}
+
+// reconcileExternalKubeconfigSecret reconciles the administrative kubeconfig that connects to the API server from
+// outside the hosting cluster and deletes it if the API server has no external address. If the external address
+// of the API server has not been assigned yet, a RequeueAfterError is returned.
+func (a *actuator) reconcileExternalKubeconfigSecret(ctx context.Context, cluster *clusterv1alpha1.Cluster, exposure *v1alpha1.APIServerExposure) error {
+	if !hasExternalAddress(exposure) {
+		secret := &corev1.Secret{ObjectMeta: util.ObjectMeta(cluster.Namespace, ClusterNames(cluster).Scoped(ExternalKubeconfigSecretName))}
+		return client.IgnoreNotFound(a.Client.Delete(ctx, secret))
+	}
+
+	service := &corev1.Service{}
+	exists, err := a.getObject(ctx, cluster.Namespace, ClusterNames(cluster).Scoped(APIServerServiceName), service)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &controllererror.RequeueAfterError{RequeueAfter: ExposureRequeueAfter}
+	}
+
+	var nodeIPs []net.IP
+	if apiServerExposureType(exposure) == v1alpha1.APIServerExposureNodePort {
+		if nodeIPs, err = a.nodeAddresses(ctx); err != nil {
+			return err
+		}
+	}
+
+	server, ok := externalAPIServerURL(service, exposure, nodeIPs)
+	if !ok {
+		return &controllererror.RequeueAfterError{RequeueAfter: ExposureRequeueAfter}
+	}
+	return a.reconcileKubeconfigSecretForServer(ctx, cluster, ExternalKubeconfigSecretName, AdminCertificateName, server)
+}
@@ -0,0 +1,152 @@
+package cluster
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/util"
+	"kubeception.cloud/kubeception/pkg/util/pointers"
+)
+
+var _ = Describe("Exposure", func() {
+	Describe("#validateExposure", func() {
+		It("should accept the default exposure", func() {
+			Expect(validateExposure(nil)).To(Succeed())
+			Expect(validateExposure(&v1alpha1.APIServerExposure{})).To(Succeed())
+		})
+
+		It("should reject unsupported types", func() {
+			Expect(validateExposure(&v1alpha1.APIServerExposure{Type: "ExternalName"})).NotTo(Succeed())
+		})
+
+		It("should reject node ports for types without node ports", func() {
+			Expect(validateExposure(&v1alpha1.APIServerExposure{
+				Type:     v1alpha1.APIServerExposureClusterIP,
+				NodePort: pointers.Int32(30443),
+			})).NotTo(Succeed())
+		})
+
+		It("should require a host name for the ingress type", func() {
+			Expect(validateExposure(&v1alpha1.APIServerExposure{Type: v1alpha1.APIServerExposureIngress})).NotTo(Succeed())
+			Expect(validateExposure(&v1alpha1.APIServerExposure{
+				Type: v1alpha1.APIServerExposureIngress,
+				Host: "10.0.0.1",
+			})).NotTo(Succeed())
+			Expect(validateExposure(&v1alpha1.APIServerExposure{
+				Type: v1alpha1.APIServerExposureIngress,
+				Host: "foo.example.com",
+			})).To(Succeed())
+		})
+	})
+
+	Describe("#setAPIServerServiceExposure", func() {
+		It("should keep a node port allocated by the hosting cluster", func() {
+			service := &corev1.Service{
+				Spec: corev1.ServiceSpec{
+					Type:  corev1.ServiceTypeNodePort,
+					Ports: []corev1.ServicePort{{Port: APIServerPort, NodePort: 31234}},
+				},
+			}
+
+			setAPIServerServiceExposure(service, nil)
+
+			Expect(service.Spec.Type).To(Equal(corev1.ServiceTypeNodePort))
+			Expect(service.Spec.Ports).To(HaveLen(1))
+			Expect(service.Spec.Ports[0].NodePort).To(Equal(int32(31234)))
+		})
+
+		It("should set a fixed node port", func() {
+			service := &corev1.Service{}
+
+			setAPIServerServiceExposure(service, &v1alpha1.APIServerExposure{NodePort: pointers.Int32(30443)})
+
+			Expect(service.Spec.Ports[0].NodePort).To(Equal(int32(30443)))
+		})
+
+		It("should drop the node port of ingress exposures", func() {
+			service := &corev1.Service{
+				Spec: corev1.ServiceSpec{
+					Type:  corev1.ServiceTypeNodePort,
+					Ports: []corev1.ServicePort{{Port: APIServerPort, NodePort: 31234}},
+				},
+			}
+
+			setAPIServerServiceExposure(service, &v1alpha1.APIServerExposure{
+				Type: v1alpha1.APIServerExposureIngress,
+				Host: "foo.example.com",
+			})
+
+			Expect(service.Spec.Type).To(Equal(corev1.ServiceTypeClusterIP))
+			Expect(service.Spec.Ports[0].NodePort).To(BeZero())
+		})
+	})
+
+	Describe("#hasExternalAddress", func() {
+		It("should require a host for cluster IP exposures", func() {
+			Expect(hasExternalAddress(nil)).To(BeTrue())
+			Expect(hasExternalAddress(&v1alpha1.APIServerExposure{Type: v1alpha1.APIServerExposureLoadBalancer})).To(BeTrue())
+			Expect(hasExternalAddress(&v1alpha1.APIServerExposure{Type: v1alpha1.APIServerExposureClusterIP})).To(BeFalse())
+			Expect(hasExternalAddress(&v1alpha1.APIServerExposure{
+				Type: v1alpha1.APIServerExposureClusterIP,
+				Host: "foo.example.com",
+			})).To(BeTrue())
+		})
+	})
+
+	Describe("#externalAPIServerURL", func() {
+		var service *corev1.Service
+
+		BeforeEach(func() {
+			service = &corev1.Service{
+				ObjectMeta: util.ObjectMeta("default", "foo-apiserver"),
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{{Port: APIServerPort, NodePort: 31234}},
+				},
+			}
+		})
+
+		It("should use a node address and the node port", func() {
+			url, ok := externalAPIServerURL(service, nil, []net.IP{net.ParseIP("172.17.0.2")})
+
+			Expect(ok).To(BeTrue())
+			Expect(url).To(Equal("https://172.17.0.2:31234"))
+		})
+
+		It("should use the address of the load balancer once assigned", func() {
+			exposure := &v1alpha1.APIServerExposure{Type: v1alpha1.APIServerExposureLoadBalancer}
+
+			_, ok := externalAPIServerURL(service, exposure, nil)
+			Expect(ok).To(BeFalse())
+
+			service.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{Hostname: "lb.example.com"}}
+			url, ok := externalAPIServerURL(service, exposure, nil)
+			Expect(ok).To(BeTrue())
+			Expect(url).To(Equal("https://lb.example.com:443"))
+		})
+
+		It("should use the host of the ingress", func() {
+			url, ok := externalAPIServerURL(service, &v1alpha1.APIServerExposure{
+				Type: v1alpha1.APIServerExposureIngress,
+				Host: "foo.example.com",
+			}, nil)
+
+			Expect(ok).To(BeTrue())
+			Expect(url).To(Equal("https://foo.example.com:443"))
+		})
+
+		It("should only use the host for cluster IP exposures", func() {
+			_, ok := externalAPIServerURL(service, &v1alpha1.APIServerExposure{Type: v1alpha1.APIServerExposureClusterIP}, nil)
+			Expect(ok).To(BeFalse())
+
+			url, ok := externalAPIServerURL(service, &v1alpha1.APIServerExposure{
+				Type: v1alpha1.APIServerExposureClusterIP,
+				Host: "foo.example.com",
+			}, nil)
+			Expect(ok).To(BeTrue())
+			Expect(url).To(Equal("https://foo.example.com:443"))
+		})
+	})
+})
@@ -0,0 +1,64 @@
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/util/flags"
+	"kubeception.cloud/kubeception/pkg/util/pointers"
+)
+
+// validateLeaderElection validates the leader election of a component with the given number of replicas.
+func validateLeaderElection(replicas int32, leaderElection *v1alpha1.LeaderElection) error {
+	if replicas < 0 {
+		return fmt.Errorf("invalid negative number of replicas %d", replicas)
+	}
+	if leaderElection == nil {
+		return nil
+	}
+
+	if replicas > 1 && !pointers.DerefBoolOrDefault(leaderElection.LeaderElect, true) {
+		return fmt.Errorf("leader election is required for %d replicas", replicas)
+	}
+
+	for _, field := range []struct {
+		name     string
+		duration *metav1.Duration
+	}{
+		{"lease duration", leaderElection.LeaseDuration},
+		{"renew deadline", leaderElection.RenewDeadline},
+		{"retry period", leaderElection.RetryPeriod},
+	} {
+		if field.duration != nil && field.duration.Duration <= 0 {
+			return fmt.Errorf("invalid %s %s, must be positive", field.name, field.duration.Duration)
+		}
+	}
+
+	if leaderElection.LeaseDuration != nil && leaderElection.RenewDeadline != nil &&
+		leaderElection.RenewDeadline.Duration >= leaderElection.LeaseDuration.Duration {
+		return fmt.Errorf("renew deadline %s must be less than lease duration %s",
+			leaderElection.RenewDeadline.Duration, leaderElection.LeaseDuration.Duration)
+	}
+	return nil
+}
+
+// setLeaderElectionFlags sets the leader election flags of a control plane component. Leader election is
+// enabled unless it is disabled explicitly.
+func setLeaderElectionFlags(f *flags.Flags, leaderElection *v1alpha1.LeaderElection) {
+	if leaderElection == nil {
+		leaderElection = &v1alpha1.LeaderElection{}
+	}
+
+	f.Set("leader-elect", strconv.FormatBool(pointers.DerefBoolOrDefault(leaderElection.LeaderElect, true)))
+	if leaderElection.LeaseDuration != nil {
+		f.Set("leader-elect-lease-duration", leaderElection.LeaseDuration.Duration.String())
+	}
+	if leaderElection.RenewDeadline != nil {
+		f.Set("leader-elect-renew-deadline", leaderElection.RenewDeadline.Duration.String())
+	}
+	if leaderElection.RetryPeriod != nil {
+		f.Set("leader-elect-retry-period", leaderElection.RetryPeriod.Duration.String())
+	}
+}
@@ -0,0 +1,114 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"kubeception.cloud/kubeception/pkg/util"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Names derives the names and labels of the objects generated for a cluster, so that multiple clusters can
+// live in the same namespace.
+//
+// Clusters created before names were scoped by cluster are marked with the LegacyNamesAnnotation. Their objects
+// keep the unscoped base names and are labelled by component only, as workload selectors cannot be updated.
+// Such a cluster has to be the only cluster in its namespace.
+type Names struct {
+	// Cluster is the name of the cluster.
+	Cluster string
+	// Legacy indicates that the objects of the cluster use the unscoped base names.
+	Legacy bool
+}
+
+// ClusterNames returns the Names of the given cluster.
+func ClusterNames(cluster *clusterv1alpha1.Cluster) Names {
+	return Names{
+		Cluster: cluster.Name,
+		Legacy:  cluster.Annotations[LegacyNamesAnnotation] == "true",
+	}
+}
+
+// Scoped returns the name of the object with the given base name.
+func (n Names) Scoped(baseName string) string {
+	if n.Legacy {
+		return baseName
+	}
+	return fmt.Sprintf("%s-%s", n.Cluster, baseName)
+}
+
+// Labels returns the labels of the objects and pods of the given control plane component. The workloads and
+// services of the component select its pods by these labels.
+func (n Names) Labels(component string) map[string]string {
+	if n.Legacy {
+		return map[string]string{
+			ControlPlaneComponentLabel: component,
+		}
+	}
+	return map[string]string{
+		ControlPlaneComponentLabel: component,
+		ClusterNameLabel:           n.Cluster,
+	}
+}
+
+// hasLegacyObjects checks whether the given cluster controls objects with unscoped names.
+func (a *actuator) hasLegacyObjects(ctx context.Context, cluster *clusterv1alpha1.Cluster) (bool, error) {
+	for _, obj := range []interface {
+		runtime.Object
+		metav1.Object
+	}{
+		&appsv1.StatefulSet{ObjectMeta: util.ObjectMeta(cluster.Namespace, ETCDStatefulSetName)},
+		&appsv1.Deployment{ObjectMeta: util.ObjectMeta(cluster.Namespace, APIServerDeploymentName)},
+	} {
+		if err := a.Client.Get(ctx, util.Key(obj.GetNamespace(), obj.GetName()), obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return false, err
+		}
+
+		if metav1.IsControlledBy(obj, cluster) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// reconcileNames records on the cluster whether its objects use unscoped names. Clusters whose objects have been
+// created with unscoped names keep them. A cluster with scoped names cannot share its namespace with a cluster
+// with unscoped names, as the selectors of the latter would select the pods of both clusters.
+func (a *actuator) reconcileNames(ctx context.Context, cluster *clusterv1alpha1.Cluster) error {
+	if _, ok := cluster.Annotations[LegacyNamesAnnotation]; !ok {
+		legacy, err := a.hasLegacyObjects(ctx, cluster)
+		if err != nil {
+			return err
+		}
+
+		withoutAnnotation := cluster.DeepCopy()
+		util.SetMetaDataAnnotation(cluster, LegacyNamesAnnotation, strconv.FormatBool(legacy))
+		if err := a.Client.Patch(ctx, cluster, client.MergeFrom(withoutAnnotation)); err != nil {
+			return err
+		}
+	}
+
+	if ClusterNames(cluster).Legacy {
+		return nil
+	}
+
+	clusterList := &clusterv1alpha1.ClusterList{}
+	if err := a.Client.List(ctx, clusterList, client.InNamespace(cluster.Namespace)); err != nil {
+		return err
+	}
+	for _, other := range clusterList.Items {
+		if ClusterNames(&other).Legacy {
+			return fmt.Errorf("cluster %s in namespace %s uses unscoped names, migrate it to another namespace first", other.Name, cluster.Namespace)
+		}
+	}
+	return nil
+}
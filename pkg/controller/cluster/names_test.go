@@ -0,0 +1,48 @@
+package cluster
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+var _ = Describe("Names", func() {
+	Describe("#ClusterNames", func() {
+		It("should only be legacy if the cluster is annotated as such", func() {
+			cluster := &clusterv1alpha1.Cluster{}
+			cluster.Name = "foo"
+			Expect(ClusterNames(cluster)).To(Equal(Names{Cluster: "foo"}))
+
+			cluster.Annotations = map[string]string{LegacyNamesAnnotation: "false"}
+			Expect(ClusterNames(cluster)).To(Equal(Names{Cluster: "foo"}))
+
+			cluster.Annotations[LegacyNamesAnnotation] = "true"
+			Expect(ClusterNames(cluster)).To(Equal(Names{Cluster: "foo", Legacy: true}))
+		})
+	})
+
+	Describe("#Scoped", func() {
+		It("should prefix the base name with the cluster name", func() {
+			Expect(Names{Cluster: "foo"}.Scoped(KubeconfigSecretName)).To(Equal("foo-kubeconfig"))
+		})
+
+		It("should keep the base name of legacy clusters", func() {
+			Expect(Names{Cluster: "foo", Legacy: true}.Scoped(KubeconfigSecretName)).To(Equal("kubeconfig"))
+		})
+	})
+
+	Describe("#Labels", func() {
+		It("should label by component and cluster", func() {
+			Expect(Names{Cluster: "foo"}.Labels(ETCDComponent)).To(Equal(map[string]string{
+				ControlPlaneComponentLabel: ETCDComponent,
+				ClusterNameLabel:           "foo",
+			}))
+		})
+
+		It("should label legacy clusters by component only", func() {
+			Expect(Names{Cluster: "foo", Legacy: true}.Labels(ETCDComponent)).To(Equal(map[string]string{
+				ControlPlaneComponentLabel: ETCDComponent,
+			}))
+		})
+	})
+})
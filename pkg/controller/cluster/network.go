@@ -0,0 +1,269 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/controller/common"
+	"kubeception.cloud/kubeception/pkg/util"
+	"kubeception.cloud/kubeception/pkg/util/pointers"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// CNIConfDir is the directory of the CNI configuration on the machines.
+	CNIConfDir = "/etc/cni/net.d"
+	// CNIBinDir is the directory of the CNI plugins on the machines.
+	CNIBinDir = "/opt/cni/bin"
+
+	// DefaultFlannelBackend is the backend of flannel networks that do not configure any.
+	DefaultFlannelBackend = v1alpha1.FlannelVXLAN
+
+	// The flannel objects are created in the kube-system namespace of the hosted cluster under the names of the
+	// upstream manifest.
+	flannelNamespace     = metav1.NamespaceSystem
+	flannelName          = "flannel"
+	flannelConfigMapName = "kube-flannel-cfg"
+	flannelDaemonSetName = "kube-flannel-ds"
+	flannelConfigDir     = "/etc/kube-flannel"
+	flannelCNIConfKey    = "cni-conf.json"
+	flannelNetConfKey    = "net-conf.json"
+	flannelCNIConfFile   = "10-flannel.conflist"
+)
+
+var (
+	flannelLabels = map[string]string{"app": "flannel"}
+)
+
+// flannelCNIConf is the CNI configuration that delegates to the bridge plugin with the subnet flannel leased
+// for the node.
+const flannelCNIConf = `{
+  "name": "cbr0",
+  "plugins": [
+    {
+      "type": "flannel",
+      "delegate": {
+        "hairpinMode": true,
+        "isDefaultGateway": true
+      }
+    },
+    {
+      "type": "portmap",
+      "capabilities": {
+        "portMappings": true
+      }
+    }
+  ]
+}
+`
+
+// validateNetwork validates the given network configuration of the given cluster configuration.
+func validateNetwork(config *v1alpha1.ClusterConfig, network *v1alpha1.Network) error {
+	if config.ControlPlane.ControllerManager == nil {
+		return fmt.Errorf("a controller manager is required to allocate the pod CIDRs of the nodes")
+	}
+
+	switch network.Type {
+	case v1alpha1.NetworkFlannel:
+	default:
+		return fmt.Errorf("unsupported network type %q", network.Type)
+	}
+
+	if network.Flannel != nil {
+		switch network.Flannel.Backend {
+		case "", v1alpha1.FlannelVXLAN, v1alpha1.FlannelHostGW:
+		default:
+			return fmt.Errorf("unsupported flannel backend %q", network.Flannel.Backend)
+		}
+	}
+	return nil
+}
+
+// flannelNetConf renders the flannel network configuration for the given pod CIDR and flannel configuration.
+func flannelNetConf(podCIDR string, flannel *v1alpha1.FlannelNetwork) (string, error) {
+	backend := DefaultFlannelBackend
+	if flannel != nil && flannel.Backend != "" {
+		backend = flannel.Backend
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"Network": podCIDR,
+		"Backend": map[string]interface{}{"Type": backend},
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// reconcileNetwork applies the pod network with the given configuration to the hosted cluster with the given
+// client.
+func reconcileNetwork(ctx context.Context, guest client.Client, cluster *clusterv1alpha1.Cluster, config *v1alpha1.ClusterConfig, network *v1alpha1.Network) error {
+	if err := validateNetwork(config, network); err != nil {
+		return fmt.Errorf("invalid network configuration: %v", err)
+	}
+	return reconcileFlannel(ctx, guest, cluster, network.Flannel)
+}
+
+// reconcileFlannel applies flannel with the given configuration to the hosted cluster with the given client.
+func reconcileFlannel(ctx context.Context, guest client.Client, cluster *clusterv1alpha1.Cluster, flannel *v1alpha1.FlannelNetwork) error {
+	netConf, err := flannelNetConf(cluster.Spec.ClusterNetwork.Pods.CIDRBlocks[0], flannel)
+	if err != nil {
+		return err
+	}
+
+	var image *v1alpha1.Image
+	if flannel != nil {
+		image = flannel.Image
+	}
+
+	serviceAccount := &corev1.ServiceAccount{ObjectMeta: util.ObjectMeta(flannelNamespace, flannelName)}
+	if _, err := controllerruntime.CreateOrUpdate(ctx, guest, serviceAccount, func() error {
+		util.SetMetaDataLabels(serviceAccount, flannelLabels)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	clusterRole := &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: flannelName}}
+	if _, err := controllerruntime.CreateOrUpdate(ctx, guest, clusterRole, func() error {
+		util.SetMetaDataLabels(clusterRole, flannelLabels)
+		clusterRole.Rules = []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{""},
+				Resources: []string{"pods"},
+				Verbs:     []string{"get"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"nodes"},
+				Verbs:     []string{"list", "watch"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"nodes/status"},
+				Verbs:     []string{"patch"},
+			},
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	clusterRoleBinding := &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: flannelName}}
+	if _, err := controllerruntime.CreateOrUpdate(ctx, guest, clusterRoleBinding, func() error {
+		util.SetMetaDataLabels(clusterRoleBinding, flannelLabels)
+		clusterRoleBinding.RoleRef = rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     flannelName,
+		}
+		clusterRoleBinding.Subjects = []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Namespace: flannelNamespace,
+				Name:      flannelName,
+			},
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	configMap := &corev1.ConfigMap{ObjectMeta: util.ObjectMeta(flannelNamespace, flannelConfigMapName)}
+	if _, err := controllerruntime.CreateOrUpdate(ctx, guest, configMap, func() error {
+		util.SetMetaDataLabels(configMap, flannelLabels)
+		configMap.Data = map[string]string{
+			flannelCNIConfKey: flannelCNIConf,
+			flannelNetConfKey: netConf,
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	daemonSet := &appsv1.DaemonSet{ObjectMeta: util.ObjectMeta(flannelNamespace, flannelDaemonSetName)}
+	_, err = controllerruntime.CreateOrUpdate(ctx, guest, daemonSet, func() error {
+		util.SetMetaDataLabels(daemonSet, flannelLabels)
+		daemonSet.Spec = appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: flannelLabels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: flannelLabels,
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: flannelName,
+					PriorityClassName:  "system-node-critical",
+					HostNetwork:        true,
+					Tolerations: []corev1.Toleration{
+						{
+							Operator: corev1.TolerationOpExists,
+						},
+					},
+					InitContainers: []corev1.Container{
+						{
+							Name:    "install-cni",
+							Image:   common.FlannelImage(image),
+							Command: []string{"cp", "-f", fmt.Sprintf("%s/%s", flannelConfigDir, flannelCNIConfKey), fmt.Sprintf("%s/%s", CNIConfDir, flannelCNIConfFile)},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "cni", MountPath: CNIConfDir},
+								{Name: "config", MountPath: flannelConfigDir},
+							},
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:    "kube-flannel",
+							Image:   common.FlannelImage(image),
+							Command: []string{"/opt/bin/flanneld", "--ip-masq", "--kube-subnet-mgr"},
+							Env: []corev1.EnvVar{
+								{Name: "POD_NAME", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}}},
+								{Name: "POD_NAMESPACE", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"}}},
+							},
+							SecurityContext: &corev1.SecurityContext{
+								Privileged: pointers.Bool(false),
+								Capabilities: &corev1.Capabilities{
+									Add: []corev1.Capability{"NET_ADMIN"},
+								},
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "run", MountPath: "/run/flannel"},
+								{Name: "config", MountPath: flannelConfigDir},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name:         "run",
+							VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/run/flannel"}},
+						},
+						{
+							Name:         "cni",
+							VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: CNIConfDir}},
+						},
+						{
+							Name: "config",
+							VolumeSource: corev1.VolumeSource{
+								ConfigMap: &corev1.ConfigMapVolumeSource{
+									LocalObjectReference: corev1.LocalObjectReference{Name: flannelConfigMapName},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+		return nil
+	})
+	return err
+}
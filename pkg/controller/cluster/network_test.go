@@ -0,0 +1,56 @@
+package cluster
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+)
+
+var _ = Describe("Network", func() {
+	Describe("#validateNetwork", func() {
+		var config *v1alpha1.ClusterConfig
+		BeforeEach(func() {
+			config = &v1alpha1.ClusterConfig{
+				ControlPlane: v1alpha1.ControlPlane{ControllerManager: &v1alpha1.ControllerManager{}},
+			}
+		})
+
+		It("should accept flannel networks", func() {
+			Expect(validateNetwork(config, &v1alpha1.Network{Type: v1alpha1.NetworkFlannel})).To(Succeed())
+			Expect(validateNetwork(config, &v1alpha1.Network{
+				Type:    v1alpha1.NetworkFlannel,
+				Flannel: &v1alpha1.FlannelNetwork{Backend: v1alpha1.FlannelHostGW},
+			})).To(Succeed())
+		})
+
+		It("should require a controller manager", func() {
+			config.ControlPlane.ControllerManager = nil
+
+			Expect(validateNetwork(config, &v1alpha1.Network{Type: v1alpha1.NetworkFlannel})).NotTo(Succeed())
+		})
+
+		It("should reject unsupported types and backends", func() {
+			Expect(validateNetwork(config, &v1alpha1.Network{Type: "Calico"})).NotTo(Succeed())
+			Expect(validateNetwork(config, &v1alpha1.Network{
+				Type:    v1alpha1.NetworkFlannel,
+				Flannel: &v1alpha1.FlannelNetwork{Backend: "udp"},
+			})).NotTo(Succeed())
+		})
+	})
+
+	Describe("#flannelNetConf", func() {
+		It("should default to the vxlan backend", func() {
+			netConf, err := flannelNetConf("192.169.0.0/16", nil)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netConf).To(MatchJSON(`{"Network": "192.169.0.0/16", "Backend": {"Type": "vxlan"}}`))
+		})
+
+		It("should use the configured backend", func() {
+			netConf, err := flannelNetConf("192.169.0.0/16", &v1alpha1.FlannelNetwork{Backend: v1alpha1.FlannelHostGW})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netConf).To(MatchJSON(`{"Network": "192.169.0.0/16", "Backend": {"Type": "host-gw"}}`))
+		})
+	})
+})
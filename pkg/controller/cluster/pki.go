@@ -0,0 +1,373 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"kubeception.cloud/kubeception/pkg/apis/certificate/v1alpha1"
+	kubeceptionv1alpha1 "kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/apitypes"
+	"kubeception.cloud/kubeception/pkg/util"
+	"kubeception.cloud/kubeception/pkg/util/cidr"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllererror "sigs.k8s.io/cluster-api/pkg/controller/error"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+)
+
+// The certificate names are base names that are scoped by the cluster, see Names.Scoped.
+const (
+	CACertificateName         = "ca"
+	APIServerCertificateName  = "apiserver"
+	ETCDServerCertificateName = "etcd-server"
+	ETCDPeerCertificateName   = "etcd-peer"
+	ETCDClientCertificateName = "etcd-client"
+
+	AdminCertificateName             = "admin"
+	ControllerManagerCertificateName = "controller-manager"
+	SchedulerCertificateName         = "scheduler"
+
+	// ServiceAccountKeyName is the base name of the key pair service account tokens are signed with.
+	ServiceAccountKeyName = "service-account"
+
+	// CAFile is the file name of the CA certificate in a certificate volume.
+	CAFile = "ca.crt"
+	// CertFile is the file name of the certificate in a certificate volume.
+	CertFile = "tls.crt"
+	// KeyFile is the file name of the private key in a certificate volume.
+	KeyFile = "tls.key"
+	// ServiceAccountKeyFile is the file name of the service account signing key in a service account key volume.
+	ServiceAccountKeyFile = "sa.key"
+	// CAKeyFile is the file name of the private key of the CA in a cluster signing volume.
+	CAKeyFile = "ca.key"
+
+	// CertificateRequeueAfter is the duration after which a reconciliation is retried if a
+	// certificate has not yet been issued.
+	CertificateRequeueAfter = 5 * time.Second
+)
+
+// KeyPairName returns the name of the key pair backing the certificate with the given name.
+func KeyPairName(certificateName string) string {
+	return fmt.Sprintf("%s-key", certificateName)
+}
+
+type certificateConfig struct {
+	Name        string
+	Type        v1alpha1.Type
+	Parent      string
+	Subject     v1alpha1.CertificateSubject
+	DNSNames    []string
+	IPAddresses []net.IP
+}
+
+func toAPIIPs(ips []net.IP) []apitypes.IP {
+	var out []apitypes.IP
+	for _, ip := range ips {
+		out = append(out, apitypes.IP{IP: ip})
+	}
+	return out
+}
+
+// reconcileCertificate reconciles the certificate and its key pair described by the given config.
+// The name and parent of the config are base names that are scoped by the cluster.
+// Only the fields of the certificate spec that are described by the config are touched, the remaining
+// fields (serial number, validity) are left to the certificate controller.
+func (a *actuator) reconcileCertificate(ctx context.Context, cluster *clusterv1alpha1.Cluster, config *certificateConfig) error {
+	names := ClusterNames(cluster)
+	keyPairName := KeyPairName(names.Scoped(config.Name))
+	if err := a.reconcileKeyPair(ctx, cluster, keyPairName); err != nil {
+		return err
+	}
+
+	cert := &v1alpha1.Certificate{ObjectMeta: util.ObjectMeta(cluster.Namespace, names.Scoped(config.Name))}
+	_, err := controllerruntime.CreateOrUpdate(ctx, a.Client, cert, func() error {
+		cert.Spec.Type = config.Type
+		cert.Spec.KeyPair = &corev1.LocalObjectReference{Name: keyPairName}
+		if config.Parent != "" {
+			cert.Spec.Parent = &corev1.LocalObjectReference{Name: names.Scoped(config.Parent)}
+		} else {
+			cert.Spec.Parent = nil
+		}
+		cert.Spec.Info.Subject = config.Subject
+		cert.Spec.Info.DNSNames = config.DNSNames
+		cert.Spec.Info.IPAddresses = toAPIIPs(config.IPAddresses)
+		return controllerruntime.SetControllerReference(cluster, cert, a.Scheme)
+	})
+	return err
+}
+
+// reconcileKeyPair reconciles the key pair with the given name. Its secret of the same name is provisioned by
+// the key pair controller.
+func (a *actuator) reconcileKeyPair(ctx context.Context, cluster *clusterv1alpha1.Cluster, name string) error {
+	keyPair := &v1alpha1.KeyPair{ObjectMeta: util.ObjectMeta(cluster.Namespace, name)}
+	_, err := controllerruntime.CreateOrUpdate(ctx, a.Client, keyPair, func() error {
+		return controllerruntime.SetControllerReference(cluster, keyPair, a.Scheme)
+	})
+	return err
+}
+
+// reconcileServiceAccountKeyPair reconciles the key pair the controller manager signs service account tokens with
+// and the API server verifies them with.
+func (a *actuator) reconcileServiceAccountKeyPair(ctx context.Context, cluster *clusterv1alpha1.Cluster) error {
+	return a.reconcileKeyPair(ctx, cluster, KeyPairName(ClusterNames(cluster).Scoped(ServiceAccountKeyName)))
+}
+
+func (a *actuator) reconcileCA(ctx context.Context, cluster *clusterv1alpha1.Cluster) error {
+	return a.reconcileCertificate(ctx, cluster, &certificateConfig{
+		Name:    CACertificateName,
+		Type:    v1alpha1.CACert,
+		Subject: v1alpha1.CertificateSubject{CommonName: fmt.Sprintf("%s-ca", cluster.Name)},
+	})
+}
+
+func serviceDNSNames(namespace, name string) []string {
+	return []string{
+		name,
+		fmt.Sprintf("%s.%s", name, namespace),
+		fmt.Sprintf("%s.%s.svc", name, namespace),
+	}
+}
+
+var localhostIPs = []net.IP{net.ParseIP("127.0.0.1")}
+
+func (a *actuator) reconcileETCDCertificates(ctx context.Context, cluster *clusterv1alpha1.Cluster) error {
+	names := ClusterNames(cluster)
+	memberDNSName := fmt.Sprintf("*.%s.%s.svc", names.Scoped(ETCDPeerServiceName), cluster.Namespace)
+	for _, config := range []*certificateConfig{
+		{
+			Name:        ETCDServerCertificateName,
+			Type:        v1alpha1.ServerClientCert,
+			Parent:      CACertificateName,
+			Subject:     v1alpha1.CertificateSubject{CommonName: ETCDServerCertificateName},
+			DNSNames:    append(serviceDNSNames(cluster.Namespace, names.Scoped(ETCDServiceName)), memberDNSName, "localhost"),
+			IPAddresses: localhostIPs,
+		},
+		{
+			Name:        ETCDPeerCertificateName,
+			Type:        v1alpha1.ServerClientCert,
+			Parent:      CACertificateName,
+			Subject:     v1alpha1.CertificateSubject{CommonName: ETCDPeerCertificateName},
+			DNSNames:    []string{memberDNSName, "localhost"},
+			IPAddresses: localhostIPs,
+		},
+		{
+			Name:    ETCDClientCertificateName,
+			Type:    v1alpha1.ClientCert,
+			Parent:  CACertificateName,
+			Subject: v1alpha1.CertificateSubject{CommonName: "kube-apiserver-etcd-client"},
+		},
+	} {
+		if err := a.reconcileCertificate(ctx, cluster, config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nodeAddresses returns the sorted internal and external addresses of all nodes of the hosting cluster.
+func (a *actuator) nodeAddresses(ctx context.Context) ([]net.IP, error) {
+	nodeList := &corev1.NodeList{}
+	if err := a.Client.List(ctx, nodeList); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{})
+	var ips []net.IP
+	for _, node := range nodeList.Items {
+		for _, address := range node.Status.Addresses {
+			if address.Type != corev1.NodeInternalIP && address.Type != corev1.NodeExternalIP {
+				continue
+			}
+
+			ip := net.ParseIP(address.Address)
+			if ip == nil {
+				continue
+			}
+
+			if _, ok := seen[ip.String()]; ok {
+				continue
+			}
+			seen[ip.String()] = struct{}{}
+			ips = append(ips, ip)
+		}
+	}
+
+	sort.Slice(ips, func(i, j int) bool { return ips[i].String() < ips[j].String() })
+	return ips, nil
+}
+
+// reconcileAPIServerCertificate reconciles the serving certificate of the API server. Its SANs cover the
+// API server service (name, cluster IP and the node addresses for node port access) as well as the
+// in-cluster `kubernetes` service of the hosted cluster.
+func (a *actuator) reconcileAPIServerCertificate(ctx context.Context, cluster *clusterv1alpha1.Cluster, service *corev1.Service, exposure *kubeceptionv1alpha1.APIServerExposure) error {
+	dnsNames := append(serviceDNSNames(cluster.Namespace, service.Name),
+		"localhost",
+		"kubernetes",
+		"kubernetes.default",
+		"kubernetes.default.svc",
+	)
+	if domain := cluster.Spec.ClusterNetwork.ServiceDomain; domain != "" {
+		dnsNames = append(dnsNames, fmt.Sprintf("kubernetes.default.svc.%s", domain))
+	}
+
+	ips := append([]net.IP{}, localhostIPs...)
+	if clusterIP := net.ParseIP(service.Spec.ClusterIP); clusterIP != nil {
+		ips = append(ips, clusterIP)
+	}
+
+	if blocks := cluster.Spec.ClusterNetwork.Services.CIDRBlocks; len(blocks) > 0 {
+		kubernetesServiceIP, err := cidr.NthIP(blocks[0], 1)
+		if err != nil {
+			return err
+		}
+		ips = append(ips, kubernetesServiceIP)
+	}
+
+	nodeIPs, err := a.nodeAddresses(ctx)
+	if err != nil {
+		return err
+	}
+	ips = append(ips, nodeIPs...)
+
+	exposureDNSNames, exposureIPs := exposureCertificateAddresses(service, exposure)
+	dnsNames = append(dnsNames, exposureDNSNames...)
+	ips = append(ips, exposureIPs...)
+
+	return a.reconcileCertificate(ctx, cluster, &certificateConfig{
+		Name:        APIServerCertificateName,
+		Type:        v1alpha1.ServerCert,
+		Parent:      CACertificateName,
+		Subject:     v1alpha1.CertificateSubject{CommonName: "kube-apiserver"},
+		DNSNames:    dnsNames,
+		IPAddresses: ips,
+	})
+}
+
+// readSecretData reads the data at the given key of the secret with the given name.
+// If the secret or the data is not yet present, a RequeueAfterError is returned.
+func (a *actuator) readSecretData(ctx context.Context, namespace, name, key string) ([]byte, error) {
+	secret := &corev1.Secret{}
+	if err := a.Client.Get(ctx, util.Key(namespace, name), secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, &controllererror.RequeueAfterError{RequeueAfter: CertificateRequeueAfter}
+		}
+		return nil, err
+	}
+
+	data, ok := secret.Data[key]
+	if !ok || len(data) == 0 {
+		return nil, &controllererror.RequeueAfterError{RequeueAfter: CertificateRequeueAfter}
+	}
+	return data, nil
+}
+
+// readCertificatePEM reads the PEM encoded certificate issued for the certificate with the given name.
+// If the certificate has not been issued yet, a RequeueAfterError is returned.
+func (a *actuator) readCertificatePEM(ctx context.Context, namespace, name string) ([]byte, error) {
+	return a.readSecretData(ctx, namespace, name, v1alpha1.CertificatePEMDataKey)
+}
+
+// readPrivateKeyPEM reads the PEM encoded private key of the certificate with the given name.
+// If the key has not been generated yet, a RequeueAfterError is returned.
+func (a *actuator) readPrivateKeyPEM(ctx context.Context, namespace, certificateName string) ([]byte, error) {
+	return a.readSecretData(ctx, namespace, KeyPairName(certificateName), v1alpha1.PrivateKeyDataKey)
+}
+
+// reconcileClientCertificate reconciles a client certificate for the given user and groups.
+func (a *actuator) reconcileClientCertificate(ctx context.Context, cluster *clusterv1alpha1.Cluster, name, user string, groups ...string) error {
+	return a.reconcileCertificate(ctx, cluster, &certificateConfig{
+		Name:    name,
+		Type:    v1alpha1.ClientCert,
+		Parent:  CACertificateName,
+		Subject: v1alpha1.CertificateSubject{CommonName: user, Organization: groups},
+	})
+}
+
+// CertificateVolume returns a volume that contains the certificate with the given name at CertFile, its
+// private key at KeyFile and the certificate of the given CA at CAFile.
+func CertificateVolume(volumeName, certificateName, caName string) corev1.Volume {
+	return corev1.Volume{
+		Name: volumeName,
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{
+					{
+						Secret: &corev1.SecretProjection{
+							LocalObjectReference: corev1.LocalObjectReference{Name: certificateName},
+							Items:                []corev1.KeyToPath{{Key: v1alpha1.CertificatePEMDataKey, Path: CertFile}},
+						},
+					},
+					{
+						Secret: &corev1.SecretProjection{
+							LocalObjectReference: corev1.LocalObjectReference{Name: KeyPairName(certificateName)},
+							Items:                []corev1.KeyToPath{{Key: v1alpha1.PrivateKeyDataKey, Path: KeyFile}},
+						},
+					},
+					{
+						Secret: &corev1.SecretProjection{
+							LocalObjectReference: corev1.LocalObjectReference{Name: caName},
+							Items:                []corev1.KeyToPath{{Key: v1alpha1.CertificatePEMDataKey, Path: CAFile}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// ServiceAccountKeyVolume returns a volume that contains the private key of the service account key pair of the
+// cluster with the given names at ServiceAccountKeyFile and the certificate of the CA of the cluster at CAFile.
+func ServiceAccountKeyVolume(volumeName string, names Names) corev1.Volume {
+	return corev1.Volume{
+		Name: volumeName,
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{
+					{
+						Secret: &corev1.SecretProjection{
+							LocalObjectReference: corev1.LocalObjectReference{Name: KeyPairName(names.Scoped(ServiceAccountKeyName))},
+							Items:                []corev1.KeyToPath{{Key: v1alpha1.PrivateKeyDataKey, Path: ServiceAccountKeyFile}},
+						},
+					},
+					{
+						Secret: &corev1.SecretProjection{
+							LocalObjectReference: corev1.LocalObjectReference{Name: names.Scoped(CACertificateName)},
+							Items:                []corev1.KeyToPath{{Key: v1alpha1.CertificatePEMDataKey, Path: CAFile}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// ClusterSigningVolume returns a volume that contains the certificate of the CA of the cluster with the given names
+// at CAFile and its private key at CAKeyFile, which the controller manager signs the client certificates of the
+// kubelets with.
+func ClusterSigningVolume(volumeName string, names Names) corev1.Volume {
+	return corev1.Volume{
+		Name: volumeName,
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{
+					{
+						Secret: &corev1.SecretProjection{
+							LocalObjectReference: corev1.LocalObjectReference{Name: names.Scoped(CACertificateName)},
+							Items:                []corev1.KeyToPath{{Key: v1alpha1.CertificatePEMDataKey, Path: CAFile}},
+						},
+					},
+					{
+						Secret: &corev1.SecretProjection{
+							LocalObjectReference: corev1.LocalObjectReference{Name: KeyPairName(names.Scoped(CACertificateName))},
+							Items:                []corev1.KeyToPath{{Key: v1alpha1.PrivateKeyDataKey, Path: CAKeyFile}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,108 @@
+package cluster
+
+import (
+	"fmt"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/util/flags"
+)
+
+const (
+	// SchedulerConfigDir is the directory the policy or configuration file of the scheduler is mounted at.
+	SchedulerConfigDir = "/etc/kubernetes/scheduler"
+	// SchedulerPolicyFile is the scheduler policy file.
+	SchedulerPolicyFile = SchedulerConfigDir + "/policy.cfg"
+	// SchedulerConfigFile is the scheduler configuration file.
+	SchedulerConfigFile = SchedulerConfigDir + "/config.yaml"
+
+	// DefaultSchedulerReplicas is the default number of scheduler replicas.
+	DefaultSchedulerReplicas = 1
+
+	schedulerConfigVolumeName = "scheduler-config"
+)
+
+var (
+	// schedulerManagedFlags are the scheduler flags that are rendered by kubeception and cannot be overridden.
+	schedulerManagedFlags = append([]string{
+		"config",
+		"policy-config-file",
+		"use-legacy-policy-config",
+	}, kubeconfigFlags...)
+)
+
+// validateScheduler validates the given scheduler configuration.
+func validateScheduler(scheduler *v1alpha1.Scheduler) error {
+	replicas := int32(DefaultSchedulerReplicas)
+	if scheduler.Replicas != nil {
+		replicas = *scheduler.Replicas
+	}
+	if err := validateLeaderElection(replicas, scheduler.LeaderElection); err != nil {
+		return err
+	}
+
+	if scheduler.Policy != nil && scheduler.Config != nil {
+		return fmt.Errorf("only one of policy and config can be specified")
+	}
+	for _, selector := range []*corev1.ConfigMapKeySelector{scheduler.Policy, scheduler.Config} {
+		if selector != nil && (selector.Name == "" || selector.Key == "") {
+			return fmt.Errorf("name and key of a scheduler config map have to be specified")
+		}
+	}
+
+	if err := validateFeatureGates(scheduler.FeatureGates); err != nil {
+		return err
+	}
+	return nil
+}
+
+// schedulerFlags renders the flags of the scheduler from the given configuration.
+func schedulerFlags(scheduler *v1alpha1.Scheduler) ([]string, error) {
+	if err := validateScheduler(scheduler); err != nil {
+		return nil, err
+	}
+
+	f := flags.New()
+	for _, name := range kubeconfigFlags {
+		f.Set(name, KubeconfigFile)
+	}
+	if scheduler.Policy != nil {
+		f.Set("policy-config-file", SchedulerPolicyFile)
+		f.Set("use-legacy-policy-config", "true")
+	}
+	if scheduler.Config != nil {
+		f.Set("config", SchedulerConfigFile)
+	}
+
+	setLeaderElectionFlags(f, scheduler.LeaderElection)
+	if len(scheduler.FeatureGates) > 0 {
+		f.Set("feature-gates", flags.JoinFeatureGates(scheduler.FeatureGates))
+	}
+
+	if err := f.SetExtraArgs(scheduler.ExtraArgs, schedulerManagedFlags...); err != nil {
+		return nil, err
+	}
+	return f.Args(), nil
+}
+
+// schedulerConfigVolume returns the volume containing the policy or configuration file of the scheduler, if any.
+func schedulerConfigVolume(scheduler *v1alpha1.Scheduler) *corev1.Volume {
+	selector, file := scheduler.Policy, SchedulerPolicyFile
+	if scheduler.Config != nil {
+		selector, file = scheduler.Config, SchedulerConfigFile
+	}
+	if selector == nil {
+		return nil
+	}
+
+	return &corev1.Volume{
+		Name: schedulerConfigVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: selector.LocalObjectReference,
+				Items:                []corev1.KeyToPath{{Key: selector.Key, Path: path.Base(file)}},
+			},
+		},
+	}
+}
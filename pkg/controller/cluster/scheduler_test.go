@@ -0,0 +1,45 @@
+package cluster
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+)
+
+var _ = Describe("Scheduler", func() {
+	policy := &corev1.ConfigMapKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "scheduler"},
+		Key:                  "policy.json",
+	}
+
+	Describe("#schedulerFlags", func() {
+		It("should render the policy file flags", func() {
+			args, err := schedulerFlags(&v1alpha1.Scheduler{Policy: policy})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(ContainElement("--policy-config-file=/etc/kubernetes/scheduler/policy.cfg"))
+			Expect(args).To(ContainElement("--use-legacy-policy-config=true"))
+		})
+
+		It("should reject both a policy and a config", func() {
+			_, err := schedulerFlags(&v1alpha1.Scheduler{Policy: policy, Config: policy})
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("#schedulerConfigVolume", func() {
+		It("should not return a volume without policy or config", func() {
+			Expect(schedulerConfigVolume(&v1alpha1.Scheduler{})).To(BeNil())
+		})
+
+		It("should project the selected key to the policy file", func() {
+			volume := schedulerConfigVolume(&v1alpha1.Scheduler{Policy: policy})
+
+			Expect(volume).NotTo(BeNil())
+			Expect(volume.ConfigMap.Name).To(Equal("scheduler"))
+			Expect(volume.ConfigMap.Items).To(Equal([]corev1.KeyToPath{{Key: "policy.json", Path: "policy.cfg"}}))
+		})
+	})
+})
@@ -0,0 +1,186 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/helper"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/controller/common"
+	"kubeception.cloud/kubeception/pkg/util"
+	"kubeception.cloud/kubeception/pkg/util/pointers"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllererror "sigs.k8s.io/cluster-api/pkg/controller/error"
+)
+
+const (
+	// StatusRequeueAfter is the duration after which the status of a cluster whose control plane is not
+	// ready yet is refreshed.
+	StatusRequeueAfter = 10 * time.Second
+)
+
+func statefulSetComponentStatus(statefulSet *appsv1.StatefulSet) v1alpha1.ComponentStatus {
+	replicas := pointers.DerefInt32OrDefault(statefulSet.Spec.Replicas, 1)
+	return v1alpha1.ComponentStatus{
+		Replicas:      replicas,
+		ReadyReplicas: statefulSet.Status.ReadyReplicas,
+		Ready:         replicas > 0 && common.StatefulSetReady(statefulSet),
+	}
+}
+
+func deploymentComponentStatus(deployment *appsv1.Deployment) v1alpha1.ComponentStatus {
+	replicas := pointers.DerefInt32OrDefault(deployment.Spec.Replicas, 1)
+	return v1alpha1.ComponentStatus{
+		Replicas:      replicas,
+		ReadyReplicas: deployment.Status.ReadyReplicas,
+		Ready:         replicas > 0 && common.DeploymentReady(deployment),
+	}
+}
+
+// imageTag returns the tag of the given image or the empty string if the image is not tagged.
+func imageTag(image string) string {
+	idx := strings.LastIndex(image, ":")
+	if idx < 0 || strings.Contains(image[idx:], "/") {
+		return ""
+	}
+	return image[idx+1:]
+}
+
+// getObject reads the object with the given name into obj. It returns false if the object does not exist.
+func (a *actuator) getObject(ctx context.Context, namespace, name string, obj runtime.Object) (bool, error) {
+	if err := a.Client.Get(ctx, util.Key(namespace, name), obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (a *actuator) deploymentComponentStatus(ctx context.Context, namespace, name string) (*v1alpha1.ComponentStatus, *appsv1.Deployment, error) {
+	deployment := &appsv1.Deployment{}
+	exists, err := a.getObject(ctx, namespace, name, deployment)
+	if err != nil || !exists {
+		return &v1alpha1.ComponentStatus{}, nil, err
+	}
+
+	status := deploymentComponentStatus(deployment)
+	return &status, deployment, nil
+}
+
+// providerStatusEqual checks whether the given provider statuses are equal, disregarding their type meta.
+func providerStatusEqual(a, b *v1alpha1.ClusterStatus) bool {
+	return a.Ready == b.Ready &&
+		a.KubernetesVersion == b.KubernetesVersion &&
+		apiequality.Semantic.DeepEqual(a.ControlPlane, b.ControlPlane) &&
+		apiequality.Semantic.DeepEqual(a.Upgrade, b.Upgrade)
+}
+
+// computeProviderStatus computes the provider status of the given cluster from the status of its control plane
+// components. The Kubernetes version of the previous status is kept until the API server has been completely
+// rolled out.
+func (a *actuator) computeProviderStatus(ctx context.Context, cluster *clusterv1alpha1.Cluster, config *v1alpha1.ClusterConfig, previous *v1alpha1.ClusterStatus, upgrade *v1alpha1.UpgradeStatus) (*v1alpha1.ClusterStatus, error) {
+	names := ClusterNames(cluster)
+	status := &v1alpha1.ClusterStatus{KubernetesVersion: previous.KubernetesVersion, Upgrade: upgrade}
+
+	statefulSet := &appsv1.StatefulSet{}
+	exists, err := a.getObject(ctx, cluster.Namespace, names.Scoped(ETCDStatefulSetName), statefulSet)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		status.ControlPlane.ETCD = statefulSetComponentStatus(statefulSet)
+	}
+
+	apiServerStatus, apiServer, err := a.deploymentComponentStatus(ctx, cluster.Namespace, names.Scoped(APIServerDeploymentName))
+	if err != nil {
+		return nil, err
+	}
+	status.ControlPlane.APIServer = *apiServerStatus
+	if apiServerStatus.Ready {
+		status.KubernetesVersion = workloadVersion(apiServer, &apiServer.Spec.Template, "kube-apiserver")
+	}
+
+	status.Ready = status.ControlPlane.ETCD.Ready && status.ControlPlane.APIServer.Ready
+	if config.ControlPlane.ControllerManager != nil {
+		controllerManagerStatus, _, err := a.deploymentComponentStatus(ctx, cluster.Namespace, names.Scoped(ControllerManagerDeploymentName))
+		if err != nil {
+			return nil, err
+		}
+		status.ControlPlane.ControllerManager = controllerManagerStatus
+		status.Ready = status.Ready && controllerManagerStatus.Ready
+	}
+	if config.ControlPlane.Scheduler != nil {
+		schedulerStatus, _, err := a.deploymentComponentStatus(ctx, cluster.Namespace, names.Scoped(SchedulerDeploymentName))
+		if err != nil {
+			return nil, err
+		}
+		status.ControlPlane.Scheduler = schedulerStatus
+		status.Ready = status.Ready && schedulerStatus.Ready
+	}
+	return status, nil
+}
+
+// apiEndpoints returns the endpoints of the API server service of the given cluster.
+func (a *actuator) apiEndpoints(ctx context.Context, cluster *clusterv1alpha1.Cluster) ([]clusterv1alpha1.APIEndpoint, error) {
+	service := &corev1.Service{}
+	exists, err := a.getObject(ctx, cluster.Namespace, ClusterNames(cluster).Scoped(APIServerServiceName), service)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	var endpoints []clusterv1alpha1.APIEndpoint
+	for _, port := range service.Spec.Ports {
+		endpoints = append(endpoints, clusterv1alpha1.APIEndpoint{
+			Host: fmt.Sprintf("%s.%s.svc", service.Name, service.Namespace),
+			Port: int(port.Port),
+		})
+	}
+	return endpoints, nil
+}
+
+// updateStatus updates the status of the given cluster with its API endpoints and provider status.
+// If the control plane is not ready yet, a RequeueAfterError is returned so that the status is refreshed.
+func (a *actuator) updateStatus(ctx context.Context, cluster *clusterv1alpha1.Cluster, config *v1alpha1.ClusterConfig, upgrade *v1alpha1.UpgradeStatus) error {
+	previous := &v1alpha1.ClusterStatus{}
+	if raw := cluster.Status.ProviderStatus; raw != nil && len(raw.Raw) > 0 {
+		if loaded, err := helper.LoadClusterStatus(raw.Raw); err == nil {
+			previous = loaded
+		}
+	}
+
+	providerStatus, err := a.computeProviderStatus(ctx, cluster, config, previous, upgrade)
+	if err != nil {
+		return err
+	}
+
+	endpoints, err := a.apiEndpoints(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	if !providerStatusEqual(providerStatus, previous) || !apiequality.Semantic.DeepEqual(endpoints, cluster.Status.APIEndpoints) {
+		rawProviderStatus, err := helper.EncodeClusterStatus(providerStatus)
+		if err != nil {
+			return err
+		}
+
+		cluster.Status.APIEndpoints = endpoints
+		cluster.Status.ProviderStatus = rawProviderStatus
+		if err := a.Client.Status().Update(ctx, cluster); err != nil {
+			return err
+		}
+	}
+
+	if !providerStatus.Ready {
+		return &controllererror.RequeueAfterError{RequeueAfter: StatusRequeueAfter}
+	}
+	return nil
+}
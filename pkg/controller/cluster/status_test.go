@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/util/pointers"
+)
+
+var _ = Describe("Status", func() {
+	Describe("#imageTag", func() {
+		It("should return the tag of the image", func() {
+			Expect(imageTag("k8s.gcr.io/hyperkube:v1.13.5")).To(Equal("v1.13.5"))
+			Expect(imageTag("registry:5000/hyperkube:v1.14.1")).To(Equal("v1.14.1"))
+		})
+
+		It("should return the empty string for untagged images", func() {
+			Expect(imageTag("k8s.gcr.io/hyperkube")).To(BeEmpty())
+			Expect(imageTag("registry:5000/hyperkube")).To(BeEmpty())
+		})
+	})
+
+	Describe("#deploymentComponentStatus", func() {
+		It("should be ready if all replicas are updated and available", func() {
+			deployment := &appsv1.Deployment{
+				Spec: appsv1.DeploymentSpec{Replicas: pointers.Int32(2)},
+				Status: appsv1.DeploymentStatus{
+					Replicas:          2,
+					UpdatedReplicas:   2,
+					ReadyReplicas:     2,
+					AvailableReplicas: 2,
+				},
+			}
+
+			Expect(deploymentComponentStatus(deployment)).To(Equal(v1alpha1.ComponentStatus{
+				Replicas:      2,
+				ReadyReplicas: 2,
+				Ready:         true,
+			}))
+		})
+
+		It("should not be ready while old replicas are left", func() {
+			deployment := &appsv1.Deployment{
+				Spec: appsv1.DeploymentSpec{Replicas: pointers.Int32(1)},
+				Status: appsv1.DeploymentStatus{
+					Replicas:          2,
+					UpdatedReplicas:   1,
+					ReadyReplicas:     2,
+					AvailableReplicas: 2,
+				},
+			}
+
+			Expect(deploymentComponentStatus(deployment).Ready).To(BeFalse())
+		})
+
+		It("should not be ready if scaled down", func() {
+			deployment := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: pointers.Int32(0)}}
+
+			Expect(deploymentComponentStatus(deployment).Ready).To(BeFalse())
+		})
+	})
+
+	Describe("#statefulSetComponentStatus", func() {
+		It("should not be ready until all replicas are ready", func() {
+			statefulSet := &appsv1.StatefulSet{
+				Spec:   appsv1.StatefulSetSpec{Replicas: pointers.Int32(3)},
+				Status: appsv1.StatefulSetStatus{UpdatedReplicas: 3, ReadyReplicas: 2},
+			}
+
+			Expect(statefulSetComponentStatus(statefulSet)).To(Equal(v1alpha1.ComponentStatus{
+				Replicas:      3,
+				ReadyReplicas: 2,
+			}))
+		})
+	})
+
+	Describe("#providerStatusEqual", func() {
+		It("should disregard the type meta", func() {
+			status := &v1alpha1.ClusterStatus{Ready: true, KubernetesVersion: "v1.13.5"}
+			withTypeMeta := status.DeepCopy()
+			withTypeMeta.APIVersion = v1alpha1.SchemeGroupVersion.String()
+			withTypeMeta.Kind = v1alpha1.ClusterStatusKind
+
+			Expect(providerStatusEqual(status, withTypeMeta)).To(BeTrue())
+		})
+
+		It("should compare the component statuses", func() {
+			status := &v1alpha1.ClusterStatus{}
+			other := &v1alpha1.ClusterStatus{
+				ControlPlane: v1alpha1.ControlPlaneStatus{Scheduler: &v1alpha1.ComponentStatus{}},
+			}
+
+			Expect(providerStatusEqual(status, other)).To(BeFalse())
+		})
+	})
+})
@@ -9,24 +9,18 @@ import (
 var (
 	ControlPlaneComponentLabel = fmt.Sprintf("%s/control-plane-component", common.LabelPrefix)
 
+	// ClusterNameLabel is the label carrying the name of the cluster an object belongs to.
+	ClusterNameLabel = fmt.Sprintf("%s/cluster-name", common.LabelPrefix)
+
+	// AddonLabel is the label on the objects kubeception manages in a hosted cluster, carrying the addon they
+	// belong to.
+	AddonLabel = fmt.Sprintf("%s/addon", common.LabelPrefix)
+
+	// LegacyNamesAnnotation marks clusters whose objects were created before their names were scoped by cluster.
+	LegacyNamesAnnotation = fmt.Sprintf("%s/legacy-names", common.LabelPrefix)
+
 	ETCDComponent              = "etcd"
 	APIServerComponent         = "kube-apiserver"
 	ControllerManagerComponent = "controller-manager"
 	SchedulerComponent         = "scheduler"
-
-	ETCDLabels = map[string]string{
-		ControlPlaneComponentLabel: ETCDComponent,
-	}
-
-	APIServerLabels = map[string]string{
-		ControlPlaneComponentLabel: APIServerComponent,
-	}
-
-	ControllerManagerLabels = map[string]string{
-		ControlPlaneComponentLabel: ControllerManagerComponent,
-	}
-
-	SchedulerLabels = map[string]string{
-		ControlPlaneComponentLabel: SchedulerComponent,
-	}
 )
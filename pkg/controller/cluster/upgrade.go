@@ -0,0 +1,311 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/helper"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/controller/common"
+	"kubeception.cloud/kubeception/pkg/util"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// UpgradeRequeueAfter is the duration after which the progress of a Kubernetes version upgrade is checked.
+	UpgradeRequeueAfter = 10 * time.Second
+)
+
+var (
+	// KubernetesVersionAnnotation is the annotation on the workloads of the control plane components and the
+	// machines that carries the Kubernetes version they have been rendered with.
+	KubernetesVersionAnnotation = fmt.Sprintf("%s/kubernetes-version", common.LabelPrefix)
+	// KubeletVersionAnnotation is the annotation on a machine that carries the Kubernetes version its kubelet
+	// has been upgraded to.
+	KubeletVersionAnnotation = fmt.Sprintf("%s/kubelet-version", common.LabelPrefix)
+)
+
+// KubeletVersion returns the Kubernetes version of the kubelet of the given machine. Machines that have not been
+// upgraded by the cluster actuator yet run the version of the control plane, so that kubelets are never newer
+// than the API server.
+func KubeletVersion(cluster *clusterv1alpha1.Cluster, machine *clusterv1alpha1.Machine, config *v1alpha1.ClusterConfig) string {
+	if version, ok := machine.Annotations[KubeletVersionAnnotation]; ok {
+		return version
+	}
+
+	if raw := cluster.Status.ProviderStatus; raw != nil && len(raw.Raw) > 0 {
+		if status, err := helper.LoadClusterStatus(raw.Raw); err == nil && status.KubernetesVersion != "" {
+			return status.KubernetesVersion
+		}
+	}
+	return config.KubernetesVersion
+}
+
+// workloadVersion returns the Kubernetes version of the given workload. Workloads created before the version
+// was recorded in the KubernetesVersionAnnotation are identified by the image tag of the given container.
+func workloadVersion(obj metav1.Object, template *corev1.PodTemplateSpec, containerName string) string {
+	if version, ok := obj.GetAnnotations()[KubernetesVersionAnnotation]; ok {
+		return version
+	}
+
+	for _, container := range template.Spec.Containers {
+		if container.Name == containerName {
+			return imageTag(container.Image)
+		}
+	}
+	return ""
+}
+
+// componentVersion is the observed Kubernetes version of a control plane component.
+type componentVersion struct {
+	// Phase is the upgrade phase of the component.
+	Phase v1alpha1.UpgradePhase
+	// Version is the Kubernetes version of the component or empty if the component does not exist yet.
+	Version string
+	// Ready indicates that all replicas of the component are up to date and ready.
+	Ready bool
+}
+
+// upgradePlan holds the Kubernetes versions the control plane components are rendered with.
+type upgradePlan struct {
+	Version           string
+	APIServer         string
+	ControllerManager string
+	Scheduler         string
+	// Ready indicates that etcd and all control plane components are ready.
+	Ready bool
+	// Upgrade is the upgrade in progress or nil if the control plane runs the desired version.
+	Upgrade *v1alpha1.UpgradeStatus
+}
+
+// planUpgrade computes the versions of the given components, which are given in upgrade order, on the way to the
+// desired version. A component is only upgraded once etcd and all components before it are ready, until then it
+// keeps its version, as do all components after it. Components that do not exist yet are created with the version
+// of the component before them.
+func planUpgrade(desired string, etcdReady bool, components []componentVersion) ([]string, bool, *v1alpha1.UpgradeStatus) {
+	var (
+		versions   = make([]string, len(components))
+		upgrade    *v1alpha1.UpgradeStatus
+		waitingFor v1alpha1.UpgradePhase
+		previous   = desired
+	)
+	if !etcdReady {
+		waitingFor = v1alpha1.UpgradeETCD
+	}
+
+	for i, component := range components {
+		current := component.Version
+		if current == "" {
+			current = previous
+		}
+
+		switch {
+		case upgrade != nil:
+			versions[i] = current
+		case current != desired && waitingFor != "":
+			versions[i] = current
+			upgrade = &v1alpha1.UpgradeStatus{KubernetesVersion: desired, Phase: waitingFor}
+		case current != desired:
+			versions[i] = desired
+			upgrade = &v1alpha1.UpgradeStatus{KubernetesVersion: desired, Phase: component.Phase}
+		default:
+			versions[i] = desired
+		}
+
+		if waitingFor == "" && component.Version != "" && !component.Ready {
+			waitingFor = component.Phase
+		}
+		previous = current
+	}
+	return versions, waitingFor == "", upgrade
+}
+
+// validateUpgrade validates the upgrade of control plane components and kubelets with the given versions to the
+// desired version. Minor versions of the control plane cannot be skipped or downgraded, and kubelets can neither
+// be newer than the control plane nor more than two minor versions older.
+func validateUpgrade(desired string, controlPlaneVersions, kubeletVersions []string) error {
+	desiredVersion, err := utilversion.ParseGeneric(desired)
+	if err != nil {
+		return fmt.Errorf("invalid Kubernetes version %q: %v", desired, err)
+	}
+
+	for _, current := range controlPlaneVersions {
+		if current == desired {
+			continue
+		}
+
+		currentVersion, err := utilversion.ParseGeneric(current)
+		if err != nil {
+			return fmt.Errorf("invalid control plane version %q: %v", current, err)
+		}
+
+		switch {
+		case currentVersion.Major() != desiredVersion.Major():
+			return fmt.Errorf("cannot change the major version from %s to %s", current, desired)
+		case desiredVersion.Minor() < currentVersion.Minor():
+			return fmt.Errorf("cannot downgrade the control plane from %s to %s", current, desired)
+		case desiredVersion.Minor() > currentVersion.Minor()+1:
+			return fmt.Errorf("cannot upgrade the control plane from %s to %s, minor versions cannot be skipped", current, desired)
+		}
+	}
+
+	for _, kubelet := range kubeletVersions {
+		kubeletVersion, err := utilversion.ParseGeneric(kubelet)
+		if err != nil {
+			return fmt.Errorf("invalid kubelet version %q: %v", kubelet, err)
+		}
+
+		switch {
+		case kubeletVersion.Major() != desiredVersion.Major() || kubeletVersion.Minor() > desiredVersion.Minor():
+			return fmt.Errorf("kubelet version %s must not be newer than %s", kubelet, desired)
+		case desiredVersion.Minor() > kubeletVersion.Minor()+2:
+			return fmt.Errorf("kubelet version %s must not be more than two minor versions older than %s", kubelet, desired)
+		}
+	}
+	return nil
+}
+
+// deploymentVersion observes the version of the control plane component running in the given Deployment.
+func (a *actuator) deploymentVersion(ctx context.Context, namespace, name, containerName string, phase v1alpha1.UpgradePhase) (componentVersion, error) {
+	deployment := &appsv1.Deployment{}
+	exists, err := a.getObject(ctx, namespace, name, deployment)
+	if err != nil || !exists {
+		return componentVersion{Phase: phase}, err
+	}
+
+	return componentVersion{
+		Phase:   phase,
+		Version: workloadVersion(deployment, &deployment.Spec.Template, containerName),
+		Ready:   common.DeploymentReady(deployment),
+	}, nil
+}
+
+// clusterMachines lists the machines of the given cluster that are not being deleted, sorted by name.
+func (a *actuator) clusterMachines(ctx context.Context, cluster *clusterv1alpha1.Cluster) ([]clusterv1alpha1.Machine, error) {
+	machineList := &clusterv1alpha1.MachineList{}
+	if err := a.Client.List(ctx, machineList, client.InNamespace(cluster.Namespace), client.MatchingLabels{clusterv1alpha1.MachineClusterLabelName: cluster.Name}); err != nil {
+		return nil, err
+	}
+
+	var machines []clusterv1alpha1.Machine
+	for _, machine := range machineList.Items {
+		if machine.DeletionTimestamp == nil {
+			machines = append(machines, machine)
+		}
+	}
+	sort.Slice(machines, func(i, j int) bool { return machines[i].Name < machines[j].Name })
+	return machines, nil
+}
+
+// planControlPlaneUpgrade validates the upgrade of the given cluster to the Kubernetes version of its configuration
+// and plans the versions its control plane components are rendered with.
+func (a *actuator) planControlPlaneUpgrade(ctx context.Context, cluster *clusterv1alpha1.Cluster, config *v1alpha1.ClusterConfig) (*upgradePlan, error) {
+	names := ClusterNames(cluster)
+
+	statefulSet := &appsv1.StatefulSet{}
+	exists, err := a.getObject(ctx, cluster.Namespace, names.Scoped(ETCDStatefulSetName), statefulSet)
+	if err != nil {
+		return nil, err
+	}
+	etcdReady := exists && common.StatefulSetReady(statefulSet)
+
+	apiServer, err := a.deploymentVersion(ctx, cluster.Namespace, names.Scoped(APIServerDeploymentName), "kube-apiserver", v1alpha1.UpgradeAPIServer)
+	if err != nil {
+		return nil, err
+	}
+	controllerManager, err := a.deploymentVersion(ctx, cluster.Namespace, names.Scoped(ControllerManagerDeploymentName), "controller-manager", v1alpha1.UpgradeControllerManager)
+	if err != nil {
+		return nil, err
+	}
+	scheduler, err := a.deploymentVersion(ctx, cluster.Namespace, names.Scoped(SchedulerDeploymentName), "scheduler", v1alpha1.UpgradeScheduler)
+	if err != nil {
+		return nil, err
+	}
+
+	machines, err := a.clusterMachines(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	var controlPlaneVersions, kubeletVersions []string
+	for _, component := range []componentVersion{apiServer, controllerManager, scheduler} {
+		if component.Version != "" {
+			controlPlaneVersions = append(controlPlaneVersions, component.Version)
+		}
+	}
+	for _, machine := range machines {
+		if version, ok := machine.Annotations[KubeletVersionAnnotation]; ok {
+			kubeletVersions = append(kubeletVersions, version)
+		}
+	}
+	if err := validateUpgrade(config.KubernetesVersion, controlPlaneVersions, kubeletVersions); err != nil {
+		return nil, fmt.Errorf("invalid Kubernetes version upgrade: %v", err)
+	}
+
+	// Disabled components are not part of the upgrade.
+	components := []componentVersion{apiServer}
+	if config.ControlPlane.ControllerManager != nil {
+		components = append(components, controllerManager)
+	}
+	if config.ControlPlane.Scheduler != nil {
+		components = append(components, scheduler)
+	}
+
+	versions, ready, upgrade := planUpgrade(config.KubernetesVersion, etcdReady, components)
+	plan := &upgradePlan{
+		Version:           config.KubernetesVersion,
+		APIServer:         versions[0],
+		ControllerManager: config.KubernetesVersion,
+		Scheduler:         config.KubernetesVersion,
+		Ready:             ready,
+		Upgrade:           upgrade,
+	}
+	for i, component := range components {
+		switch component.Phase {
+		case v1alpha1.UpgradeControllerManager:
+			plan.ControllerManager = versions[i]
+		case v1alpha1.UpgradeScheduler:
+			plan.Scheduler = versions[i]
+		}
+	}
+	return plan, nil
+}
+
+// upgradeMachines upgrades the kubelets of the machines of the given cluster to the given version, one machine at a
+// time. A machine is upgraded once the StatefulSet of the previous one runs the version and is ready. It returns the
+// name of the machine that is being upgraded or the empty string if all machines run the version.
+func (a *actuator) upgradeMachines(ctx context.Context, cluster *clusterv1alpha1.Cluster, version string) (string, error) {
+	machines, err := a.clusterMachines(ctx, cluster)
+	if err != nil {
+		return "", err
+	}
+
+	for _, machine := range machines {
+		if machine.Annotations[KubeletVersionAnnotation] != version {
+			withoutAnnotation := machine.DeepCopy()
+			util.SetMetaDataAnnotation(&machine, KubeletVersionAnnotation, version)
+			if err := a.Client.Patch(ctx, &machine, client.MergeFrom(withoutAnnotation)); err != nil {
+				return "", client.IgnoreNotFound(err)
+			}
+			return machine.Name, nil
+		}
+
+		// The StatefulSet of a machine has the name of the machine.
+		statefulSet := &appsv1.StatefulSet{}
+		exists, err := a.getObject(ctx, machine.Namespace, machine.Name, statefulSet)
+		if err != nil {
+			return "", err
+		}
+		if !exists || statefulSet.Annotations[KubernetesVersionAnnotation] != version || !common.StatefulSetReady(statefulSet) {
+			return machine.Name, nil
+		}
+	}
+	return "", nil
+}
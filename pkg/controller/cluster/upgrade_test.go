@@ -0,0 +1,110 @@
+package cluster
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/util"
+)
+
+var _ = Describe("Upgrade", func() {
+	apiServer := func(version string, ready bool) componentVersion {
+		return componentVersion{Phase: v1alpha1.UpgradeAPIServer, Version: version, Ready: ready}
+	}
+	controllerManager := func(version string, ready bool) componentVersion {
+		return componentVersion{Phase: v1alpha1.UpgradeControllerManager, Version: version, Ready: ready}
+	}
+
+	Describe("#planUpgrade", func() {
+		It("should create all components with the desired version", func() {
+			versions, ready, upgrade := planUpgrade("v1.15.0", false, []componentVersion{apiServer("", false), controllerManager("", false)})
+
+			Expect(versions).To(Equal([]string{"v1.15.0", "v1.15.0"}))
+			Expect(ready).To(BeFalse())
+			Expect(upgrade).To(BeNil())
+		})
+
+		It("should wait for etcd before upgrading the API server", func() {
+			versions, _, upgrade := planUpgrade("v1.15.0", false, []componentVersion{apiServer("v1.14.0", true), controllerManager("v1.14.0", true)})
+
+			Expect(versions).To(Equal([]string{"v1.14.0", "v1.14.0"}))
+			Expect(upgrade).To(Equal(&v1alpha1.UpgradeStatus{KubernetesVersion: "v1.15.0", Phase: v1alpha1.UpgradeETCD}))
+		})
+
+		It("should upgrade the API server first", func() {
+			versions, _, upgrade := planUpgrade("v1.15.0", true, []componentVersion{apiServer("v1.14.0", true), controllerManager("v1.14.0", true)})
+
+			Expect(versions).To(Equal([]string{"v1.15.0", "v1.14.0"}))
+			Expect(upgrade).To(Equal(&v1alpha1.UpgradeStatus{KubernetesVersion: "v1.15.0", Phase: v1alpha1.UpgradeAPIServer}))
+		})
+
+		It("should wait for the upgraded API server to be ready", func() {
+			versions, ready, upgrade := planUpgrade("v1.15.0", true, []componentVersion{apiServer("v1.15.0", false), controllerManager("v1.14.0", true)})
+
+			Expect(versions).To(Equal([]string{"v1.15.0", "v1.14.0"}))
+			Expect(ready).To(BeFalse())
+			Expect(upgrade).To(Equal(&v1alpha1.UpgradeStatus{KubernetesVersion: "v1.15.0", Phase: v1alpha1.UpgradeAPIServer}))
+		})
+
+		It("should upgrade the next component once the previous one is ready", func() {
+			versions, _, upgrade := planUpgrade("v1.15.0", true, []componentVersion{apiServer("v1.15.0", true), controllerManager("v1.14.0", true)})
+
+			Expect(versions).To(Equal([]string{"v1.15.0", "v1.15.0"}))
+			Expect(upgrade).To(Equal(&v1alpha1.UpgradeStatus{KubernetesVersion: "v1.15.0", Phase: v1alpha1.UpgradeControllerManager}))
+		})
+
+		It("should create missing components with the version of the previous component", func() {
+			versions, _, _ := planUpgrade("v1.15.0", true, []componentVersion{apiServer("v1.14.0", true), controllerManager("", false)})
+
+			Expect(versions).To(Equal([]string{"v1.15.0", "v1.14.0"}))
+		})
+
+		It("should not report an upgrade once all components run the desired version", func() {
+			_, ready, upgrade := planUpgrade("v1.15.0", true, []componentVersion{apiServer("v1.15.0", true), controllerManager("v1.15.0", true)})
+
+			Expect(ready).To(BeTrue())
+			Expect(upgrade).To(BeNil())
+		})
+	})
+
+	Describe("#validateUpgrade", func() {
+		It("should allow patch and single minor version upgrades", func() {
+			Expect(validateUpgrade("v1.14.3", []string{"v1.14.1"}, nil)).To(Succeed())
+			Expect(validateUpgrade("v1.15.0", []string{"v1.14.1", "v1.15.0"}, []string{"v1.13.0"})).To(Succeed())
+		})
+
+		It("should reject skipping minor versions", func() {
+			Expect(validateUpgrade("v1.16.0", []string{"v1.14.1"}, nil)).NotTo(Succeed())
+		})
+
+		It("should reject minor version downgrades", func() {
+			Expect(validateUpgrade("v1.13.0", []string{"v1.14.1"}, nil)).NotTo(Succeed())
+		})
+
+		It("should reject kubelets newer than the control plane", func() {
+			Expect(validateUpgrade("v1.14.0", nil, []string{"v1.15.0"})).NotTo(Succeed())
+		})
+
+		It("should reject kubelets more than two minor versions older than the control plane", func() {
+			Expect(validateUpgrade("v1.15.0", nil, []string{"v1.12.0"})).NotTo(Succeed())
+		})
+
+		It("should reject invalid versions", func() {
+			Expect(validateUpgrade("latest", nil, nil)).NotTo(Succeed())
+		})
+	})
+
+	Describe("#workloadVersion", func() {
+		It("should prefer the version annotation over the image tag", func() {
+			deployment := &appsv1.Deployment{ObjectMeta: util.ObjectMeta("default", "foo-apiserver")}
+			deployment.Spec.Template.Spec.Containers = []corev1.Container{{Name: "kube-apiserver", Image: "k8s.gcr.io/hyperkube:v1.14.0"}}
+
+			Expect(workloadVersion(deployment, &deployment.Spec.Template, "kube-apiserver")).To(Equal("v1.14.0"))
+
+			util.SetMetaDataAnnotation(deployment, KubernetesVersionAnnotation, "v1.14.1")
+			Expect(workloadVersion(deployment, &deployment.Spec.Template, "kube-apiserver")).To(Equal("v1.14.1"))
+		})
+	})
+})
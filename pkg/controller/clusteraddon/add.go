@@ -0,0 +1,53 @@
+package clusteraddon
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	Name = "clusteraddon"
+)
+
+type AddArgs struct {
+	MaxConcurrentReconciles int
+}
+
+var DefaultArgs AddArgs
+
+func AddToManager(mgr manager.Manager) error {
+	return AddToManagerWithArgs(mgr, DefaultArgs)
+}
+
+func AddToManagerWithArgs(mgr manager.Manager, args AddArgs) error {
+	ctrl, err := controller.New(Name, mgr, controller.Options{
+		Reconciler:              NewReconciler(mgr.GetEventRecorderFor(Name)),
+		MaxConcurrentReconciles: args.MaxConcurrentReconciles,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := ctrl.Watch(&source.Kind{Type: &v1alpha1.ClusterAddon{}}, &handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+
+	if err := ctrl.Watch(&source.Kind{Type: &clusterv1alpha1.Cluster{}}, &handler.EnqueueRequestsFromMapFunc{ToRequests: NewClusterToClusterAddonMapper()}); err != nil {
+		return err
+	}
+
+	if err := ctrl.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{ToRequests: NewSourceToClusterAddonMapper(configMapSource)}); err != nil {
+		return err
+	}
+
+	if err := ctrl.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{ToRequests: NewSourceToClusterAddonMapper(secretSource)}); err != nil {
+		return err
+	}
+
+	return nil
+}
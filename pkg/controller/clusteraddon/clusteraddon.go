@@ -0,0 +1,347 @@
+package clusteraddon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	clustercontroller "kubeception.cloud/kubeception/pkg/controller/cluster"
+	"kubeception.cloud/kubeception/pkg/controller/common"
+	"kubeception.cloud/kubeception/pkg/util"
+	"kubeception.cloud/kubeception/pkg/util/controller"
+	"kubeception.cloud/kubeception/pkg/util/finalizer"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+const (
+	// FinalizerName is the finalizer that removes the objects of an addon from the clusters on its deletion.
+	FinalizerName = common.LabelPrefix + "/cluster-addon"
+
+	// FieldManager is the field manager of the server-side applied objects.
+	FieldManager = "kubeception"
+
+	// ReapplyInterval is the interval at which the manifests are applied again to revert changes in the clusters.
+	ReapplyInterval = 5 * time.Minute
+	// RetryInterval is the interval at which applying the manifests to a cluster is retried after a failure.
+	RetryInterval = 30 * time.Second
+
+	EventInvalidSelector  = "InvalidSelector"
+	EventInvalidManifests = "InvalidManifests"
+	EventApplyFailed      = "ApplyFailed"
+	EventPruneFailed      = "PruneFailed"
+)
+
+var logger = log.Log.WithName("clusteraddon")
+
+type reconciler struct {
+	recorder record.EventRecorder
+	controller.WithClient
+	controller.WithScheme
+	controller.WithContext
+	controller.WithLog
+}
+
+func NewReconciler(recorder record.EventRecorder) reconcile.Reconciler {
+	return &reconciler{recorder: recorder, WithLog: controller.NewWithLog(logger)}
+}
+
+func (r *reconciler) Reconcile(req reconcile.Request) (reconcile.Result, error) {
+	log := r.Log.WithValues("clusteraddon", req.String())
+	addon := &v1alpha1.ClusterAddon{}
+	if err := r.Client.Get(r.Context, req.NamespacedName, addon); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+
+	var result reconcile.Result
+	err := finalizer.Handle(r.Context, r.Client, FinalizerName, addon, finalizer.Funcs{
+		ReconcileFunc: func() error {
+			var err error
+			result, err = r.reconcile(r.Context, log, addon)
+			return err
+		},
+		FinalizeFunc: func() error {
+			return r.finalize(r.Context, log, addon)
+		},
+	})
+	return result, err
+}
+
+// clusterStatus returns the status of the cluster with the given name or nil if there is none.
+func clusterStatus(status *v1alpha1.ClusterAddonStatus, name string) *v1alpha1.ClusterAddonClusterStatus {
+	for i := range status.Clusters {
+		if status.Clusters[i].Name == name {
+			return &status.Clusters[i]
+		}
+	}
+	return nil
+}
+
+// readSource reads the manifests of the given source in the namespace of the given addon.
+func (r *reconciler) readSource(ctx context.Context, addon *v1alpha1.ClusterAddon, source v1alpha1.ClusterAddonSource) ([]unstructured.Unstructured, error) {
+	switch {
+	case source.ConfigMap != nil && source.Secret == nil:
+		configMap := &corev1.ConfigMap{}
+		if err := r.Client.Get(ctx, util.Key(addon.Namespace, source.ConfigMap.Name), configMap); err != nil {
+			return nil, fmt.Errorf("could not read config map %s: %v", source.ConfigMap.Name, err)
+		}
+
+		data := make(map[string][]byte, len(configMap.Data))
+		for key, value := range configMap.Data {
+			data[key] = []byte(value)
+		}
+		objects, err := decodeSourceData(data)
+		if err != nil {
+			return nil, fmt.Errorf("config map %s: %v", source.ConfigMap.Name, err)
+		}
+		return objects, nil
+	case source.Secret != nil && source.ConfigMap == nil:
+		secret := &corev1.Secret{}
+		if err := r.Client.Get(ctx, util.Key(addon.Namespace, source.Secret.Name), secret); err != nil {
+			return nil, fmt.Errorf("could not read secret %s: %v", source.Secret.Name, err)
+		}
+
+		objects, err := decodeSourceData(secret.Data)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s: %v", source.Secret.Name, err)
+		}
+		return objects, nil
+	default:
+		return nil, fmt.Errorf("exactly one of configMap and secret has to be set")
+	}
+}
+
+// readManifests reads the manifests of all sources of the given addon in the order of the sources.
+func (r *reconciler) readManifests(ctx context.Context, addon *v1alpha1.ClusterAddon) ([]unstructured.Unstructured, error) {
+	var objects []unstructured.Unstructured
+	for _, source := range addon.Spec.Sources {
+		decoded, err := r.readSource(ctx, addon, source)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, decoded...)
+	}
+	return objects, nil
+}
+
+// applyObject applies the given object to the given cluster with server-side apply. API servers without server-side
+// apply, i.e. before Kubernetes 1.14 or without the ServerSideApply feature gate, reject the apply patch as
+// unsupported media type. On these, the object is created or, if it exists, merge patched with the manifest.
+func applyObject(ctx context.Context, guest client.Client, object *unstructured.Unstructured) error {
+	err := guest.Patch(ctx, object.DeepCopy(), client.Apply, client.ForceOwnership, client.FieldOwner(FieldManager))
+	if !apierrors.IsUnsupportedMediaType(err) {
+		return err
+	}
+
+	if err := guest.Create(ctx, object.DeepCopy()); !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return guest.Patch(ctx, object.DeepCopy(), client.Merge)
+}
+
+// applyObjects applies the given objects to the given cluster and deletes the previously applied objects that are
+// not part of them anymore. It returns the objects that are applied to the cluster afterwards, also on failure.
+func applyObjects(ctx context.Context, guest client.Client, objects []unstructured.Unstructured, previous []v1alpha1.ClusterAddonObject) ([]v1alpha1.ClusterAddonObject, error) {
+	var applied []v1alpha1.ClusterAddonObject
+	for _, object := range objects {
+		obj := object.DeepCopy()
+		if err := applyObject(ctx, guest, obj); err != nil {
+			return append(applied, objectsToPrune(previous, applied)...), fmt.Errorf("could not apply %s %s: %v", object.GetKind(), util.KeyFromObject(obj), err)
+		}
+		applied = append(applied, objectReference(&object))
+	}
+
+	prune := objectsToPrune(previous, applied)
+	for i, ref := range prune {
+		if err := guest.Delete(ctx, objectFromReference(ref)); err != nil && !isGone(err) {
+			return append(applied, prune[i:]...), fmt.Errorf("could not delete %s %s/%s: %v", ref.Kind, ref.Namespace, ref.Name, err)
+		}
+	}
+	return applied, nil
+}
+
+// isGone reports whether the given error indicates that an object or its kind does not exist.
+func isGone(err error) bool {
+	return client.IgnoreNotFound(err) == nil || meta.IsNoMatchError(err)
+}
+
+// reconcileCluster applies the given objects to the given cluster and returns the resulting status of the cluster.
+func (r *reconciler) reconcileCluster(ctx context.Context, log logr.Logger, addon *v1alpha1.ClusterAddon, cluster *clusterv1alpha1.Cluster, objects []unstructured.Unstructured) v1alpha1.ClusterAddonClusterStatus {
+	status := v1alpha1.ClusterAddonClusterStatus{Name: cluster.Name}
+	if previous := clusterStatus(&addon.Status, cluster.Name); previous != nil {
+		status = *previous.DeepCopy()
+	}
+
+	fail := func(err error) v1alpha1.ClusterAddonClusterStatus {
+		log.Error(err, "Could not apply addon", "cluster", cluster.Name)
+		r.recorder.Eventf(addon, corev1.EventTypeWarning, EventApplyFailed, "Could not apply to cluster %s: %v", cluster.Name, err)
+		status.Phase = v1alpha1.ClusterAddonFailed
+		status.Message = err.Error()
+		return status
+	}
+
+	guest, err := clustercontroller.NewClientForCluster(ctx, r.Client, cluster)
+	if err != nil {
+		return fail(fmt.Errorf("could not create client: %v", err))
+	}
+
+	applied, err := applyObjects(ctx, guest, objects, status.Objects)
+	status.Objects = applied
+	if err != nil {
+		return fail(err)
+	}
+
+	status.Phase = v1alpha1.ClusterAddonApplied
+	status.Message = ""
+	status.LastAppliedTime = &metav1.Time{Time: time.Now()}
+	return status
+}
+
+func (r *reconciler) reconcile(ctx context.Context, log logr.Logger, addon *v1alpha1.ClusterAddon) (reconcile.Result, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&addon.Spec.ClusterSelector)
+	if err != nil {
+		r.recorder.Eventf(addon, corev1.EventTypeWarning, EventInvalidSelector, "Invalid cluster selector: %v", err)
+		return reconcile.Result{}, nil
+	}
+
+	objects, err := r.readManifests(ctx, addon)
+	if err != nil {
+		r.recorder.Eventf(addon, corev1.EventTypeWarning, EventInvalidManifests, "Invalid manifests: %v", err)
+		return reconcile.Result{}, nil
+	}
+
+	clusterList := &clusterv1alpha1.ClusterList{}
+	if err := r.Client.List(ctx, clusterList, client.InNamespace(addon.Namespace)); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	var (
+		status = v1alpha1.ClusterAddonStatus{}
+		failed bool
+	)
+	for i := range clusterList.Items {
+		cluster := &clusterList.Items[i]
+		if !cluster.DeletionTimestamp.IsZero() || !selector.Matches(labels.Set(cluster.Labels)) {
+			continue
+		}
+
+		result := r.reconcileCluster(ctx, log, addon, cluster, objects)
+		if result.Phase == v1alpha1.ClusterAddonFailed {
+			failed = true
+		}
+		status.Clusters = append(status.Clusters, result)
+	}
+
+	// Clusters that are not selected anymore keep their status until the objects are removed from them.
+	for _, previous := range addon.Status.Clusters {
+		if clusterStatus(&status, previous.Name) != nil {
+			continue
+		}
+		if result, err := r.removeFromCluster(ctx, log, addon, previous); err != nil {
+			failed = true
+			status.Clusters = append(status.Clusters, *result)
+		}
+	}
+
+	if !apiequality.Semantic.DeepEqual(status, addon.Status) {
+		addon.Status = status
+		if err := r.Client.Status().Update(ctx, addon); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	if failed {
+		return reconcile.Result{RequeueAfter: RetryInterval}, nil
+	}
+	return reconcile.Result{RequeueAfter: ReapplyInterval}, nil
+}
+
+// pruneCluster deletes the given objects from the cluster with the given name in reverse order of their
+// application. It returns the objects that are left in the cluster, also on failure. Clusters that are gone or
+// whose kubeconfig is gone along with them are skipped.
+func (r *reconciler) pruneCluster(ctx context.Context, namespace, name string, objects []v1alpha1.ClusterAddonObject) ([]v1alpha1.ClusterAddonObject, error) {
+	if len(objects) == 0 {
+		return nil, nil
+	}
+
+	cluster := &clusterv1alpha1.Cluster{}
+	if err := r.Client.Get(ctx, util.Key(namespace, name), cluster); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return objects, err
+	}
+
+	guest, err := clustercontroller.NewClientForCluster(ctx, r.Client, cluster)
+	if err != nil {
+		if apierrors.IsNotFound(err) && !cluster.DeletionTimestamp.IsZero() {
+			return nil, nil
+		}
+		return objects, fmt.Errorf("could not create client: %v", err)
+	}
+
+	for i := len(objects) - 1; i >= 0; i-- {
+		ref := objects[i]
+		if err := guest.Delete(ctx, objectFromReference(ref)); err != nil && !isGone(err) {
+			return objects[:i+1], fmt.Errorf("could not delete %s %s/%s: %v", ref.Kind, ref.Namespace, ref.Name, err)
+		}
+	}
+	return nil, nil
+}
+
+// removeFromCluster deletes the objects of the given addon from the cluster of the given status. If not all of them
+// could be deleted, it returns the resulting status of the cluster listing the remaining objects along with the error.
+func (r *reconciler) removeFromCluster(ctx context.Context, log logr.Logger, addon *v1alpha1.ClusterAddon, previous v1alpha1.ClusterAddonClusterStatus) (*v1alpha1.ClusterAddonClusterStatus, error) {
+	remaining, err := r.pruneCluster(ctx, addon.Namespace, previous.Name, previous.Objects)
+	if err == nil {
+		log.Info("Removed addon", "cluster", previous.Name)
+		return nil, nil
+	}
+
+	log.Error(err, "Could not remove addon", "cluster", previous.Name)
+	r.recorder.Eventf(addon, corev1.EventTypeWarning, EventPruneFailed, "Could not remove from cluster %s: %v", previous.Name, err)
+	status := previous.DeepCopy()
+	status.Phase = v1alpha1.ClusterAddonFailed
+	status.Message = err.Error()
+	status.Objects = remaining
+	return status, err
+}
+
+// finalize deletes the objects of the given addon from all clusters they were applied to. The addon is only
+// released once all of them are gone; until then, the status lists the remaining objects of each cluster.
+func (r *reconciler) finalize(ctx context.Context, log logr.Logger, addon *v1alpha1.ClusterAddon) error {
+	var (
+		status = v1alpha1.ClusterAddonStatus{}
+		errs   []error
+	)
+	for _, previous := range addon.Status.Clusters {
+		if result, err := r.removeFromCluster(ctx, log, addon, previous); err != nil {
+			status.Clusters = append(status.Clusters, *result)
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+
+	if !apiequality.Semantic.DeepEqual(status, addon.Status) {
+		addon.Status = status
+		if err := r.Client.Status().Update(ctx, addon); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,293 @@
+package clusteraddon
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	kubeceptioninstall "kubeception.cloud/kubeception/pkg/apis/kubeception/install"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/util"
+	"kubeception.cloud/kubeception/pkg/util/controller"
+	clusterapis "sigs.k8s.io/cluster-api/pkg/apis"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestClusterAddon(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ClusterAddon")
+}
+
+// applyRejectingClient rejects server-side apply patches like API servers without server-side apply do.
+type applyRejectingClient struct {
+	client.Client
+}
+
+func (c applyRejectingClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() == types.ApplyPatchType {
+		return &apierrors.StatusError{ErrStatus: metav1.Status{
+			Status: metav1.StatusFailure,
+			Code:   http.StatusUnsupportedMediaType,
+			Reason: metav1.StatusReasonUnsupportedMediaType,
+		}}
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func newReconciler(objects ...runtime.Object) (*reconciler, client.Client) {
+	s := runtime.NewScheme()
+	Expect(scheme.AddToScheme(s)).To(Succeed())
+	Expect(clusterapis.AddToScheme(s)).To(Succeed())
+	kubeceptioninstall.Install(s)
+
+	c := fake.NewFakeClientWithScheme(s, objects...)
+	return &reconciler{
+		recorder:    record.NewFakeRecorder(16),
+		WithClient:  controller.NewWithClient(c),
+		WithScheme:  controller.NewWithScheme(s),
+		WithContext: controller.NewWithContext(context.Background()),
+		WithLog:     controller.NewWithLog(logger),
+	}, c
+}
+
+var _ = Describe("ClusterAddon", func() {
+	Describe("#decodeManifests", func() {
+		It("should decode multiple documents and skip empty ones", func() {
+			objects, err := decodeManifests([]byte(`---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: monitoring
+---
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: prometheus
+  namespace: monitoring
+`))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(objects).To(HaveLen(2))
+			Expect(objectReference(&objects[0])).To(Equal(v1alpha1.ClusterAddonObject{APIVersion: "v1", Kind: "Namespace", Name: "monitoring"}))
+			Expect(objectReference(&objects[1])).To(Equal(v1alpha1.ClusterAddonObject{APIVersion: "v1", Kind: "ServiceAccount", Namespace: "monitoring", Name: "prometheus"}))
+		})
+
+		It("should decode JSON and flatten lists", func() {
+			objects, err := decodeManifests([]byte(`{"apiVersion": "v1", "kind": "List", "items": [
+  {"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "a", "namespace": "default"}},
+  {"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "b", "namespace": "default"}}
+]}`))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(objects).To(HaveLen(2))
+			Expect(objects[0].GetName()).To(Equal("a"))
+			Expect(objects[1].GetName()).To(Equal("b"))
+		})
+
+		It("should reject manifests without name", func() {
+			_, err := decodeManifests([]byte("apiVersion: v1\nkind: Namespace\n"))
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("#decodeSourceData", func() {
+		It("should decode the keys in order", func() {
+			objects, err := decodeSourceData(map[string][]byte{
+				"b.yaml": []byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: b\n"),
+				"a.yaml": []byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: a\n"),
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(objects).To(HaveLen(2))
+			Expect(objects[0].GetName()).To(Equal("a"))
+			Expect(objects[1].GetName()).To(Equal("b"))
+		})
+	})
+
+	Describe("#objectsToPrune", func() {
+		It("should return the objects that are not applied anymore", func() {
+			var (
+				namespace  = v1alpha1.ClusterAddonObject{APIVersion: "v1", Kind: "Namespace", Name: "monitoring"}
+				deployment = v1alpha1.ClusterAddonObject{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "monitoring", Name: "prometheus"}
+			)
+
+			Expect(objectsToPrune([]v1alpha1.ClusterAddonObject{namespace, deployment}, []v1alpha1.ClusterAddonObject{namespace})).To(ConsistOf(deployment))
+			Expect(objectsToPrune(nil, []v1alpha1.ClusterAddonObject{namespace})).To(BeEmpty())
+		})
+
+		It("should ignore the version of the objects", func() {
+			var (
+				old     = v1alpha1.ClusterAddonObject{APIVersion: "apps/v1beta2", Kind: "Deployment", Namespace: "monitoring", Name: "prometheus"}
+				current = v1alpha1.ClusterAddonObject{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "monitoring", Name: "prometheus"}
+			)
+
+			Expect(objectsToPrune([]v1alpha1.ClusterAddonObject{old}, []v1alpha1.ClusterAddonObject{current})).To(BeEmpty())
+		})
+	})
+
+	Describe("#applyObject", func() {
+		var ctx context.Context
+		BeforeEach(func() {
+			ctx = context.Background()
+		})
+
+		configMap := func(value string) *unstructured.Unstructured {
+			objects, err := decodeManifests([]byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: foo\n  namespace: default\ndata:\n  key: " + value + "\n"))
+			Expect(err).NotTo(HaveOccurred())
+			return &objects[0]
+		}
+
+		It("should create and patch the object if the API server does not support server-side apply", func() {
+			guest := applyRejectingClient{fake.NewFakeClientWithScheme(scheme.Scheme)}
+
+			Expect(applyObject(ctx, guest, configMap("a"))).To(Succeed())
+			actual := &corev1.ConfigMap{}
+			Expect(guest.Get(ctx, util.Key("default", "foo"), actual)).To(Succeed())
+			Expect(actual.Data).To(Equal(map[string]string{"key": "a"}))
+
+			actual.Labels = map[string]string{"foo": "bar"}
+			Expect(guest.Update(ctx, actual)).To(Succeed())
+
+			Expect(applyObject(ctx, guest, configMap("b"))).To(Succeed())
+			actual = &corev1.ConfigMap{}
+			Expect(guest.Get(ctx, util.Key("default", "foo"), actual)).To(Succeed())
+			Expect(actual.Data).To(Equal(map[string]string{"key": "b"}))
+			Expect(actual.Labels).To(Equal(map[string]string{"foo": "bar"}))
+		})
+	})
+
+	Describe("#Reconcile", func() {
+		var (
+			ctx   context.Context
+			addon *v1alpha1.ClusterAddon
+		)
+		BeforeEach(func() {
+			ctx = context.Background()
+			addon = &v1alpha1.ClusterAddon{ObjectMeta: util.ObjectMeta("default", "monitoring")}
+		})
+
+		getAddon := func(c client.Client) *v1alpha1.ClusterAddon {
+			actual := &v1alpha1.ClusterAddon{}
+			Expect(c.Get(ctx, util.KeyFromObject(addon), actual)).To(Succeed())
+			return actual
+		}
+
+		It("should add the finalizer", func() {
+			r, c := newReconciler(addon)
+
+			_, err := r.Reconcile(util.Request(addon.Namespace, addon.Name))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(getAddon(c).Finalizers).To(ConsistOf(FinalizerName))
+		})
+
+		Context("when the addon is deleted", func() {
+			var objects []v1alpha1.ClusterAddonObject
+			BeforeEach(func() {
+				now := metav1.Now()
+				addon.DeletionTimestamp = &now
+				addon.Finalizers = []string{FinalizerName}
+				objects = []v1alpha1.ClusterAddonObject{
+					{APIVersion: "v1", Kind: "Namespace", Name: "monitoring"},
+					{APIVersion: "v1", Kind: "ServiceAccount", Namespace: "monitoring", Name: "prometheus"},
+				}
+				addon.Status.Clusters = []v1alpha1.ClusterAddonClusterStatus{
+					{Name: "foo", Phase: v1alpha1.ClusterAddonApplied, Objects: objects},
+				}
+			})
+
+			It("should release the addon if the cluster is gone", func() {
+				r, c := newReconciler(addon)
+
+				_, err := r.Reconcile(util.Request(addon.Namespace, addon.Name))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(getAddon(c).Finalizers).To(BeEmpty())
+			})
+
+			It("should release the addon if the cluster is being deleted along with its kubeconfig", func() {
+				cluster := &clusterv1alpha1.Cluster{ObjectMeta: util.ObjectMeta("default", "foo")}
+				now := metav1.Now()
+				cluster.DeletionTimestamp = &now
+				r, c := newReconciler(addon, cluster)
+
+				_, err := r.Reconcile(util.Request(addon.Namespace, addon.Name))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(getAddon(c).Finalizers).To(BeEmpty())
+			})
+
+			It("should keep the addon and report the failure if the cluster cannot be reached", func() {
+				cluster := &clusterv1alpha1.Cluster{ObjectMeta: util.ObjectMeta("default", "foo")}
+				r, c := newReconciler(addon, cluster)
+
+				_, err := r.Reconcile(util.Request(addon.Namespace, addon.Name))
+				Expect(err).To(HaveOccurred())
+
+				actual := getAddon(c)
+				Expect(actual.Finalizers).To(ConsistOf(FinalizerName))
+				Expect(actual.Status.Clusters).To(HaveLen(1))
+				Expect(actual.Status.Clusters[0].Phase).To(Equal(v1alpha1.ClusterAddonFailed))
+				Expect(actual.Status.Clusters[0].Message).To(ContainSubstring("could not create client"))
+				Expect(actual.Status.Clusters[0].Objects).To(Equal(objects))
+			})
+		})
+
+		Context("when a cluster is not selected anymore", func() {
+			var (
+				cluster *clusterv1alpha1.Cluster
+				objects []v1alpha1.ClusterAddonObject
+			)
+			BeforeEach(func() {
+				cluster = &clusterv1alpha1.Cluster{ObjectMeta: util.ObjectMeta("default", "foo")}
+				cluster.Labels = map[string]string{"monitoring": "true"}
+				addon.Finalizers = []string{FinalizerName}
+				addon.Spec.ClusterSelector = metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "true"}}
+				objects = []v1alpha1.ClusterAddonObject{
+					{APIVersion: "v1", Kind: "Namespace", Name: "monitoring"},
+					{APIVersion: "v1", Kind: "ServiceAccount", Namespace: "monitoring", Name: "prometheus"},
+				}
+				addon.Status.Clusters = []v1alpha1.ClusterAddonClusterStatus{
+					{Name: "foo", Phase: v1alpha1.ClusterAddonApplied, Objects: objects},
+				}
+			})
+
+			It("should keep the status of the cluster until the objects are removed from it", func() {
+				r, c := newReconciler(addon, cluster)
+
+				cluster.Labels["monitoring"] = "false"
+				Expect(c.Update(ctx, cluster)).To(Succeed())
+
+				result, err := r.Reconcile(util.Request(addon.Namespace, addon.Name))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).To(Equal(RetryInterval))
+
+				actual := getAddon(c)
+				Expect(actual.Status.Clusters).To(HaveLen(1))
+				Expect(actual.Status.Clusters[0].Name).To(Equal("foo"))
+				Expect(actual.Status.Clusters[0].Phase).To(Equal(v1alpha1.ClusterAddonFailed))
+				Expect(actual.Status.Clusters[0].Message).To(ContainSubstring("could not create client"))
+				Expect(actual.Status.Clusters[0].Objects).To(Equal(objects))
+
+				Expect(c.Delete(ctx, cluster)).To(Succeed())
+
+				result, err = r.Reconcile(util.Request(addon.Namespace, addon.Name))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).To(Equal(ReapplyInterval))
+				Expect(getAddon(c).Status.Clusters).To(BeEmpty())
+			})
+		})
+	})
+})
@@ -0,0 +1,118 @@
+package clusteraddon
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+)
+
+// decodeManifests decodes the YAML or JSON documents in the given data. Empty documents are skipped and the items
+// of lists are returned in place of the list.
+func decodeManifests(data []byte) ([]unstructured.Unstructured, error) {
+	var (
+		decoder = yaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+		objects []unstructured.Unstructured
+	)
+	for {
+		obj := unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err != nil {
+			if err == io.EOF {
+				return objects, nil
+			}
+			return nil, err
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+
+		items := []unstructured.Unstructured{obj}
+		if obj.IsList() {
+			list, err := obj.ToList()
+			if err != nil {
+				return nil, err
+			}
+			items = list.Items
+		}
+
+		for _, item := range items {
+			if item.GetAPIVersion() == "" || item.GetKind() == "" || item.GetName() == "" {
+				return nil, fmt.Errorf("manifest is missing apiVersion, kind or name")
+			}
+			objects = append(objects, item)
+		}
+	}
+}
+
+// decodeSourceData decodes the manifests of all keys of the given source data in the order of the keys.
+func decodeSourceData(data map[string][]byte) ([]unstructured.Unstructured, error) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var objects []unstructured.Unstructured
+	for _, key := range keys {
+		decoded, err := decodeManifests(data[key])
+		if err != nil {
+			return nil, fmt.Errorf("could not decode %s: %v", key, err)
+		}
+		objects = append(objects, decoded...)
+	}
+	return objects, nil
+}
+
+// objectReference returns the reference to the given object.
+func objectReference(obj *unstructured.Unstructured) v1alpha1.ClusterAddonObject {
+	return v1alpha1.ClusterAddonObject{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}
+}
+
+// objectFromReference returns an object that only carries the identity of the given reference.
+func objectFromReference(ref v1alpha1.ClusterAddonObject) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(ref.APIVersion)
+	obj.SetKind(ref.Kind)
+	obj.SetNamespace(ref.Namespace)
+	obj.SetName(ref.Name)
+	return obj
+}
+
+// sameObject reports whether both references point to the same object. The version is ignored, as the same object
+// is served in all versions of its group.
+func sameObject(a, b v1alpha1.ClusterAddonObject) bool {
+	groupA, errA := schema.ParseGroupVersion(a.APIVersion)
+	groupB, errB := schema.ParseGroupVersion(b.APIVersion)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return groupA.Group == groupB.Group && a.Kind == b.Kind && a.Namespace == b.Namespace && a.Name == b.Name
+}
+
+// objectsToPrune returns the previously applied objects that are not part of the currently applied ones.
+func objectsToPrune(previous, current []v1alpha1.ClusterAddonObject) []v1alpha1.ClusterAddonObject {
+	var prune []v1alpha1.ClusterAddonObject
+	for _, p := range previous {
+		found := false
+		for _, c := range current {
+			if sameObject(p, c) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			prune = append(prune, p)
+		}
+	}
+	return prune
+}
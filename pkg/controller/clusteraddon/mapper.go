@@ -0,0 +1,102 @@
+package clusteraddon
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/util"
+	"kubeception.cloud/kubeception/pkg/util/controller"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// sourceReference returns the reference of the given source if it is of the kind of a mapper, otherwise nil.
+type sourceReference func(source v1alpha1.ClusterAddonSource) *corev1.LocalObjectReference
+
+func configMapSource(source v1alpha1.ClusterAddonSource) *corev1.LocalObjectReference {
+	return source.ConfigMap
+}
+
+func secretSource(source v1alpha1.ClusterAddonSource) *corev1.LocalObjectReference {
+	return source.Secret
+}
+
+type clusterToClusterAddonMapper struct {
+	controller.WithClient
+	controller.WithLog
+	controller.WithContext
+}
+
+func NewClusterToClusterAddonMapper() handler.Mapper {
+	return &clusterToClusterAddonMapper{WithLog: controller.NewWithLog(logger.WithName("cluster-mapper"))}
+}
+
+func (m *clusterToClusterAddonMapper) doMap(mapObject handler.MapObject) ([]reconcile.Request, error) {
+	addonList := &v1alpha1.ClusterAddonList{}
+	if err := m.Client.List(m.Context, addonList, client.InNamespace(mapObject.Meta.GetNamespace())); err != nil {
+		return nil, err
+	}
+
+	var requests []reconcile.Request
+	for _, addon := range addonList.Items {
+		selector, err := metav1.LabelSelectorAsSelector(&addon.Spec.ClusterSelector)
+		if err != nil {
+			continue
+		}
+		if selector.Matches(labels.Set(mapObject.Meta.GetLabels())) || clusterStatus(&addon.Status, mapObject.Meta.GetName()) != nil {
+			requests = append(requests, util.RequestFromObject(&addon))
+		}
+	}
+	return requests, nil
+}
+
+func (m *clusterToClusterAddonMapper) Map(mapObject handler.MapObject) []reconcile.Request {
+	requests, err := m.doMap(mapObject)
+	if err != nil {
+		m.Log.Error(err, "Could not map cluster", "cluster", util.KeyFromObject(mapObject.Meta).String())
+		return nil
+	}
+
+	return requests
+}
+
+type sourceToClusterAddonMapper struct {
+	reference sourceReference
+	controller.WithClient
+	controller.WithLog
+	controller.WithContext
+}
+
+func NewSourceToClusterAddonMapper(reference sourceReference) handler.Mapper {
+	return &sourceToClusterAddonMapper{reference: reference, WithLog: controller.NewWithLog(logger.WithName("source-mapper"))}
+}
+
+func (m *sourceToClusterAddonMapper) doMap(mapObject handler.MapObject) ([]reconcile.Request, error) {
+	addonList := &v1alpha1.ClusterAddonList{}
+	if err := m.Client.List(m.Context, addonList, client.InNamespace(mapObject.Meta.GetNamespace())); err != nil {
+		return nil, err
+	}
+
+	var requests []reconcile.Request
+	for _, addon := range addonList.Items {
+		for _, source := range addon.Spec.Sources {
+			if ref := m.reference(source); ref != nil && ref.Name == mapObject.Meta.GetName() {
+				requests = append(requests, util.RequestFromObject(&addon))
+				break
+			}
+		}
+	}
+	return requests, nil
+}
+
+func (m *sourceToClusterAddonMapper) Map(mapObject handler.MapObject) []reconcile.Request {
+	requests, err := m.doMap(mapObject)
+	if err != nil {
+		m.Log.Error(err, "Could not map source", "source", util.KeyFromObject(mapObject.Meta).String())
+		return nil
+	}
+
+	return requests
+}
@@ -0,0 +1,19 @@
+package common
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// DeploymentReady checks whether the given Deployment has been observed by its controller and all of its
+// replicas are updated and available, with no old replicas left.
+func DeploymentReady(deployment *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	return deployment.Status.ObservedGeneration >= deployment.Generation &&
+		deployment.Status.Replicas == replicas &&
+		deployment.Status.UpdatedReplicas == replicas &&
+		deployment.Status.AvailableReplicas == replicas
+}
@@ -0,0 +1,124 @@
+package common
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+)
+
+const (
+	// HyperkubeRepository is the default repository of the hyperkube image.
+	HyperkubeRepository = "k8s.gcr.io/hyperkube"
+	// ETCDRepository is the default repository of the etcd image.
+	ETCDRepository = "quay.io/coreos/etcd"
+	// ETCDTag is the default tag of the etcd image.
+	ETCDTag = "v3.3.12"
+	// KubeletRepository is the default repository of the image of the machines.
+	KubeletRepository = "adracus/dind-kubelet"
+	// CoreDNSRepository is the default repository of the CoreDNS image.
+	CoreDNSRepository = "coredns/coredns"
+	// CoreDNSTag is the default tag of the CoreDNS image.
+	CoreDNSTag = "1.3.1"
+	// FlannelRepository is the default repository of the flannel image.
+	FlannelRepository = "quay.io/coreos/flannel"
+	// FlannelTag is the default tag of the flannel image.
+	FlannelTag = "v0.11.0-amd64"
+)
+
+var (
+	// DefaultImages are the images of clusters that do not override them. They are configured via the image
+	// flags of the manager.
+	DefaultImages = v1alpha1.Images{
+		Hyperkube: &v1alpha1.Image{Repository: HyperkubeRepository},
+		ETCD:      &v1alpha1.Image{Repository: ETCDRepository, Tag: ETCDTag},
+		Kubelet:   &v1alpha1.Image{Repository: KubeletRepository},
+	}
+	// DefaultCoreDNSImage is the CoreDNS image of clusters that do not override it. It is configured via the
+	// image flags of the manager.
+	DefaultCoreDNSImage = v1alpha1.Image{Repository: CoreDNSRepository, Tag: CoreDNSTag}
+	// DefaultFlannelImage is the flannel image of clusters that do not override it. It is configured via the
+	// image flags of the manager.
+	DefaultFlannelImage = v1alpha1.Image{Repository: FlannelRepository, Tag: FlannelTag}
+	// DefaultImagePullSecrets are the names of the secrets used to pull the images of all clusters. The secrets
+	// have to exist in the namespace of each cluster.
+	DefaultImagePullSecrets []string
+)
+
+// imageReference renders the reference of an image from the given default and override. The fields of the
+// override take precedence. A tag overriding the default one also overrides the default digest. If no tag is
+// set, the given default tag is used.
+func imageReference(defaultImage, override *v1alpha1.Image, defaultTag string) string {
+	repository, tag, digest := defaultImage.Repository, defaultImage.Tag, defaultImage.Digest
+	if override != nil {
+		if override.Repository != "" {
+			repository = override.Repository
+		}
+		if override.Tag != "" {
+			tag, digest = override.Tag, ""
+		}
+		if override.Digest != "" {
+			digest = override.Digest
+		}
+	}
+
+	if digest != "" {
+		return fmt.Sprintf("%s@%s", repository, digest)
+	}
+	if tag == "" {
+		tag = defaultTag
+	}
+	return fmt.Sprintf("%s:%s", repository, tag)
+}
+
+// HyperkubeImage returns the hyperkube image of the given Kubernetes version with the given overrides.
+func HyperkubeImage(images *v1alpha1.Images, version string) string {
+	var override *v1alpha1.Image
+	if images != nil {
+		override = images.Hyperkube
+	}
+	return imageReference(DefaultImages.Hyperkube, override, version)
+}
+
+// ETCDImage returns the etcd image with the given overrides.
+func ETCDImage(images *v1alpha1.Images) string {
+	var override *v1alpha1.Image
+	if images != nil {
+		override = images.ETCD
+	}
+	return imageReference(DefaultImages.ETCD, override, ETCDTag)
+}
+
+// KubeletImage returns the image of the machines of the given Kubernetes version with the given overrides.
+func KubeletImage(images *v1alpha1.Images, version string) string {
+	var override *v1alpha1.Image
+	if images != nil {
+		override = images.Kubelet
+	}
+	return imageReference(DefaultImages.Kubelet, override, version)
+}
+
+// CoreDNSImage returns the CoreDNS image with the given override. The image is pulled by the machines of the
+// cluster, so the image pull secrets do not apply to it.
+func CoreDNSImage(override *v1alpha1.Image) string {
+	return imageReference(&DefaultCoreDNSImage, override, CoreDNSTag)
+}
+
+// FlannelImage returns the flannel image with the given override. Like the CoreDNS image, it is pulled by the
+// machines of the cluster.
+func FlannelImage(override *v1alpha1.Image) string {
+	return imageReference(&DefaultFlannelImage, override, FlannelTag)
+}
+
+// ImagePullSecrets returns the secrets used to pull the images with the given overrides: the default image pull
+// secrets followed by the ones of the overrides.
+func ImagePullSecrets(images *v1alpha1.Images) []corev1.LocalObjectReference {
+	var secrets []corev1.LocalObjectReference
+	for _, name := range DefaultImagePullSecrets {
+		secrets = append(secrets, corev1.LocalObjectReference{Name: name})
+	}
+	if images != nil {
+		secrets = append(secrets, images.ImagePullSecrets...)
+	}
+	return secrets
+}
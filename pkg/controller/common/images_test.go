@@ -0,0 +1,51 @@
+package common
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+)
+
+func TestCommon(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Common")
+}
+
+var _ = Describe("Images Suite", func() {
+	Describe("#HyperkubeImage", func() {
+		It("should tag the default image with the Kubernetes version", func() {
+			Expect(HyperkubeImage(nil, "v1.14.1")).To(Equal("k8s.gcr.io/hyperkube:v1.14.1"))
+		})
+
+		It("should apply the overrides", func() {
+			images := &v1alpha1.Images{Hyperkube: &v1alpha1.Image{Repository: "registry.example.com/hyperkube"}}
+			Expect(HyperkubeImage(images, "v1.14.1")).To(Equal("registry.example.com/hyperkube:v1.14.1"))
+
+			images.Hyperkube.Digest = "sha256:abc"
+			Expect(HyperkubeImage(images, "v1.14.1")).To(Equal("registry.example.com/hyperkube@sha256:abc"))
+		})
+	})
+
+	Describe("#imageReference", func() {
+		It("should drop the default digest if the tag is overridden", func() {
+			defaultImage := &v1alpha1.Image{Repository: "etcd", Tag: "v3.3.12", Digest: "sha256:abc"}
+
+			Expect(imageReference(defaultImage, nil, "")).To(Equal("etcd@sha256:abc"))
+			Expect(imageReference(defaultImage, &v1alpha1.Image{Tag: "v3.3.13"}, "")).To(Equal("etcd:v3.3.13"))
+		})
+	})
+
+	Describe("#ImagePullSecrets", func() {
+		It("should combine the default and the overridden image pull secrets", func() {
+			defer func(secrets []string) { DefaultImagePullSecrets = secrets }(DefaultImagePullSecrets)
+			DefaultImagePullSecrets = []string{"default"}
+
+			Expect(ImagePullSecrets(&v1alpha1.Images{
+				ImagePullSecrets: []corev1.LocalObjectReference{{Name: "cluster"}},
+			})).To(Equal([]corev1.LocalObjectReference{{Name: "default"}, {Name: "cluster"}}))
+		})
+	})
+})
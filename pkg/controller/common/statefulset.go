@@ -0,0 +1,57 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	// ImmutableSpecHashAnnotation is the annotation that stores the hash of the immutable fields of a StatefulSet spec.
+	ImmutableSpecHashAnnotation = fmt.Sprintf("%s/immutable-spec-hash", LabelPrefix)
+)
+
+// StatefulSetImmutableSpecHash computes a hash over all fields of the given StatefulSet spec that cannot be updated.
+func StatefulSetImmutableSpecHash(spec *appsv1.StatefulSetSpec) (string, error) {
+	data, err := json.Marshal(struct {
+		Selector             *metav1.LabelSelector          `json:"selector"`
+		ServiceName          string                         `json:"serviceName"`
+		PodManagementPolicy  appsv1.PodManagementPolicyType `json:"podManagementPolicy"`
+		VolumeClaimTemplates []corev1.PersistentVolumeClaim `json:"volumeClaimTemplates"`
+	}{
+		Selector:             spec.Selector,
+		ServiceName:          spec.ServiceName,
+		PodManagementPolicy:  spec.PodManagementPolicy,
+		VolumeClaimTemplates: spec.VolumeClaimTemplates,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// StatefulSetNeedsRecreation checks whether the existing StatefulSet was created with different immutable fields
+// than the ones of the given hash. StatefulSets without a hash annotation always need to be recreated.
+func StatefulSetNeedsRecreation(existing *appsv1.StatefulSet, hash string) bool {
+	return existing.Annotations[ImmutableSpecHashAnnotation] != hash
+}
+
+// StatefulSetReady checks whether the given StatefulSet has been observed by its controller and all of its
+// replicas are updated and ready.
+func StatefulSetReady(statefulSet *appsv1.StatefulSet) bool {
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+
+	return statefulSet.Status.ObservedGeneration >= statefulSet.Generation &&
+		statefulSet.Status.UpdatedReplicas == replicas &&
+		statefulSet.Status.ReadyReplicas == replicas
+}
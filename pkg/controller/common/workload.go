@@ -0,0 +1,62 @@
+package common
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+)
+
+// ApplyWorkloadSettings applies the given workload settings to the given pod spec. The resources are set on the
+// main container, which is the first container of the pod. The node and pod affinities of the settings replace
+// the ones of the pod spec, the terms of their pod anti-affinity are added to the ones of the pod spec.
+func ApplyWorkloadSettings(spec *corev1.PodSpec, settings *v1alpha1.WorkloadSettings) {
+	if len(spec.Containers) > 0 {
+		spec.Containers[0].Resources = *settings.Resources.DeepCopy()
+	}
+
+	if settings.NodeSelector != nil {
+		spec.NodeSelector = make(map[string]string, len(settings.NodeSelector))
+		for key, value := range settings.NodeSelector {
+			spec.NodeSelector[key] = value
+		}
+	}
+
+	for _, toleration := range settings.Tolerations {
+		spec.Tolerations = append(spec.Tolerations, *toleration.DeepCopy())
+	}
+
+	if settings.PriorityClassName != "" {
+		spec.PriorityClassName = settings.PriorityClassName
+	}
+
+	spec.Affinity = mergeAffinity(spec.Affinity, settings.Affinity)
+}
+
+// mergeAffinity merges the given affinity override into the given affinity.
+func mergeAffinity(affinity, override *corev1.Affinity) *corev1.Affinity {
+	if override == nil {
+		return affinity
+	}
+	if affinity == nil {
+		return override.DeepCopy()
+	}
+
+	merged := affinity.DeepCopy()
+	if override.NodeAffinity != nil {
+		merged.NodeAffinity = override.NodeAffinity.DeepCopy()
+	}
+	if override.PodAffinity != nil {
+		merged.PodAffinity = override.PodAffinity.DeepCopy()
+	}
+	if override.PodAntiAffinity != nil {
+		if merged.PodAntiAffinity == nil {
+			merged.PodAntiAffinity = &corev1.PodAntiAffinity{}
+		}
+		for _, term := range override.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+			merged.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(merged.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, *term.DeepCopy())
+		}
+		for _, term := range override.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+			merged.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(merged.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution, *term.DeepCopy())
+		}
+	}
+	return merged
+}
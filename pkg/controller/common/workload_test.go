@@ -0,0 +1,84 @@
+package common
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+)
+
+var _ = Describe("Workload Suite", func() {
+	Describe("#ApplyWorkloadSettings", func() {
+		var (
+			defaultTerm corev1.WeightedPodAffinityTerm
+			spec        *corev1.PodSpec
+		)
+
+		BeforeEach(func() {
+			defaultTerm = corev1.WeightedPodAffinityTerm{
+				Weight:          100,
+				PodAffinityTerm: corev1.PodAffinityTerm{TopologyKey: "kubernetes.io/hostname"},
+			}
+			spec = &corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "main"}},
+				Affinity: &corev1.Affinity{
+					PodAntiAffinity: &corev1.PodAntiAffinity{
+						PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{defaultTerm},
+					},
+				},
+			}
+		})
+
+		It("should keep the pod spec without settings", func() {
+			expected := spec.DeepCopy()
+
+			ApplyWorkloadSettings(spec, &v1alpha1.WorkloadSettings{})
+
+			Expect(spec).To(Equal(expected))
+		})
+
+		It("should apply the resources and scheduling constraints", func() {
+			resources := corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+			}
+			toleration := corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpExists}
+
+			ApplyWorkloadSettings(spec, &v1alpha1.WorkloadSettings{
+				Resources:         resources,
+				NodeSelector:      map[string]string{"pool": "control-planes"},
+				Tolerations:       []corev1.Toleration{toleration},
+				PriorityClassName: "control-plane",
+			})
+
+			Expect(spec.Containers[0].Resources).To(Equal(resources))
+			Expect(spec.NodeSelector).To(Equal(map[string]string{"pool": "control-planes"}))
+			Expect(spec.Tolerations).To(ConsistOf(toleration))
+			Expect(spec.PriorityClassName).To(Equal("control-plane"))
+		})
+
+		It("should add the pod anti-affinity terms to the default ones", func() {
+			nodeAffinity := &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+						MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "pool", Operator: corev1.NodeSelectorOpExists}},
+					}},
+				},
+			}
+			term := corev1.PodAffinityTerm{TopologyKey: "failure-domain.beta.kubernetes.io/zone"}
+
+			ApplyWorkloadSettings(spec, &v1alpha1.WorkloadSettings{
+				Affinity: &corev1.Affinity{
+					NodeAffinity: nodeAffinity,
+					PodAntiAffinity: &corev1.PodAntiAffinity{
+						RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{term},
+					},
+				},
+			})
+
+			Expect(spec.Affinity.NodeAffinity).To(Equal(nodeAffinity))
+			Expect(spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution).To(ConsistOf(defaultTerm))
+			Expect(spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution).To(ConsistOf(term))
+		})
+	})
+})
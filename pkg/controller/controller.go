@@ -3,6 +3,8 @@ package controller
 import (
 	"kubeception.cloud/kubeception/pkg/controller/certificate"
 	"kubeception.cloud/kubeception/pkg/controller/cluster"
+	"kubeception.cloud/kubeception/pkg/controller/clusteraddon"
+	"kubeception.cloud/kubeception/pkg/controller/etcdbackup"
 	"kubeception.cloud/kubeception/pkg/controller/machine"
 	"kubeception.cloud/kubeception/pkg/util"
 )
@@ -12,6 +14,8 @@ var (
 		cluster.AddToManager,
 		machine.AddToManager,
 		certificate.AddToManager,
+		etcdbackup.AddToManager,
+		clusteraddon.AddToManager,
 	)
 
 	// AddToManager adds all kubeception controllers to the given manager.
@@ -0,0 +1,16 @@
+package etcdbackup
+
+import (
+	"kubeception.cloud/kubeception/pkg/controller/etcdbackup/backup"
+	"kubeception.cloud/kubeception/pkg/controller/etcdbackup/schedule"
+	"kubeception.cloud/kubeception/pkg/util"
+)
+
+var (
+	addToManagerBuilder = util.NewAddToManagerBuilder(
+		backup.AddToManager,
+		schedule.AddToManager,
+	)
+
+	AddToManager = addToManagerBuilder.AddToManager
+)
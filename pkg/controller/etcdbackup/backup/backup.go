@@ -0,0 +1,223 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/helper"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/controller/common"
+	"kubeception.cloud/kubeception/pkg/util"
+	"kubeception.cloud/kubeception/pkg/util/controller"
+	"kubeception.cloud/kubeception/pkg/util/finalizer"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+const (
+	FinalizerName = common.LabelPrefix + "/etcd-backup"
+
+	EventSnapshotStarted   = "SnapshotStarted"
+	EventSnapshotSucceeded = "SnapshotSucceeded"
+	EventSnapshotFailed    = "SnapshotFailed"
+	EventCleanupFailed     = "CleanupFailed"
+
+	// CleanupRequeueAfter is the duration after which the finalization of a backup is retried while its
+	// snapshot is being removed.
+	CleanupRequeueAfter = 10 * time.Second
+)
+
+var logger = log.Log.WithName("etcdbackup")
+
+// errCleanupPending signals that the snapshot of a backup is still being removed.
+var errCleanupPending = errors.New("snapshot cleanup pending")
+
+type reconciler struct {
+	recorder record.EventRecorder
+	controller.WithClient
+	controller.WithScheme
+	controller.WithContext
+	controller.WithLog
+}
+
+func NewReconciler(recorder record.EventRecorder) reconcile.Reconciler {
+	return &reconciler{recorder: recorder, WithLog: controller.NewWithLog(logger)}
+}
+
+func (r *reconciler) Reconcile(req reconcile.Request) (reconcile.Result, error) {
+	log := r.Log.WithValues("etcdbackup", req.String())
+	backup := &v1alpha1.EtcdBackup{}
+	if err := r.Client.Get(r.Context, req.NamespacedName, backup); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+
+	err := finalizer.Handle(r.Context, r.Client, FinalizerName, backup, finalizer.Funcs{
+		ReconcileFunc: func() error {
+			return r.reconcile(r.Context, log, backup)
+		},
+		FinalizeFunc: func() error {
+			return r.finalize(r.Context, log, backup)
+		},
+	})
+	if err == errCleanupPending {
+		return reconcile.Result{RequeueAfter: CleanupRequeueAfter}, nil
+	}
+	return reconcile.Result{}, err
+}
+
+func (r *reconciler) updateStatus(ctx context.Context, backup *v1alpha1.EtcdBackup, f func(status *v1alpha1.EtcdBackupStatus)) error {
+	f(&backup.Status)
+	return r.Client.Status().Update(ctx, backup)
+}
+
+func (r *reconciler) fail(ctx context.Context, backup *v1alpha1.EtcdBackup, message string) error {
+	r.recorder.Event(backup, corev1.EventTypeWarning, EventSnapshotFailed, message)
+	return r.updateStatus(ctx, backup, func(status *v1alpha1.EtcdBackupStatus) {
+		status.Phase = v1alpha1.EtcdBackupFailed
+		status.Message = message
+	})
+}
+
+// getOrCreateJob creates the given job if it does not exist yet and reads its current state into it.
+func (r *reconciler) getOrCreateJob(ctx context.Context, job *batchv1.Job) error {
+	if err := r.Client.Create(ctx, job); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+		return r.Client.Get(ctx, util.KeyFromObject(job), job)
+	}
+	return nil
+}
+
+func (r *reconciler) deleteJob(ctx context.Context, namespace, name string) error {
+	job := &batchv1.Job{ObjectMeta: util.ObjectMeta(namespace, name)}
+	return client.IgnoreNotFound(r.Client.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)))
+}
+
+// terminationMessage returns the termination message of the succeeded pod of the given job and the node it ran on.
+func (r *reconciler) terminationMessage(ctx context.Context, job *batchv1.Job) (string, string, error) {
+	podList := &corev1.PodList{}
+	if err := r.Client.List(ctx, podList, client.InNamespace(job.Namespace), client.MatchingLabels(job.Spec.Selector.MatchLabels)); err != nil {
+		return "", "", err
+	}
+
+	for _, pod := range podList.Items {
+		if pod.Status.Phase != corev1.PodSucceeded {
+			continue
+		}
+
+		for _, status := range pod.Status.ContainerStatuses {
+			if terminated := status.State.Terminated; terminated != nil && terminated.Message != "" {
+				return terminated.Message, pod.Spec.NodeName, nil
+			}
+		}
+	}
+	return "", "", fmt.Errorf("no succeeded pod with termination message found for job %s", job.Name)
+}
+
+func (r *reconciler) reconcile(ctx context.Context, log logr.Logger, backup *v1alpha1.EtcdBackup) error {
+	switch backup.Status.Phase {
+	case v1alpha1.EtcdBackupSucceeded, v1alpha1.EtcdBackupFailed:
+		return r.deleteJob(ctx, backup.Namespace, snapshotJobName(backup))
+	}
+
+	if err := validateStorage(&backup.Spec.Storage); err != nil {
+		return r.fail(ctx, backup, err.Error())
+	}
+
+	cluster := &clusterv1alpha1.Cluster{}
+	if err := r.Client.Get(ctx, util.Key(backup.Namespace, backup.Spec.Cluster.Name), cluster); err != nil {
+		if apierrors.IsNotFound(err) {
+			return r.fail(ctx, backup, fmt.Sprintf("cluster %s not found", backup.Spec.Cluster.Name))
+		}
+		return err
+	}
+
+	if backup.Status.Phase == "" || backup.Status.Phase == v1alpha1.EtcdBackupPending {
+		log.Info("Starting snapshot")
+		r.recorder.Event(backup, corev1.EventTypeNormal, EventSnapshotStarted, "Starting etcd snapshot")
+		return r.updateStatus(ctx, backup, func(status *v1alpha1.EtcdBackupStatus) {
+			status.Phase = v1alpha1.EtcdBackupRunning
+			status.Path = SnapshotPath(backup)
+		})
+	}
+
+	config, err := helper.LoadClusterConfig(cluster.Spec.ProviderSpec.Value.Raw)
+	if err != nil {
+		return r.fail(ctx, backup, fmt.Sprintf("could not load configuration of cluster %s: %v", cluster.Name, err))
+	}
+
+	job := snapshotJob(backup, cluster, config.Images)
+	if err := r.getOrCreateJob(ctx, job); err != nil {
+		return err
+	}
+
+	finished, failed, message := jobFinished(job)
+	if !finished {
+		return nil
+	}
+	if failed {
+		return r.fail(ctx, backup, fmt.Sprintf("snapshot job failed: %s", message))
+	}
+
+	message, nodeName, err := r.terminationMessage(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := parseSnapshotStatus(message)
+	if err != nil {
+		return r.fail(ctx, backup, err.Error())
+	}
+
+	log.Info("Snapshot succeeded", "revision", snapshot.Revision, "size", snapshot.TotalSize)
+	r.recorder.Eventf(backup, corev1.EventTypeNormal, EventSnapshotSucceeded, "Took snapshot at revision %d (%d bytes)", snapshot.Revision, snapshot.TotalSize)
+	now := metav1.Now()
+	return r.updateStatus(ctx, backup, func(status *v1alpha1.EtcdBackupStatus) {
+		status.Phase = v1alpha1.EtcdBackupSucceeded
+		status.NodeName = nodeName
+		status.Size = snapshot.TotalSize
+		status.Revision = snapshot.Revision
+		status.SnapshotTime = &now
+		status.Message = ""
+	})
+}
+
+// finalize removes the snapshot jobs and the snapshot of the backup. As a failing removal must not block the
+// deletion of the backup forever, a failed cleanup job is only reported as event.
+func (r *reconciler) finalize(ctx context.Context, log logr.Logger, backup *v1alpha1.EtcdBackup) error {
+	if err := r.deleteJob(ctx, backup.Namespace, snapshotJobName(backup)); err != nil {
+		return err
+	}
+
+	if backup.Status.Path == "" || validateStorage(&backup.Spec.Storage) != nil {
+		return nil
+	}
+
+	job := cleanupJob(backup)
+	if err := r.getOrCreateJob(ctx, job); err != nil {
+		return err
+	}
+
+	finished, failed, message := jobFinished(job)
+	if !finished {
+		return errCleanupPending
+	}
+	if failed {
+		r.recorder.Eventf(backup, corev1.EventTypeWarning, EventCleanupFailed, "Could not remove snapshot %s: %s", backup.Status.Path, message)
+	} else {
+		log.Info("Removed snapshot", "path", backup.Status.Path)
+	}
+
+	return r.deleteJob(ctx, backup.Namespace, job.Name)
+}
@@ -0,0 +1,314 @@
+package backup
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	kubeceptioninstall "kubeception.cloud/kubeception/pkg/apis/kubeception/install"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/util"
+	"kubeception.cloud/kubeception/pkg/util/controller"
+	clusterapis "sigs.k8s.io/cluster-api/pkg/apis"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestBackup(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Backup")
+}
+
+const namespace = "default"
+
+func newScheme() *runtime.Scheme {
+	s := runtime.NewScheme()
+	Expect(scheme.AddToScheme(s)).To(Succeed())
+	Expect(clusterapis.AddToScheme(s)).To(Succeed())
+	kubeceptioninstall.Install(s)
+	return s
+}
+
+func newReconciler(c client.Client, s *runtime.Scheme) *reconciler {
+	return &reconciler{
+		recorder:    record.NewFakeRecorder(16),
+		WithClient:  controller.NewWithClient(c),
+		WithScheme:  controller.NewWithScheme(s),
+		WithContext: controller.NewWithContext(context.Background()),
+		WithLog:     controller.NewWithLog(logger),
+	}
+}
+
+func newCluster() *clusterv1alpha1.Cluster {
+	cluster := &clusterv1alpha1.Cluster{ObjectMeta: util.ObjectMeta(namespace, "foo")}
+	cluster.Spec.ProviderSpec.Value = &runtime.RawExtension{Raw: []byte("{}")}
+	return cluster
+}
+
+func newBackup(phase v1alpha1.EtcdBackupPhase) *v1alpha1.EtcdBackup {
+	backup := &v1alpha1.EtcdBackup{
+		ObjectMeta: util.ObjectMeta(namespace, "foo-backup"),
+		Spec: v1alpha1.EtcdBackupSpec{
+			Cluster: corev1.LocalObjectReference{Name: "foo"},
+			Storage: v1alpha1.EtcdBackupStorage{
+				HostPath: &corev1.HostPathVolumeSource{Path: "/var/backups"},
+			},
+		},
+		Status: v1alpha1.EtcdBackupStatus{Phase: phase},
+	}
+	if phase != "" && phase != v1alpha1.EtcdBackupPending {
+		backup.Status.Path = SnapshotPath(backup)
+	}
+	return backup
+}
+
+// finishedJob returns the given job as it is reported by the API server once it finished.
+func finishedJob(job *batchv1.Job, conditionType batchv1.JobConditionType, message string) *batchv1.Job {
+	job.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"job-name": job.Name}}
+	job.Status.Conditions = []batchv1.JobCondition{
+		{Type: conditionType, Status: corev1.ConditionTrue, Message: message},
+	}
+	return job
+}
+
+// succeededPod returns a succeeded pod of the given job that terminated with the given message.
+func succeededPod(job *batchv1.Job, message string) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: util.ObjectMeta(namespace, job.Name+"-abcde")}
+	pod.Labels = job.Spec.Selector.MatchLabels
+	pod.Spec.NodeName = "node-1"
+	pod.Status.Phase = corev1.PodSucceeded
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: message}}},
+	}
+	return pod
+}
+
+var _ = Describe("Backup Suite", func() {
+	var (
+		ctx context.Context
+		s   *runtime.Scheme
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		s = newScheme()
+	})
+
+	reconcileBackup := func(c client.Client, backup *v1alpha1.EtcdBackup) (reconcile.Result, *v1alpha1.EtcdBackup) {
+		result, err := newReconciler(c, s).Reconcile(util.Request(backup.Namespace, backup.Name))
+		Expect(err).NotTo(HaveOccurred())
+
+		actual := &v1alpha1.EtcdBackup{}
+		Expect(c.Get(ctx, util.KeyFromObject(backup), actual)).To(Succeed())
+		return result, actual
+	}
+
+	jobExists := func(c client.Client, name string) bool {
+		err := c.Get(ctx, util.Key(namespace, name), &batchv1.Job{})
+		if apierrors.IsNotFound(err) {
+			return false
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return true
+	}
+
+	Describe("#parseSnapshotStatus", func() {
+		table.DescribeTable("should parse the snapshot status",
+			func(message string, expected *snapshotStatus, expectErr bool) {
+				actual, err := parseSnapshotStatus(message)
+				if expectErr {
+					Expect(err).To(HaveOccurred())
+					return
+				}
+				Expect(err).NotTo(HaveOccurred())
+				Expect(actual).To(Equal(expected))
+			},
+			table.Entry("complete status",
+				`{"hash":1234,"revision":42,"totalKey":7,"totalSize":20480}`,
+				&snapshotStatus{Hash: 1234, Revision: 42, TotalKey: 7, TotalSize: 20480}, false),
+			table.Entry("partial status", `{"revision":42}`, &snapshotStatus{Revision: 42}, false),
+			table.Entry("empty message", "", nil, true),
+			table.Entry("plain text message", "Error: snapshot file not found", nil, true),
+		)
+	})
+
+	Describe("#jobFinished", func() {
+		table.DescribeTable("should report whether the job finished",
+			func(conditions []batchv1.JobCondition, finished, failed bool, message string) {
+				job := &batchv1.Job{Status: batchv1.JobStatus{Conditions: conditions}}
+
+				actualFinished, actualFailed, actualMessage := jobFinished(job)
+				Expect(actualFinished).To(Equal(finished))
+				Expect(actualFailed).To(Equal(failed))
+				Expect(actualMessage).To(Equal(message))
+			},
+			table.Entry("no conditions", nil, false, false, ""),
+			table.Entry("complete",
+				[]batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}, true, false, ""),
+			table.Entry("failed",
+				[]batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "backoff limit exceeded"}},
+				true, true, "backoff limit exceeded"),
+			table.Entry("condition not true",
+				[]batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionFalse}}, false, false, ""),
+		)
+	})
+
+	Describe("#Reconcile", func() {
+		It("should add the finalizer and start the snapshot of a new backup", func() {
+			backup := newBackup("")
+			c := fake.NewFakeClientWithScheme(s, newCluster(), backup)
+
+			_, actual := reconcileBackup(c, backup)
+
+			Expect(actual.Finalizers).To(ConsistOf(FinalizerName))
+			Expect(actual.Status.Phase).To(Equal(v1alpha1.EtcdBackupRunning))
+			Expect(actual.Status.Path).To(Equal("default/foo/foo-backup.db"))
+			Expect(jobExists(c, snapshotJobName(backup))).To(BeFalse())
+		})
+
+		It("should create the snapshot job of a running backup", func() {
+			backup := newBackup(v1alpha1.EtcdBackupRunning)
+			c := fake.NewFakeClientWithScheme(s, newCluster(), backup)
+
+			_, actual := reconcileBackup(c, backup)
+
+			Expect(actual.Status.Phase).To(Equal(v1alpha1.EtcdBackupRunning))
+			job := &batchv1.Job{}
+			Expect(c.Get(ctx, util.Key(namespace, snapshotJobName(backup)), job)).To(Succeed())
+			Expect(job.Labels).To(HaveKeyWithValue(EtcdBackupLabel, backup.Name))
+			Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name:  "SNAPSHOT_FILE",
+				Value: "/backups/default/foo/foo-backup.db",
+			}))
+		})
+
+		It("should record the snapshot once the snapshot job succeeded", func() {
+			backup := newBackup(v1alpha1.EtcdBackupRunning)
+			job := finishedJob(snapshotJob(backup, newCluster(), nil), batchv1.JobComplete, "")
+			pod := succeededPod(job, `{"hash":1234,"revision":42,"totalKey":7,"totalSize":20480}`)
+			c := fake.NewFakeClientWithScheme(s, newCluster(), backup, job, pod)
+
+			_, actual := reconcileBackup(c, backup)
+
+			Expect(actual.Status.Phase).To(Equal(v1alpha1.EtcdBackupSucceeded))
+			Expect(actual.Status.Revision).To(Equal(int64(42)))
+			Expect(actual.Status.Size).To(Equal(int64(20480)))
+			Expect(actual.Status.NodeName).To(Equal("node-1"))
+			Expect(actual.Status.SnapshotTime).NotTo(BeNil())
+		})
+
+		It("should fail the backup if the snapshot status cannot be parsed", func() {
+			backup := newBackup(v1alpha1.EtcdBackupRunning)
+			job := finishedJob(snapshotJob(backup, newCluster(), nil), batchv1.JobComplete, "")
+			pod := succeededPod(job, "not json")
+			c := fake.NewFakeClientWithScheme(s, newCluster(), backup, job, pod)
+
+			_, actual := reconcileBackup(c, backup)
+
+			Expect(actual.Status.Phase).To(Equal(v1alpha1.EtcdBackupFailed))
+			Expect(actual.Status.Message).To(ContainSubstring("could not parse snapshot status"))
+		})
+
+		table.DescribeTable("should fail the backup",
+			func(mutate func(backup *v1alpha1.EtcdBackup) []runtime.Object, message string) {
+				backup := newBackup(v1alpha1.EtcdBackupRunning)
+				objects := append(mutate(backup), backup)
+				c := fake.NewFakeClientWithScheme(s, objects...)
+
+				_, actual := reconcileBackup(c, backup)
+
+				Expect(actual.Status.Phase).To(Equal(v1alpha1.EtcdBackupFailed))
+				Expect(actual.Status.Message).To(ContainSubstring(message))
+			},
+			table.Entry("if the snapshot job failed", func(backup *v1alpha1.EtcdBackup) []runtime.Object {
+				return []runtime.Object{newCluster(), finishedJob(snapshotJob(backup, newCluster(), nil), batchv1.JobFailed, "backoff limit exceeded")}
+			}, "snapshot job failed: backoff limit exceeded"),
+			table.Entry("if the cluster does not exist", func(backup *v1alpha1.EtcdBackup) []runtime.Object {
+				return nil
+			}, "cluster foo not found"),
+			table.Entry("if no storage is configured", func(backup *v1alpha1.EtcdBackup) []runtime.Object {
+				backup.Spec.Storage = v1alpha1.EtcdBackupStorage{}
+				return []runtime.Object{newCluster()}
+			}, "exactly one of persistentVolumeClaim and hostPath"),
+		)
+
+		table.DescribeTable("should remove the snapshot job of a finished backup",
+			func(phase v1alpha1.EtcdBackupPhase) {
+				backup := newBackup(phase)
+				job := snapshotJob(backup, newCluster(), nil)
+				c := fake.NewFakeClientWithScheme(s, newCluster(), backup, job)
+
+				_, actual := reconcileBackup(c, backup)
+
+				Expect(actual.Status.Phase).To(Equal(phase))
+				Expect(jobExists(c, snapshotJobName(backup))).To(BeFalse())
+			},
+			table.Entry("succeeded", v1alpha1.EtcdBackupSucceeded),
+			table.Entry("failed", v1alpha1.EtcdBackupFailed),
+		)
+
+		Context("when the backup is deleted", func() {
+			deletedBackup := func(phase v1alpha1.EtcdBackupPhase) *v1alpha1.EtcdBackup {
+				backup := newBackup(phase)
+				now := metav1.Now()
+				backup.DeletionTimestamp = &now
+				backup.Finalizers = []string{FinalizerName}
+				return backup
+			}
+
+			It("should remove the snapshot with a cleanup job before removing the finalizer", func() {
+				backup := deletedBackup(v1alpha1.EtcdBackupSucceeded)
+				backup.Status.NodeName = "node-1"
+				c := fake.NewFakeClientWithScheme(s, backup, snapshotJob(backup, newCluster(), nil))
+
+				result, actual := reconcileBackup(c, backup)
+
+				Expect(result.RequeueAfter).To(Equal(CleanupRequeueAfter))
+				Expect(actual.Finalizers).To(ConsistOf(FinalizerName))
+				Expect(jobExists(c, snapshotJobName(backup))).To(BeFalse())
+
+				job := &batchv1.Job{}
+				Expect(c.Get(ctx, util.Key(namespace, cleanupJobName(backup)), job)).To(Succeed())
+				Expect(job.Spec.Template.Spec.NodeName).To(Equal("node-1"))
+
+				Expect(c.Update(ctx, finishedJob(job, batchv1.JobComplete, ""))).To(Succeed())
+
+				result, actual = reconcileBackup(c, backup)
+
+				Expect(result.RequeueAfter).To(BeZero())
+				Expect(actual.Finalizers).To(BeEmpty())
+				Expect(jobExists(c, cleanupJobName(backup))).To(BeFalse())
+			})
+
+			It("should not block the deletion if the cleanup job failed", func() {
+				backup := deletedBackup(v1alpha1.EtcdBackupSucceeded)
+				c := fake.NewFakeClientWithScheme(s, backup, finishedJob(cleanupJob(backup), batchv1.JobFailed, "backoff limit exceeded"))
+
+				_, actual := reconcileBackup(c, backup)
+
+				Expect(actual.Finalizers).To(BeEmpty())
+				Expect(jobExists(c, cleanupJobName(backup))).To(BeFalse())
+			})
+
+			It("should remove the finalizer right away if no snapshot was started", func() {
+				backup := deletedBackup(v1alpha1.EtcdBackupPending)
+				c := fake.NewFakeClientWithScheme(s, backup)
+
+				_, actual := reconcileBackup(c, backup)
+
+				Expect(actual.Finalizers).To(BeEmpty())
+				Expect(jobExists(c, cleanupJobName(backup))).To(BeFalse())
+			})
+		})
+	})
+})
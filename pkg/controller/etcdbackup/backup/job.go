@@ -0,0 +1,178 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	clustercontroller "kubeception.cloud/kubeception/pkg/controller/cluster"
+	"kubeception.cloud/kubeception/pkg/controller/common"
+	"kubeception.cloud/kubeception/pkg/util"
+	"kubeception.cloud/kubeception/pkg/util/pointers"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// StorageDir is the directory the backup storage is mounted at in snapshot and cleanup jobs.
+	StorageDir = "/backups"
+
+	storageVolumeName     = "storage"
+	certificateVolumeName = "client-certificate"
+)
+
+var (
+	// EtcdBackupLabel is the label carrying the name of the EtcdBackup a job belongs to.
+	EtcdBackupLabel = fmt.Sprintf("%s/etcd-backup", common.LabelPrefix)
+)
+
+const snapshotScript = `
+export ETCDCTL_API=3
+mkdir -p "$(dirname "${SNAPSHOT_FILE}")"
+etcdctl --dial-timeout=5s --endpoints="${ENDPOINT}" --cacert="${PKI_DIR}/ca.crt" --cert="${PKI_DIR}/tls.crt" --key="${PKI_DIR}/tls.key" \
+  snapshot save "${SNAPSHOT_FILE}.part"
+mv "${SNAPSHOT_FILE}.part" "${SNAPSHOT_FILE}"
+etcdctl snapshot status "${SNAPSHOT_FILE}" --write-out=json > /dev/termination-log
+`
+
+const cleanupScript = `
+rm -f "${SNAPSHOT_FILE}" "${SNAPSHOT_FILE}.part"
+`
+
+// SnapshotPath returns the path of the snapshot of the given backup relative to the root of its storage.
+func SnapshotPath(backup *v1alpha1.EtcdBackup) string {
+	return path.Join(backup.Namespace, backup.Spec.Cluster.Name, fmt.Sprintf("%s.db", backup.Name))
+}
+
+func snapshotJobName(backup *v1alpha1.EtcdBackup) string {
+	return fmt.Sprintf("%s-snapshot", backup.Name)
+}
+
+func cleanupJobName(backup *v1alpha1.EtcdBackup) string {
+	return fmt.Sprintf("%s-cleanup", backup.Name)
+}
+
+// validateStorage checks that exactly one storage location is set.
+func validateStorage(storage *v1alpha1.EtcdBackupStorage) error {
+	if (storage.PersistentVolumeClaim == nil) == (storage.HostPath == nil) {
+		return fmt.Errorf("exactly one of persistentVolumeClaim and hostPath has to be specified")
+	}
+	return nil
+}
+
+// StorageVolumeSource returns the volume source of the given backup storage.
+func StorageVolumeSource(storage *v1alpha1.EtcdBackupStorage) corev1.VolumeSource {
+	if storage.PersistentVolumeClaim != nil {
+		return corev1.VolumeSource{PersistentVolumeClaim: storage.PersistentVolumeClaim.DeepCopy()}
+	}
+	return corev1.VolumeSource{HostPath: storage.HostPath.DeepCopy()}
+}
+
+func backupJob(backup *v1alpha1.EtcdBackup, images *v1alpha1.Images, name, script string, container corev1.Container, volumes ...corev1.Volume) *batchv1.Job {
+	container.Image = common.ETCDImage(images)
+	container.Command = []string{"/bin/sh", "-ec", script}
+	container.Env = append(container.Env, corev1.EnvVar{Name: "SNAPSHOT_FILE", Value: path.Join(StorageDir, backup.Status.Path)})
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{Name: storageVolumeName, MountPath: StorageDir})
+
+	job := &batchv1.Job{
+		ObjectMeta: util.ObjectMeta(backup.Namespace, name),
+		Spec: batchv1.JobSpec{
+			BackoffLimit: pointers.Int32(3),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy:                corev1.RestartPolicyNever,
+					AutomountServiceAccountToken: pointers.Bool(false),
+					ImagePullSecrets:             common.ImagePullSecrets(images),
+					Containers:                   []corev1.Container{container},
+					Volumes: append(volumes, corev1.Volume{
+						Name:         storageVolumeName,
+						VolumeSource: StorageVolumeSource(&backup.Spec.Storage),
+					}),
+				},
+			},
+		},
+	}
+	util.SetMetaDataLabel(job, EtcdBackupLabel, backup.Name)
+	return job
+}
+
+// snapshotJob returns the job taking the snapshot of the given backup of the given cluster with the etcd image
+// of the cluster.
+func snapshotJob(backup *v1alpha1.EtcdBackup, cluster *clusterv1alpha1.Cluster, images *v1alpha1.Images) *batchv1.Job {
+	names := clustercontroller.ClusterNames(cluster)
+	return backupJob(backup, images, snapshotJobName(backup), snapshotScript,
+		corev1.Container{
+			Name: "snapshot",
+			Env: []corev1.EnvVar{
+				{Name: "ENDPOINT", Value: fmt.Sprintf("https://%s:%d", names.Scoped(clustercontroller.ETCDServiceName), clustercontroller.ETCDClientPort)},
+				{Name: "PKI_DIR", Value: clustercontroller.ETCDClientPKIDir},
+			},
+			VolumeMounts: []corev1.VolumeMount{
+				{
+					Name:      certificateVolumeName,
+					MountPath: clustercontroller.ETCDClientPKIDir,
+				},
+			},
+		},
+		clustercontroller.CertificateVolume(certificateVolumeName, names.Scoped(clustercontroller.ETCDClientCertificateName), names.Scoped(clustercontroller.CACertificateName)),
+	)
+}
+
+// cleanupJob returns the job removing the snapshot of the given backup. Host path snapshots are removed on the
+// node they were taken on. As the cluster may already be gone, the default etcd image is used.
+func cleanupJob(backup *v1alpha1.EtcdBackup) *batchv1.Job {
+	job := backupJob(backup, nil, cleanupJobName(backup), cleanupScript, corev1.Container{
+		Name: "cleanup",
+	})
+	if backup.Spec.Storage.HostPath != nil {
+		job.Spec.Template.Spec.NodeName = backup.Status.NodeName
+	}
+	return job
+}
+
+// jobFinished returns whether the job has finished and, if it did, whether it failed and why.
+func jobFinished(job *batchv1.Job) (finished bool, failed bool, message string) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return true, false, ""
+		case batchv1.JobFailed:
+			return true, true, condition.Message
+		}
+	}
+	return false, false, ""
+}
+
+// snapshotStatus is the output of `etcdctl snapshot status --write-out=json`.
+type snapshotStatus struct {
+	Hash      uint32 `json:"hash"`
+	Revision  int64  `json:"revision"`
+	TotalKey  int    `json:"totalKey"`
+	TotalSize int64  `json:"totalSize"`
+}
+
+// parseSnapshotStatus parses the snapshot status written by the snapshot job as termination message.
+func parseSnapshotStatus(message string) (*snapshotStatus, error) {
+	status := &snapshotStatus{}
+	if err := json.Unmarshal([]byte(message), status); err != nil {
+		return nil, fmt.Errorf("could not parse snapshot status %q: %v", message, err)
+	}
+	return status, nil
+}
+
+// MapJobToBackup maps a snapshot or cleanup job to the EtcdBackup it belongs to.
+func MapJobToBackup(mapObject handler.MapObject) []reconcile.Request {
+	name, ok := mapObject.Meta.GetLabels()[EtcdBackupLabel]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{util.Request(mapObject.Meta.GetNamespace(), name)}
+}
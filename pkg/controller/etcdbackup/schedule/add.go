@@ -0,0 +1,43 @@
+package schedule
+
+import (
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	Name = "etcdbackupschedule"
+)
+
+type AddArgs struct {
+	MaxConcurrentReconciles int
+}
+
+var DefaultArgs AddArgs
+
+func AddToManager(mgr manager.Manager) error {
+	return AddToManagerWithArgs(mgr, DefaultArgs)
+}
+
+func AddToManagerWithArgs(mgr manager.Manager, args AddArgs) error {
+	ctrl, err := controller.New(Name, mgr, controller.Options{
+		Reconciler:              NewReconciler(mgr.GetEventRecorderFor(Name)),
+		MaxConcurrentReconciles: args.MaxConcurrentReconciles,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := ctrl.Watch(&source.Kind{Type: &v1alpha1.EtcdBackupSchedule{}}, &handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+
+	if err := ctrl.Watch(&source.Kind{Type: &v1alpha1.EtcdBackup{}}, &handler.EnqueueRequestForOwner{OwnerType: &v1alpha1.EtcdBackupSchedule{}, IsController: true}); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/controller/common"
+	"kubeception.cloud/kubeception/pkg/util"
+	"kubeception.cloud/kubeception/pkg/util/controller"
+	"kubeception.cloud/kubeception/pkg/util/cron"
+	"kubeception.cloud/kubeception/pkg/util/pointers"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+const (
+	DefaultGenerations = 3
+
+	EventInvalidSchedule = "InvalidSchedule"
+	EventBackupCreated   = "BackupCreated"
+	EventBackupPruned    = "BackupPruned"
+)
+
+var (
+	// EtcdBackupScheduleLabel is the label carrying the name of the EtcdBackupSchedule that created a backup.
+	EtcdBackupScheduleLabel = fmt.Sprintf("%s/etcd-backup-schedule", common.LabelPrefix)
+)
+
+var logger = log.Log.WithName("etcdbackupschedule")
+
+type reconciler struct {
+	recorder record.EventRecorder
+	controller.WithClient
+	controller.WithScheme
+	controller.WithContext
+	controller.WithLog
+}
+
+func NewReconciler(recorder record.EventRecorder) reconcile.Reconciler {
+	return &reconciler{recorder: recorder, WithLog: controller.NewWithLog(logger)}
+}
+
+func (r *reconciler) Reconcile(req reconcile.Request) (reconcile.Result, error) {
+	log := r.Log.WithValues("etcdbackupschedule", req.String())
+	schedule := &v1alpha1.EtcdBackupSchedule{}
+	if err := r.Client.Get(r.Context, req.NamespacedName, schedule); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !schedule.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+	return r.reconcile(r.Context, log, schedule)
+}
+
+// backupsToPrune returns the backups that exceed the given number of generations. Backups are considered from
+// newest to oldest: successful backups beyond the number of generations as well as finished failed backups that
+// are older than a successful one are pruned. Running backups are never pruned.
+func backupsToPrune(backups []v1alpha1.EtcdBackup, generations int) []v1alpha1.EtcdBackup {
+	sorted := append([]v1alpha1.EtcdBackup{}, backups...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[j].CreationTimestamp.Before(&sorted[i].CreationTimestamp)
+	})
+
+	var (
+		prune     []v1alpha1.EtcdBackup
+		succeeded int
+	)
+	for _, backup := range sorted {
+		switch backup.Status.Phase {
+		case v1alpha1.EtcdBackupSucceeded:
+			succeeded++
+			if succeeded > generations {
+				prune = append(prune, backup)
+			}
+		case v1alpha1.EtcdBackupFailed:
+			if succeeded > 0 {
+				prune = append(prune, backup)
+			}
+		}
+	}
+	return prune
+}
+
+// lastSuccessfulBackup returns the name of the newest successful backup or the empty string if there is none.
+func lastSuccessfulBackup(backups []v1alpha1.EtcdBackup) string {
+	var last *v1alpha1.EtcdBackup
+	for i, backup := range backups {
+		if backup.Status.Phase != v1alpha1.EtcdBackupSucceeded {
+			continue
+		}
+		if last == nil || last.CreationTimestamp.Before(&backup.CreationTimestamp) {
+			last = &backups[i]
+		}
+	}
+	if last == nil {
+		return ""
+	}
+	return last.Name
+}
+
+// lastScheduleTime returns the most recent time not after now the schedule fired since the given time.
+// If the schedule did not fire since then, the zero time is returned.
+func lastScheduleTime(schedule *cron.Schedule, since, now time.Time) time.Time {
+	var last time.Time
+	for next := schedule.Next(since); !next.IsZero() && !next.After(now); next = schedule.Next(next) {
+		last = next
+	}
+	return last
+}
+
+func (r *reconciler) createBackup(ctx context.Context, log logr.Logger, schedule *v1alpha1.EtcdBackupSchedule, scheduleTime time.Time) error {
+	backup := &v1alpha1.EtcdBackup{
+		ObjectMeta: util.ObjectMeta(schedule.Namespace, fmt.Sprintf("%s-%d", schedule.Name, scheduleTime.Unix())),
+		Spec: v1alpha1.EtcdBackupSpec{
+			Cluster: schedule.Spec.Cluster,
+			Storage: *schedule.Spec.Storage.DeepCopy(),
+		},
+	}
+	util.SetMetaDataLabel(backup, EtcdBackupScheduleLabel, schedule.Name)
+	if err := controllerruntime.SetControllerReference(schedule, backup, r.Scheme); err != nil {
+		return err
+	}
+
+	if err := r.Client.Create(ctx, backup); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return nil
+		}
+		return err
+	}
+
+	log.Info("Created backup", "backup", backup.Name)
+	r.recorder.Eventf(schedule, corev1.EventTypeNormal, EventBackupCreated, "Created backup %s", backup.Name)
+	return nil
+}
+
+func (r *reconciler) pruneBackups(ctx context.Context, log logr.Logger, schedule *v1alpha1.EtcdBackupSchedule, backups []v1alpha1.EtcdBackup) error {
+	generations := pointers.DerefInt32OrDefault(schedule.Spec.Generations, DefaultGenerations)
+	for _, backup := range backupsToPrune(backups, int(generations)) {
+		if !backup.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		log.Info("Pruning backup", "backup", backup.Name)
+		if err := client.IgnoreNotFound(r.Client.Delete(ctx, &backup)); err != nil {
+			return err
+		}
+		r.recorder.Eventf(schedule, corev1.EventTypeNormal, EventBackupPruned, "Pruned backup %s", backup.Name)
+	}
+	return nil
+}
+
+func (r *reconciler) reconcile(ctx context.Context, log logr.Logger, schedule *v1alpha1.EtcdBackupSchedule) (reconcile.Result, error) {
+	cronSchedule, err := cron.Parse(schedule.Spec.Schedule)
+	if err != nil {
+		r.recorder.Eventf(schedule, corev1.EventTypeWarning, EventInvalidSchedule, "Invalid schedule: %v", err)
+		return reconcile.Result{}, nil
+	}
+
+	backupList := &v1alpha1.EtcdBackupList{}
+	if err := r.Client.List(ctx, backupList, client.InNamespace(schedule.Namespace), client.MatchingLabels{EtcdBackupScheduleLabel: schedule.Name}); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if err := r.pruneBackups(ctx, log, schedule, backupList.Items); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	now := time.Now()
+	status := schedule.Status.DeepCopy()
+	status.LastSuccessfulBackup = lastSuccessfulBackup(backupList.Items)
+
+	if !schedule.Spec.Suspend {
+		since := schedule.CreationTimestamp.Time
+		if status.LastScheduleTime != nil {
+			since = status.LastScheduleTime.Time
+		}
+
+		if scheduleTime := lastScheduleTime(cronSchedule, since, now); !scheduleTime.IsZero() {
+			if err := r.createBackup(ctx, log, schedule, scheduleTime); err != nil {
+				return reconcile.Result{}, err
+			}
+			status.LastScheduleTime = &metav1.Time{Time: scheduleTime}
+		}
+	}
+
+	if status.LastSuccessfulBackup != schedule.Status.LastSuccessfulBackup || !status.LastScheduleTime.Equal(schedule.Status.LastScheduleTime) {
+		schedule.Status = *status
+		if err := r.Client.Status().Update(ctx, schedule); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	if schedule.Spec.Suspend {
+		return reconcile.Result{}, nil
+	}
+
+	next := cronSchedule.Next(now)
+	if next.IsZero() {
+		return reconcile.Result{}, nil
+	}
+	return reconcile.Result{RequeueAfter: next.Sub(now)}, nil
+}
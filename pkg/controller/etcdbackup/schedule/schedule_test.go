@@ -0,0 +1,81 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/util/cron"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestSchedule(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Schedule")
+}
+
+func backup(name string, minute int, phase v1alpha1.EtcdBackupPhase) v1alpha1.EtcdBackup {
+	return v1alpha1.EtcdBackup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(time.Date(2019, 5, 1, 0, minute, 0, 0, time.UTC)),
+		},
+		Status: v1alpha1.EtcdBackupStatus{Phase: phase},
+	}
+}
+
+func names(backups []v1alpha1.EtcdBackup) []string {
+	var out []string
+	for _, backup := range backups {
+		out = append(out, backup.Name)
+	}
+	return out
+}
+
+var _ = Describe("Schedule Suite", func() {
+	Describe("#backupsToPrune", func() {
+		It("should prune successful backups exceeding the generations", func() {
+			backups := []v1alpha1.EtcdBackup{
+				backup("a", 1, v1alpha1.EtcdBackupSucceeded),
+				backup("c", 3, v1alpha1.EtcdBackupSucceeded),
+				backup("b", 2, v1alpha1.EtcdBackupSucceeded),
+				backup("d", 4, v1alpha1.EtcdBackupRunning),
+			}
+
+			Expect(names(backupsToPrune(backups, 2))).To(Equal([]string{"a"}))
+		})
+
+		It("should only prune failed backups superseded by a successful one", func() {
+			backups := []v1alpha1.EtcdBackup{
+				backup("a", 1, v1alpha1.EtcdBackupFailed),
+				backup("b", 2, v1alpha1.EtcdBackupSucceeded),
+				backup("c", 3, v1alpha1.EtcdBackupFailed),
+			}
+
+			Expect(names(backupsToPrune(backups, 3))).To(Equal([]string{"a"}))
+		})
+	})
+
+	Describe("#lastScheduleTime", func() {
+		It("should return the most recent missed schedule time", func() {
+			schedule, err := cron.Parse("*/10 * * * *")
+			Expect(err).NotTo(HaveOccurred())
+
+			since := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+			now := time.Date(2019, 5, 1, 0, 35, 0, 0, time.UTC)
+			Expect(lastScheduleTime(schedule, since, now)).To(Equal(time.Date(2019, 5, 1, 0, 30, 0, 0, time.UTC)))
+		})
+
+		It("should return the zero time if the schedule did not fire", func() {
+			schedule, err := cron.Parse("@hourly")
+			Expect(err).NotTo(HaveOccurred())
+
+			since := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+			now := time.Date(2019, 5, 1, 0, 35, 0, 0, time.UTC)
+			Expect(lastScheduleTime(schedule, since, now).IsZero()).To(BeTrue())
+		})
+	})
+})
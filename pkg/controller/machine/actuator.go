@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 
+	"kubeception.cloud/kubeception/pkg/controller/common"
+	"kubeception.cloud/kubeception/pkg/util"
 	"kubeception.cloud/kubeception/pkg/util/controller"
 
 	"kubeception.cloud/kubeception/pkg/apis/kubeception/helper"
@@ -18,6 +20,7 @@ import (
 	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
 	"kubeception.cloud/kubeception/pkg/util/pointers"
 	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllererror "sigs.k8s.io/cluster-api/pkg/controller/error"
 	"sigs.k8s.io/cluster-api/pkg/controller/machine"
 	controllerruntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -63,15 +66,32 @@ func mkMachineStatefulSet(machine *clusterv1alpha1.Machine) *appsv1.StatefulSet
 }
 
 func (a *actuator) Create(ctx context.Context, cluster *clusterv1alpha1.Cluster, machine *clusterv1alpha1.Machine) error {
-	config, _, err := configsFromObjects(cluster, machine)
+	config, machineConfig, err := configsFromObjects(cluster, machine)
 	if err != nil {
 		return err
 	}
 
+	version := cluster2.KubeletVersion(cluster, machine, config)
+	kubeletArgs, err := kubeletFlags(cluster, config, machineConfig.Kubelet, kubeletNodeName(machine, machineConfig.Storage, "$(POD_IP)"))
+	if err != nil {
+		return fmt.Errorf("invalid kubelet configuration: %v", err)
+	}
+	additionalDockerdArgs, err := dockerdArgs(machineConfig.Docker, machineConfig.Storage)
+	if err != nil {
+		return fmt.Errorf("invalid docker configuration: %v", err)
+	}
+
+	if err := a.reconcileBootstrapKubeconfig(ctx, cluster, machine); err != nil {
+		return fmt.Errorf("could not reconcile bootstrap kubeconfig: %v", err)
+	}
+
+	volumes, volumeClaimTemplates, volumeMounts := storageVolumes(machine, machineConfig.Storage)
+
 	labels := StatefulSetLabels(machine.Name)
 	statefulSet := mkMachineStatefulSet(machine)
 	if _, err := controllerruntime.CreateOrUpdate(ctx, a.Client, statefulSet, func() error {
 		statefulSet.Labels = labels
+		util.SetMetaDataAnnotation(statefulSet, cluster2.KubernetesVersionAnnotation, version)
 		statefulSet.Spec = appsv1.StatefulSetSpec{
 			Replicas: pointers.Int32(1),
 			Selector: &metav1.LabelSelector{
@@ -84,32 +104,24 @@ func (a *actuator) Create(ctx context.Context, cluster *clusterv1alpha1.Cluster,
 				Spec: corev1.PodSpec{
 					TerminationGracePeriodSeconds: pointers.Int64(10),
 					AutomountServiceAccountToken:  pointers.Bool(false),
+					ImagePullSecrets:              common.ImagePullSecrets(config.Images),
 					Containers: []corev1.Container{
 						{
 							Name:  "kubelet",
-							Image: fmt.Sprintf("adracus/dind-kubelet:%s", config.KubernetesVersion),
+							Image: common.KubeletImage(config.Images, version),
 							Env: []corev1.EnvVar{
-								{Name: "KUBECONFIG", Value: "/etc/kubeconfig/kubeconfig"},
-								{Name: "ADDITIONAL_DOCKERD_ARGS", Value: "--storage-driver=vfs"},
+								{Name: "KUBECONFIG", Value: KubeletKubeconfigFile},
+								{Name: "ADDITIONAL_DOCKERD_ARGS", Value: additionalDockerdArgs},
 								{Name: "POD_IP", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.podIP"}}},
 							},
-							Command: []string{
-								"/entrypoint.sh",
-								"--kubeconfig=/etc/kubeconfig/kubeconfig",
-								"--fail-swap-on=false",
-								"--port=20250",
-								"--containerized",
-								"--feature-gates=LocalStorageCapacityIsolation=false",
-								"--cloud-provider=",
-								"--hostname-override=$(POD_IP)",
-							},
+							Command: kubeletCommand(kubeletArgs, detectsStorageDriver(machineConfig.Docker, machineConfig.Storage)),
 							SecurityContext: &corev1.SecurityContext{
 								Privileged: pointers.Bool(true),
 							},
 							VolumeMounts: []corev1.VolumeMount{
 								{
 									Name:      "kubeconfig",
-									MountPath: "/etc/kubeconfig",
+									MountPath: BootstrapKubeconfigDir,
 								},
 								{
 									Name:      "rootfs",
@@ -119,7 +131,7 @@ func (a *actuator) Create(ctx context.Context, cluster *clusterv1alpha1.Cluster,
 							},
 							Ports: []corev1.ContainerPort{
 								{
-									ContainerPort: 20250,
+									ContainerPort: KubeletPort,
 								},
 							},
 						},
@@ -129,7 +141,7 @@ func (a *actuator) Create(ctx context.Context, cluster *clusterv1alpha1.Cluster,
 							Name: "kubeconfig",
 							VolumeSource: corev1.VolumeSource{
 								Secret: &corev1.SecretVolumeSource{
-									SecretName: cluster2.KubeconfigSecretName,
+									SecretName: BootstrapKubeconfigSecretName(machine.Name),
 								},
 							},
 						},
@@ -144,18 +156,63 @@ func (a *actuator) Create(ctx context.Context, cluster *clusterv1alpha1.Cluster,
 					},
 				},
 			},
+			VolumeClaimTemplates: volumeClaimTemplates,
 		}
+		podSpec := &statefulSet.Spec.Template.Spec
+		podSpec.Volumes = append(podSpec.Volumes, volumes...)
+		podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, volumeMounts...)
+		if config.Network != nil {
+			// The pod network needs the kernel modules of the host, for example for VXLAN.
+			podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+				Name:         "modules",
+				VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/lib/modules"}},
+			})
+			podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+				Name:      "modules",
+				MountPath: "/lib/modules",
+				ReadOnly:  true,
+			})
+		}
+		common.ApplyWorkloadSettings(&statefulSet.Spec.Template.Spec, &machineConfig.WorkloadSettings)
 
 		return controllerruntime.SetControllerReference(machine, statefulSet, a.Scheme)
 	}); err != nil {
 		return err
 	}
 
-	return nil
+	return a.updateStatus(ctx, cluster, machine)
 }
 
+// Delete drains and deletes the node of the machine in the hosted cluster, then deletes the StatefulSet of the
+// machine and its persistent volume claims. Until all of them are gone, a RequeueAfterError is returned.
 func (a *actuator) Delete(ctx context.Context, cluster *clusterv1alpha1.Cluster, machine *clusterv1alpha1.Machine) error {
-	return nil
+	statefulSet := mkMachineStatefulSet(machine)
+	if err := a.Client.Get(ctx, client.ObjectKey{Namespace: machine.Namespace, Name: machine.Name}, statefulSet); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		deleted, err := a.deleteStorage(ctx, machine)
+		if err != nil || deleted {
+			return err
+		}
+		return &controllererror.RequeueAfterError{RequeueAfter: DeleteRequeueAfter}
+	}
+
+	if statefulSet.DeletionTimestamp == nil {
+		deregistered, err := a.deregisterNode(ctx, cluster, machine)
+		if err != nil {
+			return err
+		}
+		if !deregistered {
+			return &controllererror.RequeueAfterError{RequeueAfter: DrainRequeueAfter}
+		}
+
+		if err := a.Client.Delete(ctx, statefulSet, client.PropagationPolicy(metav1.DeletePropagationForeground)); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+	}
+	return &controllererror.RequeueAfterError{RequeueAfter: DeleteRequeueAfter}
 }
 
 func (a *actuator) Update(ctx context.Context, cluster *clusterv1alpha1.Cluster, machine *clusterv1alpha1.Machine) error {
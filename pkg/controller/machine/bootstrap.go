@@ -0,0 +1,152 @@
+package machine
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cluster2 "kubeception.cloud/kubeception/pkg/controller/cluster"
+	"kubeception.cloud/kubeception/pkg/controller/common"
+	"kubeception.cloud/kubeception/pkg/util"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// BootstrapTokenTTL is the duration the bootstrap token of a machine is valid for.
+	BootstrapTokenTTL = 24 * time.Hour
+	// BootstrapTokenRenewBefore is the remaining validity of the bootstrap token of a machine below which a new
+	// token is minted, so that a restarted machine can always bootstrap its kubelet.
+	BootstrapTokenRenewBefore = 12 * time.Hour
+
+	// The bootstrap token secrets are created in the kube-system namespace of the hosted cluster, where the API
+	// server looks them up.
+	bootstrapTokenNamespace    = metav1.NamespaceSystem
+	bootstrapTokenSecretPrefix = "bootstrap-token-"
+	bootstrapTokenIDLength     = 6
+	bootstrapTokenSecretLength = 16
+	bootstrapTokenCharset      = "abcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+var (
+	// BootstrapTokenExpirationAnnotation is the annotation of the bootstrap kubeconfig secret of a machine carrying
+	// the expiration time of its token.
+	BootstrapTokenExpirationAnnotation = fmt.Sprintf("%s/bootstrap-token-expiration", common.LabelPrefix)
+)
+
+// BootstrapKubeconfigSecretName returns the name of the secret containing the bootstrap kubeconfig of the machine
+// with the given name.
+func BootstrapKubeconfigSecretName(machineName string) string {
+	return fmt.Sprintf("%s-bootstrap-kubeconfig", machineName)
+}
+
+// randomString returns a random string of the given length consisting of characters of the bootstrap token charset.
+func randomString(length int) (string, error) {
+	max := big.NewInt(int64(len(bootstrapTokenCharset)))
+	data := make([]byte, length)
+	for i := range data {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		data[i] = bootstrapTokenCharset[n.Int64()]
+	}
+	return string(data), nil
+}
+
+// newBootstrapToken generates the id and the secret of a new bootstrap token.
+func newBootstrapToken() (string, string, error) {
+	id, err := randomString(bootstrapTokenIDLength)
+	if err != nil {
+		return "", "", err
+	}
+
+	secret, err := randomString(bootstrapTokenSecretLength)
+	if err != nil {
+		return "", "", err
+	}
+	return id, secret, nil
+}
+
+// bootstrapTokenSecret returns the secret of the bootstrap token with the given id and secret for the given machine.
+// The token only authenticates in NodeBootstrapTokenGroup, which may request node client certificates.
+func bootstrapTokenSecret(machine *clusterv1alpha1.Machine, id, secret string, expiration time.Time) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: util.ObjectMeta(bootstrapTokenNamespace, bootstrapTokenSecretPrefix+id),
+		Type:       corev1.SecretTypeBootstrapToken,
+		StringData: map[string]string{
+			"description":                    fmt.Sprintf("Bootstrap token of machine %s/%s", machine.Namespace, machine.Name),
+			"token-id":                       id,
+			"token-secret":                   secret,
+			"expiration":                     expiration.UTC().Format(time.RFC3339),
+			"usage-bootstrap-authentication": "true",
+			"auth-extra-groups":              cluster2.NodeBootstrapTokenGroup,
+		},
+	}
+}
+
+// bootstrapTokenValid reports whether the token of the given bootstrap kubeconfig secret is valid for longer than
+// BootstrapTokenRenewBefore.
+func bootstrapTokenValid(secret *corev1.Secret, now time.Time) bool {
+	expiration, err := time.Parse(time.RFC3339, secret.Annotations[BootstrapTokenExpirationAnnotation])
+	if err != nil {
+		return false
+	}
+	return expiration.Sub(now) > BootstrapTokenRenewBefore
+}
+
+// reconcileBootstrapKubeconfig ensures that the given machine has a bootstrap kubeconfig whose token is valid for
+// long enough. The kubelet of the machine uses it to request its client certificate.
+func (a *actuator) reconcileBootstrapKubeconfig(ctx context.Context, cluster *clusterv1alpha1.Cluster, machine *clusterv1alpha1.Machine) error {
+	secret := &corev1.Secret{}
+	if err := a.Client.Get(ctx, util.Key(machine.Namespace, BootstrapKubeconfigSecretName(machine.Name)), secret); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	} else if bootstrapTokenValid(secret, time.Now()) {
+		return nil
+	}
+
+	adminSecret := &corev1.Secret{}
+	if err := a.Client.Get(ctx, util.Key(cluster.Namespace, cluster2.ClusterNames(cluster).Scoped(cluster2.KubeconfigSecretName)), adminSecret); err != nil {
+		return err
+	}
+	adminKubeconfig, err := cluster2.ReadKubeconfigSecret(adminSecret)
+	if err != nil {
+		return err
+	}
+	kubeconfigCluster, err := cluster2.KubeconfigCluster(adminKubeconfig)
+	if err != nil {
+		return err
+	}
+
+	guest, err := cluster2.NewClientForKubeconfigSecret(adminSecret)
+	if err != nil {
+		return fmt.Errorf("could not create client for cluster %s: %v", cluster.Name, err)
+	}
+
+	id, tokenSecret, err := newBootstrapToken()
+	if err != nil {
+		return err
+	}
+	expiration := time.Now().Add(BootstrapTokenTTL)
+	if err := guest.Create(ctx, bootstrapTokenSecret(machine, id, tokenSecret, expiration)); err != nil {
+		return fmt.Errorf("could not create bootstrap token in cluster %s: %v", cluster.Name, err)
+	}
+
+	bootstrapKubeconfig := cluster2.NewTokenKubeconfig(kubeconfigCluster.Server, kubeconfigCluster.CertificateAuthorityData, fmt.Sprintf("%s.%s", id, tokenSecret))
+	secret = &corev1.Secret{ObjectMeta: util.ObjectMeta(machine.Namespace, BootstrapKubeconfigSecretName(machine.Name))}
+	_, err = controllerruntime.CreateOrUpdate(ctx, a.Client, secret, func() error {
+		util.SetMetaDataAnnotation(secret, BootstrapTokenExpirationAnnotation, expiration.UTC().Format(time.RFC3339))
+		if err := cluster2.UpdateKubeconfigSecret(secret, bootstrapKubeconfig); err != nil {
+			return err
+		}
+		return controllerruntime.SetControllerReference(machine, secret, a.Scheme)
+	})
+	return err
+}
@@ -0,0 +1,70 @@
+package machine
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cluster2 "kubeception.cloud/kubeception/pkg/controller/cluster"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bootstrap", func() {
+	Describe("#newBootstrapToken", func() {
+		It("should generate tokens in the bootstrap token format", func() {
+			id, secret, err := newBootstrapToken()
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(id).To(MatchRegexp(`^[a-z0-9]{6}$`))
+			Expect(secret).To(MatchRegexp(`^[a-z0-9]{16}$`))
+		})
+	})
+
+	Describe("#bootstrapTokenSecret", func() {
+		It("should create a bootstrap token authenticating in the node bootstrap group", func() {
+			machine := &clusterv1alpha1.Machine{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example"}}
+			expiration := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+
+			secret := bootstrapTokenSecret(machine, "abcdef", "0123456789abcdef", expiration)
+
+			Expect(secret.Namespace).To(Equal(metav1.NamespaceSystem))
+			Expect(secret.Name).To(Equal("bootstrap-token-abcdef"))
+			Expect(secret.Type).To(Equal(corev1.SecretTypeBootstrapToken))
+			Expect(secret.StringData).To(HaveKeyWithValue("token-id", "abcdef"))
+			Expect(secret.StringData).To(HaveKeyWithValue("token-secret", "0123456789abcdef"))
+			Expect(secret.StringData).To(HaveKeyWithValue("expiration", "2019-06-01T12:00:00Z"))
+			Expect(secret.StringData).To(HaveKeyWithValue("usage-bootstrap-authentication", "true"))
+			Expect(secret.StringData).To(HaveKeyWithValue("auth-extra-groups", cluster2.NodeBootstrapTokenGroup))
+		})
+	})
+
+	Describe("#bootstrapTokenValid", func() {
+		var (
+			now    time.Time
+			secret *corev1.Secret
+		)
+		BeforeEach(func() {
+			now = time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+			secret = &corev1.Secret{}
+		})
+
+		It("should consider tokens without expiration invalid", func() {
+			Expect(bootstrapTokenValid(secret, now)).To(BeFalse())
+		})
+
+		It("should consider tokens expiring within the renewal window invalid", func() {
+			secret.Annotations = map[string]string{BootstrapTokenExpirationAnnotation: now.Add(time.Hour).Format(time.RFC3339)}
+
+			Expect(bootstrapTokenValid(secret, now)).To(BeFalse())
+		})
+
+		It("should consider tokens expiring after the renewal window valid", func() {
+			secret.Annotations = map[string]string{BootstrapTokenExpirationAnnotation: now.Add(BootstrapTokenTTL).Format(time.RFC3339)}
+
+			Expect(bootstrapTokenValid(secret, now)).To(BeTrue())
+		})
+	})
+})
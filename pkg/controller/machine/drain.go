@@ -0,0 +1,195 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/helper"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	cluster2 "kubeception.cloud/kubeception/pkg/controller/cluster"
+	"kubeception.cloud/kubeception/pkg/util"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultDrainTimeout is the maximum duration the node of a deleted machine is drained for unless its
+	// configuration specifies otherwise.
+	DefaultDrainTimeout = 5 * time.Minute
+	// DrainRequeueAfter is the duration after which the drain of the node of a deleted machine is checked again.
+	DrainRequeueAfter = 5 * time.Second
+
+	// mirrorPodAnnotation is the annotation the kubelet sets on the API objects of its static pods.
+	mirrorPodAnnotation = "kubernetes.io/config.mirror"
+)
+
+// machinePodName returns the name of the pod running the given machine.
+func machinePodName(machine *clusterv1alpha1.Machine) string {
+	return fmt.Sprintf("%s-0", machine.Name)
+}
+
+// machineNodeName returns the name of the node of the given machine running in a pod with the given IP or the
+// empty string if it is not known, see kubeletNodeName.
+func machineNodeName(machine *clusterv1alpha1.Machine, podIP string) string {
+	var storage *v1alpha1.MachineStorage
+	if value := machine.Spec.ProviderSpec.Value; value != nil {
+		if machineConfig, err := helper.LoadMachineConfig(value.Raw); err == nil {
+			storage = machineConfig.Storage
+		}
+	}
+	return kubeletNodeName(machine, storage, podIP)
+}
+
+// nodeName returns the name of the node of the given machine or the empty string if it is not known.
+func (a *actuator) nodeName(ctx context.Context, machine *clusterv1alpha1.Machine) (string, error) {
+	if machine.Status.NodeRef != nil {
+		return machine.Status.NodeRef.Name, nil
+	}
+
+	var podIP string
+	pod := &corev1.Pod{}
+	if err := a.Client.Get(ctx, util.Key(machine.Namespace, machinePodName(machine)), pod); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return "", err
+		}
+	} else {
+		podIP = pod.Status.PodIP
+	}
+	return machineNodeName(machine, podIP), nil
+}
+
+// guestClientset returns a clientset for the API server of the given cluster that authenticates with its
+// administrative kubeconfig.
+func (a *actuator) guestClientset(ctx context.Context, cluster *clusterv1alpha1.Cluster) (kubernetes.Interface, error) {
+	secret := &corev1.Secret{}
+	if err := a.Client.Get(ctx, util.Key(cluster.Namespace, cluster2.ClusterNames(cluster).Scoped(cluster2.KubeconfigSecretName)), secret); err != nil {
+		return nil, err
+	}
+
+	restConfig, err := cluster2.NewRESTConfigForKubeconfigSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(restConfig)
+}
+
+// podsToEvict returns the pods that have to be evicted to drain a node. Mirror pods and pods of DaemonSets are
+// skipped, as they would be recreated on the node, as well as pods that already terminated.
+func podsToEvict(pods []corev1.Pod) []corev1.Pod {
+	var evict []corev1.Pod
+	for _, pod := range pods {
+		if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+			continue
+		}
+		if ref := metav1.GetControllerOf(&pod); ref != nil && ref.Kind == "DaemonSet" {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		evict = append(evict, pod)
+	}
+	return evict
+}
+
+// drainNode cordons the node with the given name and evicts its pods, honoring their PodDisruptionBudgets.
+// It returns true once no pods to evict are left on the node.
+func drainNode(guest kubernetes.Interface, nodeName string) (bool, error) {
+	node, err := guest.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	if !node.Spec.Unschedulable {
+		node.Spec.Unschedulable = true
+		if _, err := guest.CoreV1().Nodes().Update(node); err != nil {
+			return false, err
+		}
+	}
+
+	podList, err := guest.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	pods := podsToEvict(podList.Items)
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+
+		// An eviction that would violate a PodDisruptionBudget is rejected with TooManyRequests and retried
+		// with the next drain.
+		if err := guest.PolicyV1beta1().Evictions(pod.Namespace).Evict(&policyv1beta1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{Namespace: pod.Namespace, Name: pod.Name},
+		}); err != nil && !apierrors.IsNotFound(err) && !apierrors.IsTooManyRequests(err) {
+			return false, err
+		}
+	}
+	return len(pods) == 0, nil
+}
+
+// removeNode drains the node with the given name and deletes it once it is drained. After the drain timeout has
+// passed, the node is deleted regardless of the pods left on it, which are then removed by the pod garbage
+// collector of the cluster. It returns true once the node is gone, or if the drain timed out and the node cannot
+// be deleted because the cluster is unreachable.
+func removeNode(guest kubernetes.Interface, nodeName string, timedOut bool) (bool, error) {
+	drained, err := drainNode(guest, nodeName)
+	if !timedOut && (err != nil || !drained) {
+		return false, err
+	}
+
+	if err := guest.CoreV1().Nodes().Delete(nodeName, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return timedOut, err
+	}
+	return true, nil
+}
+
+// drainAndDeleteNode drains the node of the given machine and deletes it, see removeNode. It returns true once
+// the node is gone.
+func (a *actuator) drainAndDeleteNode(ctx context.Context, cluster *clusterv1alpha1.Cluster, machine *clusterv1alpha1.Machine, timedOut bool) (bool, error) {
+	nodeName, err := a.nodeName(ctx, machine)
+	if err != nil || nodeName == "" {
+		return err == nil || timedOut, err
+	}
+
+	guest, err := a.guestClientset(ctx, cluster)
+	if err != nil {
+		return timedOut, fmt.Errorf("could not create client for cluster %s: %v", cluster.Name, err)
+	}
+	return removeNode(guest, nodeName, timedOut)
+}
+
+// deregisterNode drains and deletes the node of the given deleted machine. It returns true once the node is gone,
+// or if the drain timeout of the machine has passed and the cluster is unreachable.
+func (a *actuator) deregisterNode(ctx context.Context, cluster *clusterv1alpha1.Cluster, machine *clusterv1alpha1.Machine) (bool, error) {
+	// The nodes of a deleted cluster go away along with its control plane.
+	if cluster == nil || cluster.DeletionTimestamp != nil {
+		return true, nil
+	}
+
+	timeout := DefaultDrainTimeout
+	if machineConfig, err := helper.LoadMachineConfig(machine.Spec.ProviderSpec.Value.Raw); err == nil && machineConfig.DrainTimeout != nil {
+		timeout = machineConfig.DrainTimeout.Duration
+	}
+	timedOut := machine.DeletionTimestamp != nil && time.Since(machine.DeletionTimestamp.Time) > timeout
+
+	done, err := a.drainAndDeleteNode(ctx, cluster, machine, timedOut)
+	if done && timedOut {
+		// The node is gone or cannot be deleted, the machine is removed anyway.
+		return true, nil
+	}
+	return done, err
+}
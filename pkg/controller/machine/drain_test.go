@@ -0,0 +1,132 @@
+package machine
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"kubeception.cloud/kubeception/pkg/util/pointers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// rejectEvictions makes the given clientset reject all evictions like a PodDisruptionBudget that does not allow
+// any disruption.
+func rejectEvictions(guest *fake.Clientset) {
+	guest.PrependReactor("post", "pods", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 10)
+	})
+}
+
+var _ = Describe("Drain", func() {
+	var (
+		node *corev1.Node
+		pod  *corev1.Pod
+	)
+	BeforeEach(func() {
+		node = &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "10.0.0.1"}}
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+			Spec:       corev1.PodSpec{NodeName: "10.0.0.1"},
+		}
+	})
+
+	Describe("#podsToEvict", func() {
+		It("should skip mirror, daemon set and terminated pods", func() {
+			var (
+				regular = corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "regular"}}
+				mirror  = corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+					Name:        "mirror",
+					Annotations: map[string]string{mirrorPodAnnotation: "abc"},
+				}}
+				daemon = corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+					Name:            "daemon",
+					OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Name: "flannel", Controller: pointers.Bool(true)}},
+				}}
+				succeeded = corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: "succeeded"},
+					Status:     corev1.PodStatus{Phase: corev1.PodSucceeded},
+				}
+			)
+
+			Expect(podsToEvict([]corev1.Pod{regular, mirror, daemon, succeeded})).To(ConsistOf(regular))
+		})
+	})
+
+	Describe("#drainNode", func() {
+		It("should consider missing nodes drained", func() {
+			Expect(drainNode(fake.NewSimpleClientset(), "10.0.0.1")).To(BeTrue())
+		})
+
+		It("should cordon the node", func() {
+			guest := fake.NewSimpleClientset(node)
+
+			Expect(drainNode(guest, "10.0.0.1")).To(BeTrue())
+
+			node, err := guest.CoreV1().Nodes().Get("10.0.0.1", metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(node.Spec.Unschedulable).To(BeTrue())
+		})
+
+		It("should not be drained while evictions are rejected by disruption budgets", func() {
+			guest := fake.NewSimpleClientset(node, pod)
+			rejectEvictions(guest)
+
+			Expect(drainNode(guest, "10.0.0.1")).To(BeFalse())
+		})
+	})
+
+	Describe("#removeNode", func() {
+		It("should delete drained nodes", func() {
+			guest := fake.NewSimpleClientset(node)
+
+			Expect(removeNode(guest, "10.0.0.1", false)).To(BeTrue())
+
+			_, err := guest.CoreV1().Nodes().Get("10.0.0.1", metav1.GetOptions{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should keep nodes whose pods cannot be evicted until the drain timed out", func() {
+			guest := fake.NewSimpleClientset(node, pod)
+			rejectEvictions(guest)
+
+			Expect(removeNode(guest, "10.0.0.1", false)).To(BeFalse())
+
+			_, err := guest.CoreV1().Nodes().Get("10.0.0.1", metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should delete nodes whose pods cannot be evicted after the drain timed out", func() {
+			guest := fake.NewSimpleClientset(node, pod)
+			rejectEvictions(guest)
+
+			Expect(removeNode(guest, "10.0.0.1", true)).To(BeTrue())
+
+			_, err := guest.CoreV1().Nodes().Get("10.0.0.1", metav1.GetOptions{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should only give up on unreachable clusters after the drain timed out", func() {
+			guest := fake.NewSimpleClientset(node)
+			guest.PrependReactor("*", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				return true, nil, fmt.Errorf("connection refused")
+			})
+
+			done, err := removeNode(guest, "10.0.0.1", false)
+			Expect(err).To(HaveOccurred())
+			Expect(done).To(BeFalse())
+
+			done, err = removeNode(guest, "10.0.0.1", true)
+			Expect(err).To(HaveOccurred())
+			Expect(done).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,199 @@
+package machine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	cluster2 "kubeception.cloud/kubeception/pkg/controller/cluster"
+	"kubeception.cloud/kubeception/pkg/util/flags"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+const (
+	// KubeletPort is the port the kubelet of a machine listens on. It differs from the default port, as the
+	// machines share the network of the pod running them with the kubelet of the hosting node.
+	KubeletPort = 20250
+
+	// DefaultStorageDriver is the storage driver of docker of machines that do not configure any.
+	DefaultStorageDriver = "vfs"
+
+	// BootstrapKubeconfigDir is the directory the bootstrap kubeconfig of a machine is mounted to.
+	BootstrapKubeconfigDir = "/etc/kubeconfig"
+	// BootstrapKubeconfigFile is the bootstrap kubeconfig the kubelet of a machine requests its client certificate
+	// with.
+	BootstrapKubeconfigFile = BootstrapKubeconfigDir + "/kubeconfig"
+	// KubeletKubeconfigFile is the kubeconfig the kubelet of a machine writes after it obtained its client
+	// certificate.
+	KubeletKubeconfigFile = "/var/lib/kubelet/kubeconfig"
+)
+
+var (
+	// kubeletManagedFlags are the kubelet flags that are rendered by kubeception and cannot be overridden.
+	kubeletManagedFlags = []string{
+		"kubeconfig",
+		"bootstrap-kubeconfig",
+		"rotate-certificates",
+		"port",
+		"containerized",
+		"hostname-override",
+		"node-ip",
+		"cluster-dns",
+		"cluster-domain",
+		"network-plugin",
+		"cni-conf-dir",
+		"cni-bin-dir",
+	}
+
+	// defaultKubeletFeatureGates are the feature gates of the kubelet unless configured otherwise.
+	// Local storage capacity isolation does not work with the root file system of the machine containers.
+	defaultKubeletFeatureGates = map[string]bool{
+		"LocalStorageCapacityIsolation": false,
+	}
+)
+
+// validateKubelet validates the given kubelet configuration.
+func validateKubelet(kubelet *v1alpha1.Kubelet) error {
+	if kubelet.MaxPods != nil && *kubelet.MaxPods <= 0 {
+		return fmt.Errorf("invalid non-positive maximum number of pods %d", *kubelet.MaxPods)
+	}
+
+	if err := flags.ValidateMap(kubelet.NodeLabels); err != nil {
+		return fmt.Errorf("invalid node labels: %v", err)
+	}
+
+	gates := make(map[string]string, len(kubelet.FeatureGates))
+	for gate := range kubelet.FeatureGates {
+		gates[gate] = ""
+	}
+	if err := flags.ValidateMap(gates); err != nil {
+		return fmt.Errorf("invalid feature gates: %v", err)
+	}
+
+	for _, taint := range kubelet.Taints {
+		if taint.Key == "" || strings.ContainsAny(taint.Key+taint.Value, ",=:") {
+			return fmt.Errorf("invalid taint %q, key must be non-empty and key and value must not contain ',', '=' or ':'", taint.Key)
+		}
+		switch taint.Effect {
+		case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			return fmt.Errorf("invalid effect %q of taint %s", taint.Effect, taint.Key)
+		}
+	}
+	return nil
+}
+
+// joinTaints joins the given taints into the format of the --register-with-taints flag.
+func joinTaints(taints []corev1.Taint) string {
+	pairs := make([]string, 0, len(taints))
+	for _, taint := range taints {
+		pairs = append(pairs, fmt.Sprintf("%s=%s:%s", taint.Key, taint.Value, taint.Effect))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// kubeletNodeName returns the name the kubelet of the given machine registers its node with if the machine runs in
+// a pod with the given IP. The client certificate of the kubelet is bound to the node name. Machines with storage
+// keep it across restarts of their pod, which may change its IP, so that they register with the name of the
+// machine. Other machines register with the IP of their pod.
+func kubeletNodeName(machine *clusterv1alpha1.Machine, storage *v1alpha1.MachineStorage, podIP string) string {
+	if storage != nil {
+		return machine.Name
+	}
+	return podIP
+}
+
+// kubeletFlags renders the flags of the kubelet of a machine of the given cluster from the given configurations.
+// The kubelet registers its node with the given name.
+func kubeletFlags(cluster *clusterv1alpha1.Cluster, config *v1alpha1.ClusterConfig, kubelet *v1alpha1.Kubelet, nodeName string) ([]string, error) {
+	if kubelet == nil {
+		kubelet = &v1alpha1.Kubelet{}
+	}
+	if err := validateKubelet(kubelet); err != nil {
+		return nil, err
+	}
+
+	f := flags.New()
+	f.Set("kubeconfig", KubeletKubeconfigFile)
+	f.Set("bootstrap-kubeconfig", BootstrapKubeconfigFile)
+	f.Set("rotate-certificates", "true")
+	f.Set("fail-swap-on", "false")
+	f.Set("port", strconv.Itoa(KubeletPort))
+	f.Set("containerized", "true")
+	f.Set("cloud-provider", "")
+	f.Set("hostname-override", nodeName)
+	f.Set("node-ip", "$(POD_IP)")
+
+	featureGates := make(map[string]bool, len(defaultKubeletFeatureGates)+len(kubelet.FeatureGates))
+	for gate, enabled := range defaultKubeletFeatureGates {
+		featureGates[gate] = enabled
+	}
+	for gate, enabled := range kubelet.FeatureGates {
+		featureGates[gate] = enabled
+	}
+	f.Set("feature-gates", flags.JoinFeatureGates(featureGates))
+
+	if config.DNS != nil {
+		dnsServiceIP, err := cluster2.DNSServiceIP(cluster)
+		if err != nil {
+			return nil, err
+		}
+		f.Set("cluster-dns", dnsServiceIP.String())
+		f.Set("cluster-domain", cluster2.ClusterDomain(cluster))
+	}
+	if config.Network != nil {
+		f.Set("network-plugin", "cni")
+		f.Set("cni-conf-dir", cluster2.CNIConfDir)
+		f.Set("cni-bin-dir", cluster2.CNIBinDir)
+	}
+
+	if len(kubelet.NodeLabels) > 0 {
+		f.Set("node-labels", flags.JoinMap(kubelet.NodeLabels))
+	}
+	if len(kubelet.Taints) > 0 {
+		f.Set("register-with-taints", joinTaints(kubelet.Taints))
+	}
+	if kubelet.MaxPods != nil {
+		f.Set("max-pods", strconv.Itoa(int(*kubelet.MaxPods)))
+	}
+
+	if err := f.SetExtraArgs(kubelet.ExtraArgs, kubeletManagedFlags...); err != nil {
+		return nil, err
+	}
+	return f.Args(), nil
+}
+
+// dockerdArgs renders the additional arguments of dockerd of a machine from the given configurations.
+// The arguments are passed to the machine as a single space separated string. If the storage driver is detected
+// when the machine starts, it is not part of the arguments.
+func dockerdArgs(docker *v1alpha1.Docker, storage *v1alpha1.MachineStorage) (string, error) {
+	detectStorageDriver := detectsStorageDriver(docker, storage)
+	if docker == nil {
+		docker = &v1alpha1.Docker{}
+	}
+
+	storageDriver := DefaultStorageDriver
+	if docker.StorageDriver != "" {
+		storageDriver = docker.StorageDriver
+	}
+	if strings.ContainsAny(storageDriver, " \t\n") {
+		return "", fmt.Errorf("invalid storage driver %q", storageDriver)
+	}
+
+	var args []string
+	if !detectStorageDriver {
+		args = append(args, fmt.Sprintf("--storage-driver=%s", storageDriver))
+	}
+	for _, arg := range docker.ExtraArgs {
+		if arg == "" || strings.ContainsAny(arg, " \t\n") {
+			return "", fmt.Errorf("invalid dockerd argument %q, must be non-empty and must not contain whitespace", arg)
+		}
+		if arg == "-s" || arg == "--storage-driver" || strings.HasPrefix(arg, "--storage-driver=") {
+			return "", fmt.Errorf("the storage driver is managed by kubeception and cannot be set as extra argument")
+		}
+		args = append(args, arg)
+	}
+	return strings.Join(args, " "), nil
+}
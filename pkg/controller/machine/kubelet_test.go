@@ -0,0 +1,148 @@
+package machine
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	"kubeception.cloud/kubeception/pkg/util/pointers"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestMachine(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Machine")
+}
+
+var _ = Describe("Kubelet", func() {
+	Describe("#kubeletNodeName", func() {
+		machine := &clusterv1alpha1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+
+		It("should name nodes of machines without storage after the pod IP", func() {
+			Expect(kubeletNodeName(machine, nil, "10.0.0.1")).To(Equal("10.0.0.1"))
+		})
+
+		It("should name nodes of machines with storage after the machine", func() {
+			Expect(kubeletNodeName(machine, &v1alpha1.MachineStorage{}, "10.0.0.1")).To(Equal("foo"))
+		})
+	})
+
+	Describe("#kubeletFlags", func() {
+		var (
+			cluster *clusterv1alpha1.Cluster
+			config  *v1alpha1.ClusterConfig
+		)
+		BeforeEach(func() {
+			cluster = &clusterv1alpha1.Cluster{
+				Spec: clusterv1alpha1.ClusterSpec{
+					ClusterNetwork: clusterv1alpha1.ClusterNetworkingConfig{
+						Services: clusterv1alpha1.NetworkRanges{CIDRBlocks: []string{"10.96.0.0/12"}},
+					},
+				},
+			}
+			config = &v1alpha1.ClusterConfig{}
+		})
+
+		It("should render the default flags", func() {
+			args, err := kubeletFlags(cluster, config, nil, "$(POD_IP)")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(Equal([]string{
+				"--kubeconfig=/var/lib/kubelet/kubeconfig",
+				"--bootstrap-kubeconfig=/etc/kubeconfig/kubeconfig",
+				"--rotate-certificates=true",
+				"--fail-swap-on=false",
+				"--port=20250",
+				"--containerized=true",
+				"--cloud-provider=",
+				"--hostname-override=$(POD_IP)",
+				"--node-ip=$(POD_IP)",
+				"--feature-gates=LocalStorageCapacityIsolation=false",
+			}))
+		})
+
+		It("should render the DNS and network flags", func() {
+			config.DNS = &v1alpha1.DNS{}
+			config.Network = &v1alpha1.Network{Type: v1alpha1.NetworkFlannel}
+
+			args, err := kubeletFlags(cluster, config, nil, "$(POD_IP)")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(ContainElement("--cluster-dns=10.96.0.10"))
+			Expect(args).To(ContainElement("--cluster-domain=cluster.local"))
+			Expect(args).To(ContainElement("--network-plugin=cni"))
+		})
+
+		It("should render the kubelet configuration", func() {
+			args, err := kubeletFlags(cluster, config, &v1alpha1.Kubelet{
+				NodeLabels: map[string]string{"pool": "workers", "zone": "a"},
+				Taints: []corev1.Taint{
+					{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule},
+					{Key: "spot", Effect: corev1.TaintEffectPreferNoSchedule},
+				},
+				MaxPods:      pointers.Int32(50),
+				FeatureGates: map[string]bool{"LocalStorageCapacityIsolation": true, "CSIDriverRegistry": true},
+				ExtraArgs:    map[string]string{"v": "2", "fail-swap-on": "true"},
+			}, "$(POD_IP)")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(args).To(ContainElement("--node-labels=pool=workers,zone=a"))
+			Expect(args).To(ContainElement("--register-with-taints=dedicated=gpu:NoSchedule,spot=:PreferNoSchedule"))
+			Expect(args).To(ContainElement("--max-pods=50"))
+			Expect(args).To(ContainElement("--feature-gates=CSIDriverRegistry=true,LocalStorageCapacityIsolation=true"))
+			Expect(args).To(ContainElement("--fail-swap-on=true"))
+			Expect(args).To(ContainElement("--v=2"))
+		})
+
+		It("should reject invalid configurations", func() {
+			for _, kubelet := range []*v1alpha1.Kubelet{
+				{MaxPods: pointers.Int32(0)},
+				{NodeLabels: map[string]string{"pool": "a,b"}},
+				{Taints: []corev1.Taint{{Key: "dedicated", Effect: "Sometimes"}}},
+				{Taints: []corev1.Taint{{Effect: corev1.TaintEffectNoSchedule}}},
+				{ExtraArgs: map[string]string{"port": "10250"}},
+			} {
+				_, err := kubeletFlags(cluster, config, kubelet, "$(POD_IP)")
+
+				Expect(err).To(HaveOccurred())
+			}
+		})
+	})
+
+	Describe("#dockerdArgs", func() {
+		It("should default the storage driver", func() {
+			Expect(dockerdArgs(nil, nil)).To(Equal("--storage-driver=vfs"))
+		})
+
+		It("should leave the storage driver to detection for machines with storage", func() {
+			storage := &v1alpha1.MachineStorage{}
+
+			Expect(dockerdArgs(nil, storage)).To(BeEmpty())
+			Expect(dockerdArgs(&v1alpha1.Docker{ExtraArgs: []string{"--debug"}}, storage)).To(Equal("--debug"))
+			Expect(dockerdArgs(&v1alpha1.Docker{StorageDriver: "vfs"}, storage)).To(Equal("--storage-driver=vfs"))
+		})
+
+		It("should render the docker configuration", func() {
+			Expect(dockerdArgs(&v1alpha1.Docker{
+				StorageDriver: "overlay2",
+				ExtraArgs:     []string{"--registry-mirror=https://mirror.example.com", "--debug"},
+			}, nil)).To(Equal("--storage-driver=overlay2 --registry-mirror=https://mirror.example.com --debug"))
+		})
+
+		It("should reject invalid arguments", func() {
+			for _, docker := range []*v1alpha1.Docker{
+				{ExtraArgs: []string{"--storage-driver=overlay2"}},
+				{ExtraArgs: []string{"--label a=b"}},
+				{StorageDriver: "overlay 2"},
+			} {
+				_, err := dockerdArgs(docker, nil)
+
+				Expect(err).To(HaveOccurred())
+			}
+		})
+	})
+})
@@ -0,0 +1,130 @@
+package machine
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/helper"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	cluster2 "kubeception.cloud/kubeception/pkg/controller/cluster"
+	"kubeception.cloud/kubeception/pkg/util"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	controllererror "sigs.k8s.io/cluster-api/pkg/controller/error"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// nodeReady reports whether the given node has a true ready condition.
+func nodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// providerStatusEqual checks whether the given provider statuses are equal, disregarding their type meta.
+func providerStatusEqual(a, b *v1alpha1.MachineStatus) bool {
+	return a.PodName == b.PodName &&
+		a.PodIP == b.PodIP &&
+		a.Ready == b.Ready &&
+		a.KubeletVersion == b.KubeletVersion
+}
+
+// nodeReference returns a reference to the given node.
+func nodeReference(node *corev1.Node) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Node",
+		Name:       node.Name,
+		UID:        node.UID,
+	}
+}
+
+// machineStatus computes the provider status, node reference and addresses of a machine from the given pod running
+// it and its node. Both may be nil if they do not exist (yet).
+func machineStatus(pod *corev1.Pod, node *corev1.Node) (*v1alpha1.MachineStatus, *corev1.ObjectReference, []corev1.NodeAddress) {
+	status := &v1alpha1.MachineStatus{}
+	if pod == nil {
+		return status, nil, nil
+	}
+
+	status.PodName = pod.Name
+	status.PodIP = pod.Status.PodIP
+	if node == nil {
+		if pod.Status.PodIP == "" {
+			return status, nil, nil
+		}
+		return status, nil, []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: pod.Status.PodIP}}
+	}
+
+	status.Ready = nodeReady(node)
+	status.KubeletVersion = node.Status.NodeInfo.KubeletVersion
+	return status, nodeReference(node), node.Status.Addresses
+}
+
+// getNode returns the node with the given name in the given cluster or nil if it does not exist or the API server
+// of the cluster cannot be reached.
+func (a *actuator) getNode(ctx context.Context, cluster *clusterv1alpha1.Cluster, name string) *corev1.Node {
+	guest, err := cluster2.NewClientForCluster(ctx, a.Client, cluster)
+	if err != nil {
+		return nil
+	}
+
+	node := &corev1.Node{}
+	if err := guest.Get(ctx, client.ObjectKey{Name: name}, node); err != nil {
+		return nil
+	}
+	return node
+}
+
+// updateStatus updates the status of the given machine with its node reference, addresses and provider status.
+// Until the node of the machine is ready, a RequeueAfterError is returned so that the status is refreshed.
+func (a *actuator) updateStatus(ctx context.Context, cluster *clusterv1alpha1.Cluster, machine *clusterv1alpha1.Machine) error {
+	var (
+		pod  *corev1.Pod
+		node *corev1.Node
+	)
+	existing := &corev1.Pod{}
+	if err := a.Client.Get(ctx, util.Key(machine.Namespace, machinePodName(machine)), existing); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	} else {
+		pod = existing
+		if name := machineNodeName(machine, pod.Status.PodIP); name != "" {
+			node = a.getNode(ctx, cluster, name)
+		}
+	}
+
+	providerStatus, nodeRef, addresses := machineStatus(pod, node)
+
+	previous := &v1alpha1.MachineStatus{}
+	if raw := machine.Status.ProviderStatus; raw != nil && len(raw.Raw) > 0 {
+		if loaded, err := helper.LoadMachineStatus(raw.Raw); err == nil {
+			previous = loaded
+		}
+	}
+
+	if !providerStatusEqual(providerStatus, previous) ||
+		!apiequality.Semantic.DeepEqual(nodeRef, machine.Status.NodeRef) ||
+		!apiequality.Semantic.DeepEqual(addresses, machine.Status.Addresses) {
+		rawProviderStatus, err := helper.EncodeMachineStatus(providerStatus)
+		if err != nil {
+			return err
+		}
+
+		machine.Status.NodeRef = nodeRef
+		machine.Status.Addresses = addresses
+		machine.Status.ProviderStatus = rawProviderStatus
+		if err := a.Client.Status().Update(ctx, machine); err != nil {
+			return err
+		}
+	}
+
+	if !providerStatus.Ready {
+		return &controllererror.RequeueAfterError{RequeueAfter: StatusRequeueAfter}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package machine
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Status", func() {
+	Describe("#machineStatus", func() {
+		var pod *corev1.Pod
+		BeforeEach(func() {
+			pod = &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "machine-example-0"},
+				Status:     corev1.PodStatus{PodIP: "10.244.0.5"},
+			}
+		})
+
+		It("should report an empty status without pod", func() {
+			status, nodeRef, addresses := machineStatus(nil, nil)
+
+			Expect(status).To(Equal(&v1alpha1.MachineStatus{}))
+			Expect(nodeRef).To(BeNil())
+			Expect(addresses).To(BeEmpty())
+		})
+
+		It("should report the pod IP as internal IP until the node registered", func() {
+			status, nodeRef, addresses := machineStatus(pod, nil)
+
+			Expect(status).To(Equal(&v1alpha1.MachineStatus{PodName: "machine-example-0", PodIP: "10.244.0.5"}))
+			Expect(nodeRef).To(BeNil())
+			Expect(addresses).To(Equal([]corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.244.0.5"}}))
+		})
+
+		It("should report the node", func() {
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "10.244.0.5", UID: "1234"},
+				Status: corev1.NodeStatus{
+					Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+					Addresses: []corev1.NodeAddress{
+						{Type: corev1.NodeInternalIP, Address: "10.244.0.5"},
+						{Type: corev1.NodeHostName, Address: "10.244.0.5"},
+					},
+					NodeInfo: corev1.NodeSystemInfo{KubeletVersion: "v1.13.5"},
+				},
+			}
+
+			status, nodeRef, addresses := machineStatus(pod, node)
+
+			Expect(status).To(Equal(&v1alpha1.MachineStatus{
+				PodName:        "machine-example-0",
+				PodIP:          "10.244.0.5",
+				Ready:          true,
+				KubeletVersion: "v1.13.5",
+			}))
+			Expect(nodeRef).To(Equal(&corev1.ObjectReference{APIVersion: "v1", Kind: "Node", Name: "10.244.0.5", UID: "1234"}))
+			Expect(addresses).To(Equal(node.Status.Addresses))
+		})
+	})
+
+	Describe("#nodeReady", func() {
+		It("should require a true ready condition", func() {
+			node := &corev1.Node{}
+			Expect(nodeReady(node)).To(BeFalse())
+
+			node.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionFalse}}
+			Expect(nodeReady(node)).To(BeFalse())
+
+			node.Status.Conditions[0].Status = corev1.ConditionTrue
+			Expect(nodeReady(node)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,115 @@
+package machine
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultStorageSize is the size of the persistent volume of machines that do not configure any.
+	DefaultStorageSize = "10Gi"
+	// StorageVolumeName is the name of the volume (claim template) holding the data of docker and the kubelet.
+	StorageVolumeName = "storage"
+
+	// DockerDataDir is the directory docker stores its images and containers in.
+	DockerDataDir = "/var/lib/docker"
+	// KubeletDataDir is the directory the kubelet stores its state and the volumes of its pods in.
+	KubeletDataDir = "/var/lib/kubelet"
+)
+
+// detectStorageDriverScript starts the entrypoint given as $0 with the arguments given as $@, after prepending
+// the storage driver of docker to ADDITIONAL_DOCKERD_ARGS. overlay2 is used if the docker directory is on an ext4
+// file system (reported as ext2/ext3), vfs otherwise. xfs supports overlay2 only if it was formatted with
+// ftype=1, which cannot be checked from within the machine, so that it is left on vfs.
+const detectStorageDriverScript = `case "$(stat -f -c %T ` + DockerDataDir + `)" in
+ext2/ext3) driver=overlay2 ;;
+*) driver=` + DefaultStorageDriver + ` ;;
+esac
+export ADDITIONAL_DOCKERD_ARGS="--storage-driver=${driver} ${ADDITIONAL_DOCKERD_ARGS}"
+exec "$0" "$@"
+`
+
+// detectsStorageDriver reports whether the storage driver of docker is detected when the machine starts, which is
+// the case for machines with storage that do not configure a storage driver.
+func detectsStorageDriver(docker *v1alpha1.Docker, storage *v1alpha1.MachineStorage) bool {
+	return storage != nil && (docker == nil || docker.StorageDriver == "")
+}
+
+// kubeletCommand returns the command of the machine container running the kubelet with the given arguments.
+func kubeletCommand(kubeletArgs []string, detectStorageDriver bool) []string {
+	command := []string{"/entrypoint.sh"}
+	if detectStorageDriver {
+		command = []string{"/bin/sh", "-c", detectStorageDriverScript, "/entrypoint.sh"}
+	}
+	return append(command, kubeletArgs...)
+}
+
+// storageVolumes returns the volumes of the machine pod (in case of emptyDir storage) or the volume claim templates
+// of the machine StatefulSet (in case of persistent storage) and the mounts of the machine container for the given
+// storage configuration.
+func storageVolumes(machine *clusterv1alpha1.Machine, storage *v1alpha1.MachineStorage) ([]corev1.Volume, []corev1.PersistentVolumeClaim, []corev1.VolumeMount) {
+	if storage == nil {
+		return nil, nil, nil
+	}
+
+	volumeMounts := []corev1.VolumeMount{
+		{Name: StorageVolumeName, MountPath: DockerDataDir, SubPath: "docker"},
+		{Name: StorageVolumeName, MountPath: KubeletDataDir, SubPath: "kubelet"},
+	}
+
+	if storage.EmptyDir {
+		return []corev1.Volume{
+			{
+				Name:         StorageVolumeName,
+				VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: storage.Size}},
+			},
+		}, nil, volumeMounts
+	}
+
+	size := resource.MustParse(DefaultStorageSize)
+	if storage.Size != nil {
+		size = *storage.Size
+	}
+
+	return nil, []corev1.PersistentVolumeClaim{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   StorageVolumeName,
+				Labels: StatefulSetLabels(machine.Name),
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				StorageClassName: storage.StorageClassName,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceStorage: size,
+					},
+				},
+			},
+		},
+	}, volumeMounts
+}
+
+// deleteStorage deletes the persistent volume claims of the given machine, which are not deleted together with
+// its StatefulSet. It reports whether all claims are gone.
+func (a *actuator) deleteStorage(ctx context.Context, machine *clusterv1alpha1.Machine) (bool, error) {
+	claimList := &corev1.PersistentVolumeClaimList{}
+	if err := a.Client.List(ctx, claimList, client.InNamespace(machine.Namespace), client.MatchingLabels(StatefulSetLabels(machine.Name))); err != nil {
+		return false, err
+	}
+	for _, claim := range claimList.Items {
+		if claim.DeletionTimestamp != nil {
+			continue
+		}
+		if err := client.IgnoreNotFound(a.Client.Delete(ctx, &claim)); err != nil {
+			return false, err
+		}
+	}
+	return len(claimList.Items) == 0, nil
+}
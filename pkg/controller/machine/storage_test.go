@@ -0,0 +1,68 @@
+package machine
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"kubeception.cloud/kubeception/pkg/apis/kubeception/v1alpha1"
+	clusterv1alpha1 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Storage", func() {
+	Describe("#storageVolumes", func() {
+		var machine *clusterv1alpha1.Machine
+		BeforeEach(func() {
+			machine = &clusterv1alpha1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "example"}}
+		})
+
+		It("should not add volumes without storage", func() {
+			volumes, volumeClaimTemplates, volumeMounts := storageVolumes(machine, nil)
+
+			Expect(volumes).To(BeEmpty())
+			Expect(volumeClaimTemplates).To(BeEmpty())
+			Expect(volumeMounts).To(BeEmpty())
+		})
+
+		It("should default to a persistent volume", func() {
+			volumes, volumeClaimTemplates, volumeMounts := storageVolumes(machine, &v1alpha1.MachineStorage{})
+
+			Expect(volumes).To(BeEmpty())
+			Expect(volumeClaimTemplates).To(HaveLen(1))
+			Expect(volumeClaimTemplates[0].Name).To(Equal(StorageVolumeName))
+			Expect(volumeClaimTemplates[0].Labels).To(Equal(StatefulSetLabels("example")))
+			Expect(volumeClaimTemplates[0].Spec.Resources.Requests).To(HaveKeyWithValue(corev1.ResourceStorage, resource.MustParse(DefaultStorageSize)))
+			Expect(volumeMounts).To(ConsistOf(
+				corev1.VolumeMount{Name: StorageVolumeName, MountPath: DockerDataDir, SubPath: "docker"},
+				corev1.VolumeMount{Name: StorageVolumeName, MountPath: KubeletDataDir, SubPath: "kubelet"},
+			))
+		})
+
+		It("should limit the size of emptyDir volumes", func() {
+			size := resource.MustParse("20Gi")
+
+			volumes, volumeClaimTemplates, volumeMounts := storageVolumes(machine, &v1alpha1.MachineStorage{Size: &size, EmptyDir: true})
+
+			Expect(volumes).To(Equal([]corev1.Volume{
+				{
+					Name:         StorageVolumeName,
+					VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: &size}},
+				},
+			}))
+			Expect(volumeClaimTemplates).To(BeEmpty())
+			Expect(volumeMounts).To(HaveLen(2))
+		})
+	})
+
+	Describe("#kubeletCommand", func() {
+		It("should run the entrypoint", func() {
+			Expect(kubeletCommand([]string{"--v=2"}, false)).To(Equal([]string{"/entrypoint.sh", "--v=2"}))
+		})
+
+		It("should detect the storage driver before running the entrypoint", func() {
+			Expect(kubeletCommand([]string{"--v=2"}, true)).To(Equal([]string{"/bin/sh", "-c", detectStorageDriverScript, "/entrypoint.sh", "--v=2"}))
+		})
+	})
+})
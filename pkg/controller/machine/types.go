@@ -2,10 +2,18 @@ package machine
 
 import (
 	"fmt"
+	"time"
 
 	"kubeception.cloud/kubeception/pkg/controller/common"
 )
 
+const (
+	// DeleteRequeueAfter is the duration after which the deletion of a machine is checked again.
+	DeleteRequeueAfter = 5 * time.Second
+	// StatusRequeueAfter is the duration after which the status of a machine whose node is not ready is refreshed.
+	StatusRequeueAfter = 10 * time.Second
+)
+
 var (
 	StatefulSetNameLabel = fmt.Sprintf("%s/machine", common.LabelPrefix)
 )
@@ -0,0 +1,34 @@
+package cidr
+
+import (
+	"fmt"
+	"net"
+)
+
+// NthIP returns the n-th IP of the given CIDR, with the network address itself being the 0th IP.
+// It errors if the CIDR cannot be parsed or if the resulting IP is not contained in the CIDR.
+func NthIP(cidr string, n int) (net.IP, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("invalid negative offset %d", n)
+	}
+
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	ip := make(net.IP, len(ipNet.IP))
+	copy(ip, ipNet.IP)
+
+	carry := n
+	for i := len(ip) - 1; i >= 0 && carry > 0; i-- {
+		sum := int(ip[i]) + carry
+		ip[i] = byte(sum % 256)
+		carry = sum / 256
+	}
+
+	if carry > 0 || !ipNet.Contains(ip) {
+		return nil, fmt.Errorf("cidr %s does not contain %d addresses", cidr, n+1)
+	}
+	return ip, nil
+}
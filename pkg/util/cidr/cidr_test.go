@@ -0,0 +1,42 @@
+package cidr
+
+import (
+	"net"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestCIDR(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CIDR")
+}
+
+var _ = Describe("CIDR Suite", func() {
+	Describe("#NthIP", func() {
+		It("should return the n-th IP of an IPv4 CIDR", func() {
+			ip, err := NthIP("192.168.0.0/16", 10)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ip.Equal(net.ParseIP("192.168.0.10"))).To(BeTrue())
+		})
+
+		It("should carry over to the next octet", func() {
+			ip, err := NthIP("10.0.0.0/8", 257)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ip.Equal(net.ParseIP("10.0.1.1"))).To(BeTrue())
+		})
+
+		It("should error if the CIDR is too small", func() {
+			_, err := NthIP("10.0.0.0/30", 4)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should error if the CIDR is invalid", func() {
+			_, err := NthIP("foo", 1)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
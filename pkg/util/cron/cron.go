@@ -0,0 +1,188 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron schedule.
+type Schedule struct {
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+
+	// dayOfMonthRestricted and dayOfWeekRestricted track whether the respective fields were not `*`.
+	// If both are restricted, a day matches if either of them matches (as in cron(8)).
+	dayOfMonthRestricted, dayOfWeekRestricted bool
+}
+
+type bounds struct {
+	min, max int
+}
+
+var (
+	minuteBounds     = bounds{0, 59}
+	hourBounds       = bounds{0, 23}
+	dayOfMonthBounds = bounds{1, 31}
+	monthBounds      = bounds{1, 12}
+	dayOfWeekBounds  = bounds{0, 6}
+
+	descriptors = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// maxLookahead is the maximum duration Next looks into the future for a matching time.
+const maxLookahead = 5 * 366 * 24 * time.Hour
+
+// Parse parses a standard cron expression with the fields minute, hour, day of month, month and day of week.
+// Fields may contain `*`, single values, ranges (`a-b`), steps (`*/n`, `a-b/n`) and lists thereof.
+// In addition, the descriptors @yearly, @annually, @monthly, @weekly, @daily, @midnight and @hourly are supported.
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if expanded, ok := descriptors[spec]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields in cron expression %q but got %d", spec, len(fields))
+	}
+
+	schedule := &Schedule{}
+	for _, f := range []struct {
+		value  string
+		bounds bounds
+		into   *uint64
+	}{
+		{fields[0], minuteBounds, &schedule.minute},
+		{fields[1], hourBounds, &schedule.hour},
+		{fields[2], dayOfMonthBounds, &schedule.dayOfMonth},
+		{fields[3], monthBounds, &schedule.month},
+		{fields[4], dayOfWeekBounds, &schedule.dayOfWeek},
+	} {
+		bits, err := parseField(f.value, f.bounds)
+		if err != nil {
+			return nil, err
+		}
+		*f.into = bits
+	}
+
+	schedule.dayOfMonthRestricted = fields[2] != "*"
+	schedule.dayOfWeekRestricted = fields[4] != "*"
+	return schedule, nil
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		partBits, err := parsePart(part, b)
+		if err != nil {
+			return 0, err
+		}
+		bits |= partBits
+	}
+	return bits, nil
+}
+
+func parsePart(part string, b bounds) (uint64, error) {
+	rangePart, step := part, 1
+	if idx := strings.Index(part, "/"); idx >= 0 {
+		rangePart = part[:idx]
+		var err error
+		step, err = strconv.Atoi(part[idx+1:])
+		if err != nil || step <= 0 {
+			return 0, fmt.Errorf("invalid step in %q", part)
+		}
+	}
+
+	start, end := b.min, b.max
+	switch {
+	case rangePart == "*":
+	case strings.Contains(rangePart, "-"):
+		bounds := strings.SplitN(rangePart, "-", 2)
+		var err error
+		if start, err = parseValue(bounds[0], b); err != nil {
+			return 0, err
+		}
+		if end, err = parseValue(bounds[1], b); err != nil {
+			return 0, err
+		}
+		if start > end {
+			return 0, fmt.Errorf("invalid range %q", rangePart)
+		}
+	default:
+		value, err := parseValue(rangePart, b)
+		if err != nil {
+			return 0, err
+		}
+		start = value
+		if step == 1 {
+			end = value
+		}
+	}
+
+	var bits uint64
+	for i := start; i <= end; i += step {
+		bits |= 1 << uint(i)
+	}
+	return bits, nil
+}
+
+func parseValue(value string, b bounds) (int, error) {
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	if i < b.min || i > b.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", i, b.min, b.max)
+	}
+	return i, nil
+}
+
+func has(bits uint64, i int) bool {
+	return bits&(1<<uint(i)) != 0
+}
+
+func (s *Schedule) matchesDay(t time.Time) bool {
+	dayOfMonth := has(s.dayOfMonth, t.Day())
+	dayOfWeek := has(s.dayOfWeek, int(t.Weekday()))
+	if s.dayOfMonthRestricted && s.dayOfWeekRestricted {
+		return dayOfMonth || dayOfWeek
+	}
+	return dayOfMonth && dayOfWeek
+}
+
+// Next returns the first time after the given time that matches the schedule.
+// If no such time exists within the next five years, the zero time is returned.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Add(time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	limit := t.Add(maxLookahead)
+
+	for t.Before(limit) {
+		if !has(s.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !has(s.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !has(s.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
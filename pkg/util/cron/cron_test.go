@@ -0,0 +1,89 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestCron(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Cron")
+}
+
+func date(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+var _ = Describe("Cron Suite", func() {
+	Describe("#Parse", func() {
+		It("should reject expressions with the wrong number of fields", func() {
+			_, err := Parse("* * * *")
+
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject values out of range", func() {
+			_, err := Parse("60 * * * *")
+
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject invalid steps", func() {
+			_, err := Parse("*/0 * * * *")
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("#Next", func() {
+		It("should return the next minute for a wildcard schedule", func() {
+			schedule, err := Parse("* * * * *")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(schedule.Next(time.Date(2019, 5, 1, 10, 30, 15, 0, time.UTC))).To(Equal(date(2019, 5, 1, 10, 31)))
+		})
+
+		It("should honor steps", func() {
+			schedule, err := Parse("*/15 * * * *")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(schedule.Next(date(2019, 5, 1, 10, 30))).To(Equal(date(2019, 5, 1, 10, 45)))
+			Expect(schedule.Next(date(2019, 5, 1, 10, 45))).To(Equal(date(2019, 5, 1, 11, 0)))
+		})
+
+		It("should honor ranges and lists", func() {
+			schedule, err := Parse("0 9-17/4,22 * * *")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(schedule.Next(date(2019, 5, 1, 9, 0))).To(Equal(date(2019, 5, 1, 13, 0)))
+			Expect(schedule.Next(date(2019, 5, 1, 17, 0))).To(Equal(date(2019, 5, 1, 22, 0)))
+			Expect(schedule.Next(date(2019, 5, 1, 22, 0))).To(Equal(date(2019, 5, 2, 9, 0)))
+		})
+
+		It("should roll over months and years", func() {
+			schedule, err := Parse("@yearly")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(schedule.Next(date(2019, 5, 1, 0, 0))).To(Equal(date(2020, 1, 1, 0, 0)))
+		})
+
+		It("should match either day of month or day of week if both are restricted", func() {
+			schedule, err := Parse("0 0 13 * 5")
+			Expect(err).NotTo(HaveOccurred())
+
+			// 2019-05-03 is a Friday.
+			Expect(schedule.Next(date(2019, 5, 1, 0, 0))).To(Equal(date(2019, 5, 3, 0, 0)))
+			Expect(schedule.Next(date(2019, 5, 10, 0, 0))).To(Equal(date(2019, 5, 13, 0, 0)))
+		})
+
+		It("should return the zero time for schedules that never match", func() {
+			schedule, err := Parse("0 0 31 2 *")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(schedule.Next(date(2019, 5, 1, 0, 0)).IsZero()).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,115 @@
+package flags
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var nameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Flags is an ordered list of command line flags of the form `--name=value`. Setting a flag again replaces its
+// value but keeps its position.
+type Flags struct {
+	names  []string
+	values map[string]string
+}
+
+// New returns an empty list of flags.
+func New() *Flags {
+	return &Flags{values: make(map[string]string)}
+}
+
+// Set sets the flag with the given name to the given value.
+func (f *Flags) Set(name, value string) {
+	if _, ok := f.values[name]; !ok {
+		f.names = append(f.names, name)
+	}
+	f.values[name] = value
+}
+
+// Get returns the value of the flag with the given name and whether it is set.
+func (f *Flags) Get(name string) (string, bool) {
+	value, ok := f.values[name]
+	return value, ok
+}
+
+// SetExtraArgs sets the given extra arguments in the order of their names. Extra arguments override flags
+// that have been set before, except for the given reserved flags, which cannot be set at all.
+func (f *Flags) SetExtraArgs(extraArgs map[string]string, reserved ...string) error {
+	names := make([]string, 0, len(extraArgs))
+	for name := range extraArgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := ValidateName(name); err != nil {
+			return err
+		}
+		for _, reservedName := range reserved {
+			if name == reservedName {
+				return fmt.Errorf("flag --%s is managed by kubeception and cannot be set as extra argument", name)
+			}
+		}
+		f.Set(name, extraArgs[name])
+	}
+	return nil
+}
+
+// Args returns the flags as `--name=value` arguments.
+func (f *Flags) Args() []string {
+	args := make([]string, 0, len(f.names))
+	for _, name := range f.names {
+		args = append(args, fmt.Sprintf("--%s=%s", name, f.values[name]))
+	}
+	return args
+}
+
+// ValidateName checks that the given name is a valid flag name without leading dashes.
+func ValidateName(name string) error {
+	if !nameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid flag name %q, must consist of lower case alphanumeric characters and dashes", name)
+	}
+	return nil
+}
+
+// ValidateMap checks that the given map can be joined via JoinMap without ambiguity.
+func ValidateMap(m map[string]string) error {
+	for key, value := range m {
+		if key == "" || strings.ContainsAny(key, ",=") {
+			return fmt.Errorf("invalid key %q, must be non-empty and must not contain ',' or '='", key)
+		}
+		if strings.Contains(value, ",") {
+			return fmt.Errorf("invalid value %q of key %s, must not contain ','", value, key)
+		}
+	}
+	return nil
+}
+
+// JoinMap joins the given map into a comma separated list of `key=value` pairs sorted by key, as used by flags
+// like --runtime-config.
+func JoinMap(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, m[key]))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// JoinFeatureGates joins the given feature gates into the format of the --feature-gates flag.
+func JoinFeatureGates(gates map[string]bool) string {
+	m := make(map[string]string, len(gates))
+	for gate, enabled := range gates {
+		m[gate] = strconv.FormatBool(enabled)
+	}
+	return JoinMap(m)
+}
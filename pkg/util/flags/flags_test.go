@@ -0,0 +1,72 @@
+package flags
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestFlags(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Flags")
+}
+
+var _ = Describe("Flags Suite", func() {
+	Describe("#Flags", func() {
+		It("should render the flags in the order they were first set", func() {
+			f := New()
+			f.Set("b", "1")
+			f.Set("a", "2")
+			f.Set("b", "3")
+
+			Expect(f.Args()).To(Equal([]string{"--b=3", "--a=2"}))
+		})
+
+		It("should set extra arguments sorted by name and let them override flags", func() {
+			f := New()
+			f.Set("v", "2")
+
+			Expect(f.SetExtraArgs(map[string]string{"z": "1", "v": "4", "a": ""})).To(Succeed())
+			Expect(f.Args()).To(Equal([]string{"--v=4", "--a=", "--z=1"}))
+		})
+
+		It("should reject reserved extra arguments", func() {
+			f := New()
+
+			Expect(f.SetExtraArgs(map[string]string{"kubeconfig": "/tmp/kubeconfig"}, "kubeconfig")).NotTo(Succeed())
+		})
+
+		It("should reject invalid extra argument names", func() {
+			f := New()
+
+			Expect(f.SetExtraArgs(map[string]string{"--v": "2"})).NotTo(Succeed())
+			Expect(f.SetExtraArgs(map[string]string{"v 2": ""})).NotTo(Succeed())
+		})
+	})
+
+	Describe("#JoinMap", func() {
+		It("should join the map sorted by key", func() {
+			Expect(JoinMap(map[string]string{"b": "2", "a": "1"})).To(Equal("a=1,b=2"))
+		})
+
+		It("should join an empty map to an empty string", func() {
+			Expect(JoinMap(nil)).To(BeEmpty())
+		})
+	})
+
+	Describe("#JoinFeatureGates", func() {
+		It("should join the feature gates sorted by name", func() {
+			Expect(JoinFeatureGates(map[string]bool{"B": false, "A": true})).To(Equal("A=true,B=false"))
+		})
+	})
+
+	Describe("#ValidateMap", func() {
+		It("should reject separators in keys and values", func() {
+			Expect(ValidateMap(map[string]string{"a,b": "1"})).NotTo(Succeed())
+			Expect(ValidateMap(map[string]string{"a=b": "1"})).NotTo(Succeed())
+			Expect(ValidateMap(map[string]string{"a": "1,2"})).NotTo(Succeed())
+			Expect(ValidateMap(map[string]string{"api/all": "true"})).To(Succeed())
+		})
+	})
+})
@@ -32,3 +32,10 @@ func DerefInt64OrDefault(i *int64, defaultValue int64) int64 {
 	}
 	return *i
 }
+
+func DerefStringOrDefault(s *string, defaultValue string) string {
+	if s == nil {
+		return defaultValue
+	}
+	return *s
+}